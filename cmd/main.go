@@ -1,70 +1,117 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Natali-Skv/technopark_db_forum/config"
 	"github.com/Natali-Skv/technopark_db_forum/configRouting"
-	forumDelivery "github.com/Natali-Skv/technopark_db_forum/internal/forum/delivery/http"
-	forumRepository "github.com/Natali-Skv/technopark_db_forum/internal/forum/repo"
-	postDelivery "github.com/Natali-Skv/technopark_db_forum/internal/post/delivery/http"
-	postRepository "github.com/Natali-Skv/technopark_db_forum/internal/post/repo"
-	serviceDelivery "github.com/Natali-Skv/technopark_db_forum/internal/service/delivery/http"
-	serviceRepository "github.com/Natali-Skv/technopark_db_forum/internal/service/repo"
-	threadDelivery "github.com/Natali-Skv/technopark_db_forum/internal/thread/delivery/http"
-	threadRepository "github.com/Natali-Skv/technopark_db_forum/internal/thread/repo"
-	userDelivery "github.com/Natali-Skv/technopark_db_forum/internal/user/delivery/http"
-	userRepository "github.com/Natali-Skv/technopark_db_forum/internal/user/repo"
-	"github.com/jackc/pgx"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
-
-	"github.com/labstack/echo-contrib/pprof"
+	"github.com/Natali-Skv/technopark_db_forum/internal/bench"
+	"github.com/Natali-Skv/technopark_db_forum/internal/maintenance"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/logger"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/openapi"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
-	connStr := fmt.Sprintf("user=%s password=%s dbname=%s sslmode=disable port=%s",
-		config.DbConfig.User, config.DbConfig.Password, config.DbConfig.DBName, config.DbConfig.Port)
-	pgxConn, err := pgx.ParseConnectionString(connStr)
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(bench.Main(os.Args[2:]))
+	}
+
+	appLogger := logger.New(config.LogConfig)
+	shutdownTracing, err := tracing.Init(context.Background(), config.TraceConfig)
 	if err != nil {
-		log.Fatal(err.Error())
+		appLogger.Fatal().Err(err).Msg("init tracing")
 	}
-	pgxConn.PreferSimpleProtocol = true
-	config := pgx.ConnPoolConfig{
-		ConnConfig:     pgxConn,
-		MaxConnections: config.DbConfig.MaxConnections,
-		AfterConnect:   nil,
-		AcquireTimeout: 0,
+	spec, err := openapi.Load()
+	if err != nil {
+		shutdownTracing(context.Background())
+		appLogger.Fatal().Err(err).Msg("load openapi spec")
 	}
-	connPool, err := pgx.NewConnPool(config)
+	var storage configRouting.Repos
+	closeStorage := func() {}
+	switch config.DbConfig.Backend {
+	case config.BackendMemory:
+		storage = configRouting.MemoryRepos()
+	case config.BackendPostgres:
+		connPool, err := postgresPool()
+		if err != nil {
+			shutdownTracing(context.Background())
+			appLogger.Fatal().Err(err).Msg("connect to database")
+		}
+		storage = configRouting.PostgresRepos(connPool)
 
-	if err != nil {
-		log.Fatal(err.Error())
+		validateCtx, cancelValidate := context.WithTimeout(context.Background(), config.DbConfig.StatementTimeout)
+		err = connPool.Validate(validateCtx)
+		cancelValidate()
+		if err != nil {
+			for _, prepareErr := range dbconn.PrepareErrors(err) {
+				appLogger.Error().Err(prepareErr.Err).Str("statement", prepareErr.Name).Str("sql", prepareErr.SQL).Msg("prepare statement")
+			}
+			shutdownTracing(context.Background())
+			appLogger.Fatal().Err(err).Msg("validate statements")
+		}
+		closeStorage = connPool.Close
+	default:
+		shutdownTracing(context.Background())
+		appLogger.Fatal().Str("backend", config.DbConfig.Backend).Msg("unknown storage backend")
 	}
-	e := echo.New()
-	pprof.Register(e)
-	e.Use(middleware.Recover())
-	// e.Use(middleware.Logger())
-	userRepo := userRepository.NewRepo(connPool)
-	userHandler := userDelivery.NewHandler(userRepo)
-	forumRepo := forumRepository.NewRepo(connPool)
-	forumHandler := forumDelivery.NewHandler(forumRepo)
-	threadRepo := threadRepository.NewRepo(connPool)
-	threadHandler := threadDelivery.NewHandler(threadRepo)
-	postRepo := postRepository.NewRepo(connPool)
-	postHandler := postDelivery.NewHandler(postRepo)
-	servRepo := serviceRepository.NewRepo(connPool)
-	servHandler := serviceDelivery.NewHandler(servRepo)
+	appLogger.Info().Str("backend", config.DbConfig.Backend).Msg("storage ready")
+
+	scheduler := maintenance.NewScheduler(storage.Maintenance, config.MaintenanceConfig, appLogger)
+	e, handlers := configRouting.NewServer(storage, scheduler, spec, appLogger)
+	servHandler := handlers.ServiceHandler
 
-	handlers := configRouting.Handlers{
-		UserHandler:    userHandler,
-		ForumHandler:   forumHandler,
-		ThreadHandler:  threadHandler,
-		PostHandler:    postHandler,
-		ServiceHandler: servHandler,
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- e.Start(config.ServerConfig.Addr)
+	}()
+	scheduler.Start()
+	servHandler.SetReady(true)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err = <-serverErr:
+		shutdownTracing(context.Background())
+		appLogger.Fatal().Err(err).Msg("server stopped")
+	case sig := <-quit:
+		appLogger.Info().Str("signal", sig.String()).Msg("shutting down")
 	}
-	handlers.ConfigureRouting(e)
 
-	e.Logger.Fatal(e.Start(":5000"))
+	servHandler.SetReady(false)
+	time.Sleep(config.ServerConfig.ShutdownDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ServerConfig.ShutdownTimeout)
+	defer cancel()
+	if err = e.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error().Err(err).Msg("shutdown server")
+	}
+	scheduler.Stop()
+	closeStorage()
+	if err = shutdownTracing(shutdownCtx); err != nil {
+		appLogger.Error().Err(err).Msg("shutdown tracing")
+	}
+}
+
+func postgresPool() (*dbconn.Pool, error) {
+	connStr := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable port=%s",
+		config.DbConfig.Host, config.DbConfig.User, config.DbConfig.Password, config.DbConfig.DBName, config.DbConfig.Port)
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.MaxConns = int32(config.DbConfig.MaxConnections)
+	registry := dbconn.NewRegistry()
+	poolConfig.AfterConnect = registry.AfterConnect
+	pgxPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dbconn.NewPool(pgxPool, registry, config.LogConfig.SlowQueryThreshold, config.DbConfig.StatementTimeout), nil
 }
@@ -1,17 +1,251 @@
 package config
 
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	BackendPostgres = "postgres"
+	// BackendMemory keeps everything in process memory; nothing survives a restart.
+	BackendMemory = "memory"
+)
+
 type DbConfigStruct struct {
+	Backend        string
+	Host           string
 	User           string
 	Password       string
 	DBName         string
 	Port           string
 	MaxConnections int
+	SchemaVersion  int
+	// StatementTimeout bounds every single statement, on top of the request deadline.
+	StatementTimeout time.Duration
 }
 
 var DbConfig = DbConfigStruct{
+	Backend:        envOr("STORAGE_BACKEND", BackendPostgres),
+	Host:           "localhost",
 	User:           "docker",
 	Password:       "docker",
 	DBName:         "docker",
 	Port:           "5432",
 	MaxConnections: 1000,
+	SchemaVersion:  13,
+
+	StatementTimeout: 10 * time.Second,
+}
+
+type ServerConfigStruct struct {
+	Addr         string
+	ReadyTimeout time.Duration
+	// ShutdownDelay keeps serving after /readyz starts failing so that balancers can stop routing to us.
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+}
+
+var ServerConfig = ServerConfigStruct{
+	Addr:            ":5000",
+	ReadyTimeout:    time.Second,
+	ShutdownDelay:   3 * time.Second,
+	ShutdownTimeout: 10 * time.Second,
+}
+
+type DeadlineConfigStruct struct {
+	Default time.Duration
+	// Endpoints overrides Default per route, keyed by "METHOD /route/:param" as registered in configRouting.
+	Endpoints map[string]time.Duration
+}
+
+var DeadlineConfig = DeadlineConfigStruct{
+	Default: 5 * time.Second,
+	Endpoints: map[string]time.Duration{
+		"POST /api/thread/:slug/create":       15 * time.Second,
+		"POST /api/v2/threads/:id/posts":      15 * time.Second,
+		"POST /api/users/bulk":                time.Minute,
+		"POST /api/v2/users/bulk":             time.Minute,
+		"POST /api/user/:username/rename":     time.Minute,
+		"POST /api/v2/users/:nickname/rename": time.Minute,
+		"GET /api/user/:username/export":      time.Minute,
+		"GET /api/v2/users/:nickname/export":  time.Minute,
+		"DELETE /api/user/:username":          time.Minute,
+		"DELETE /api/v2/users/:nickname":      time.Minute,
+		"POST /api/service/clear":             time.Minute,
+		"POST /api/service/clear/votes":       time.Minute,
+		"POST /api/service/clear/forum/:slug": time.Minute,
+		"GET /api/service/diagnostics":        15 * time.Second,
+	},
+}
+
+type ReactionConfigStruct struct {
+	// Allowed are the reactions users can give posts and threads; REACTIONS overrides them, comma separated.
+	Allowed []string
+}
+
+var ReactionConfig = ReactionConfigStruct{
+	Allowed: strings.Split(envOr("REACTIONS", "like,dislike,laugh,hooray,confused,heart,rocket,eyes"), ","),
+}
+
+type LogConfigStruct struct {
+	Level string
+	// SampleN logs every N-th successful request; 4xx and 5xx responses and slow requests are always logged.
+	SampleN            uint32
+	SlowRequest        time.Duration
+	SlowQueryThreshold time.Duration
+}
+
+var LogConfig = LogConfigStruct{
+	Level:              "info",
+	SampleN:            100,
+	SlowRequest:        500 * time.Millisecond,
+	SlowQueryThreshold: 100 * time.Millisecond,
+}
+
+type TraceConfigStruct struct {
+	// Exporter is one of "otlp", "stdout", "file" or "" to disable tracing.
+	Exporter     string
+	OtlpEndpoint string
+	FilePath     string
+	SampleRatio  float64
+	ServiceName  string
+}
+
+var TraceConfig = TraceConfigStruct{
+	Exporter:     os.Getenv("TRACE_EXPORTER"),
+	OtlpEndpoint: "localhost:4318",
+	FilePath:     "traces.json",
+	SampleRatio:  1,
+	ServiceName:  "technopark_db_forum",
+}
+
+const (
+	// ProfileTest is what the functional test harness runs with: /api/service/clear is open without a token.
+	ProfileTest = "test"
+	ProfileProd = "prod"
+)
+
+type AdminConfigStruct struct {
+	Profile      string
+	Token        string
+	ClearEnabled bool
+}
+
+var AdminConfig = AdminConfigStruct{
+	Profile:      envOr("APP_PROFILE", ProfileProd),
+	Token:        os.Getenv("ADMIN_TOKEN"),
+	ClearEnabled: os.Getenv("CLEAR_ENABLED") == "true",
+}
+
+type MaintenanceTaskConfig struct {
+	Name       string
+	Statements []string
+	// Interval runs the task that long after its last run, 0 disables the time trigger.
+	Interval time.Duration
+	// RowGrowth runs the task once the live rows of Tables grew by that many since its last run.
+	RowGrowth int64
+	// DeadTupleRatio runs the task once dead/(live+dead) of one of Tables exceeds it;
+	// tables smaller than MinRows are ignored.
+	DeadTupleRatio float64
+	MinRows        int64
+	// Tables are watched by the row triggers, empty means all tables.
+	Tables []string
+}
+
+type MaintenanceConfigStruct struct {
+	// Automatic enables the triggers; tasks can be run from the admin endpoint either way.
+	Automatic     bool
+	CheckInterval time.Duration
+	// Timeout bounds one run of a task. Maintenance statements are not subject to the statement timeout.
+	Timeout time.Duration
+	// RetryDelay holds the triggers of a failed task back, so that a broken task does not run on every check.
+	RetryDelay time.Duration
+	Tasks      []MaintenanceTaskConfig
+}
+
+var MaintenanceConfig = MaintenanceConfigStruct{
+	Automatic:     envOr("MAINTENANCE_AUTOMATIC", "true") == "true",
+	CheckInterval: time.Minute,
+	Timeout:       time.Hour,
+	RetryDelay:    30 * time.Minute,
+	Tasks: []MaintenanceTaskConfig{
+		{
+			// reorders the tables along the indexes the hot queries scan, once the bulk of the data is there
+			Name: "cluster",
+			Statements: []string{
+				"CLUSTER users USING user_nick_idx",
+				"CLUSTER forums USING forum_slug_idx",
+				"CLUSTER forum_users USING forum_users_idx",
+				"CLUSTER threads USING thread_forum_created_idx",
+				"CLUSTER votes USING vote_full",
+				"CLUSTER posts USING post_thread_idx",
+				"SELECT pg_prewarm('forums')",
+				"SELECT pg_prewarm('users')",
+				"VACUUM ANALYZE",
+			},
+			RowGrowth: 1000000,
+			Tables:    []string{"posts"},
+		},
+		{
+			Name:           "vacuum_analyze",
+			Statements:     []string{"VACUUM ANALYZE"},
+			Interval:       6 * time.Hour,
+			DeadTupleRatio: 0.2,
+			MinRows:        10000,
+		},
+	},
+}
+
+type BenchConfigStruct struct {
+	URL        string
+	AdminToken string
+	// Clear wipes the server before filling it, so that runs with the same seed are comparable.
+	Clear bool
+	Seed  int64
+
+	Users   int
+	Forums  int
+	Threads int
+	Posts   int
+	Votes   int
+	// PostBatch is the number of posts per create request; MaxDepth caps the depth of post trees.
+	PostBatch int
+	MaxDepth  int
+	// UserBatch is the number of users per bulk create request.
+	UserBatch int
+
+	Concurrency int
+	Duration    time.Duration
+	// Requests stops the replay after that many requests instead of after Duration.
+	Requests int
+	// Mix weighs the replayed operations, "op=weight,...".
+	Mix string
+}
+
+var BenchConfig = BenchConfigStruct{
+	URL:        envOr("BENCH_URL", "http://localhost:5000/api"),
+	AdminToken: os.Getenv("ADMIN_TOKEN"),
+	Seed:       1,
+
+	Users:     500,
+	Forums:    10,
+	Threads:   500,
+	Posts:     20000,
+	Votes:     5000,
+	PostBatch: 100,
+	MaxDepth:  20,
+	UserBatch: 1000,
+
+	Concurrency: 8,
+	Duration:    30 * time.Second,
+	Mix: "thread_posts=30,thread_details=15,post_details=15,forum_threads=10,forum_users=5,forum_details=5," +
+		"user_profile=5,status=1,create_posts=9,vote=4,update_post=1",
+}
+
+func envOr(key string, def string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return def
 }
@@ -1,16 +1,22 @@
 package configRouting
 
 import (
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/apiv2"
 	forumHandler "github.com/Natali-Skv/technopark_db_forum/internal/forum/delivery/http"
+	maintenanceHandler "github.com/Natali-Skv/technopark_db_forum/internal/maintenance/delivery/http"
 	postHandler "github.com/Natali-Skv/technopark_db_forum/internal/post/delivery/http"
 	serviceHandler "github.com/Natali-Skv/technopark_db_forum/internal/service/delivery/http"
 	threadHandler "github.com/Natali-Skv/technopark_db_forum/internal/thread/delivery/http"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/auth"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/openapi"
 	userHandler "github.com/Natali-Skv/technopark_db_forum/internal/user/delivery/http"
 	"github.com/labstack/echo/v4"
 )
 
 const (
-	routerPrefix = "/api/"
+	routerPrefix   = "/api/"
+	routerV2Prefix = routerPrefix + "v2/"
 )
 
 type Handlers struct {
@@ -19,12 +25,23 @@ type Handlers struct {
 	ThreadHandler  *threadHandler.Handler
 	PostHandler    *postHandler.Handler
 	ServiceHandler *serviceHandler.Handler
+
+	MaintenanceHandler *maintenanceHandler.Handler
+	V2Handler          *apiv2.Handler
+	Spec               *openapi.Spec
 }
 
 func (hs *Handlers) ConfigureRouting(router *echo.Echo) {
+	admin := auth.Admin(config.AdminConfig)
 	router.POST(routerPrefix+"user/:"+userHandler.NickCtxKey+"/create", hs.UserHandler.CreateUser)
+	router.POST(routerPrefix+"users/bulk", hs.UserHandler.CreateUsers)
+	router.GET(routerPrefix+"users", hs.UserHandler.GetUsers)
 	router.GET(routerPrefix+"user/:"+userHandler.NickCtxKey+"/profile", hs.UserHandler.GetUser)
 	router.POST(routerPrefix+"user/:"+userHandler.NickCtxKey+"/profile", hs.UserHandler.UpdateUser)
+	router.POST(routerPrefix+"user/:"+userHandler.NickCtxKey+"/rename", hs.UserHandler.RenameUser)
+	router.GET(routerPrefix+"user/:"+userHandler.NickCtxKey+"/export", hs.UserHandler.ExportUser, admin)
+	router.GET(routerPrefix+"user/:"+userHandler.NickCtxKey+"/karma", hs.UserHandler.GetKarma)
+	router.DELETE(routerPrefix+"user/:"+userHandler.NickCtxKey, hs.UserHandler.DeleteUser, admin)
 	router.POST(routerPrefix+"forum/create", hs.ForumHandler.CreateForum)
 	router.GET(routerPrefix+"forum/:"+forumHandler.SlugCtxKey+"/details", hs.ForumHandler.GetForum)
 	router.GET(routerPrefix+"forum/:"+forumHandler.SlugCtxKey+"/threads", hs.ForumHandler.GetForumThreads)
@@ -32,6 +49,10 @@ func (hs *Handlers) ConfigureRouting(router *echo.Echo) {
 
 	router.POST(routerPrefix+"forum/:"+threadHandler.SlugCtxKey+"/create", hs.ThreadHandler.CreateThread)
 	router.POST(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/vote", hs.ThreadHandler.Vote)
+	router.DELETE(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/vote/:"+threadHandler.VoterCtxKey, hs.ThreadHandler.Unvote)
+	router.GET(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/votes", hs.ThreadHandler.GetVotes)
+	router.POST(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/reactions", hs.ThreadHandler.React)
+	router.DELETE(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/reactions/:"+threadHandler.VoterCtxKey+"/:"+threadHandler.ReactionCtxKey, hs.ThreadHandler.Unreact)
 	router.GET(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/details", hs.ThreadHandler.GetThread)
 	router.POST(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/details", hs.ThreadHandler.UpdateThread)
 
@@ -39,7 +60,64 @@ func (hs *Handlers) ConfigureRouting(router *echo.Echo) {
 	router.GET(routerPrefix+"thread/:"+threadHandler.SlugCtxKey+"/posts", hs.PostHandler.GetThreadPosts)
 	router.GET(routerPrefix+"post/:"+postHandler.IdCtxKey+"/details", hs.PostHandler.GetPost)
 	router.POST(routerPrefix+"post/:"+postHandler.IdCtxKey+"/details", hs.PostHandler.UpdatePost)
+	router.POST(routerPrefix+"post/:"+postHandler.IdCtxKey+"/vote", hs.PostHandler.Vote)
+	router.DELETE(routerPrefix+"post/:"+postHandler.IdCtxKey+"/vote/:"+postHandler.VoterCtxKey, hs.PostHandler.Unvote)
+	router.GET(routerPrefix+"post/:"+postHandler.IdCtxKey+"/votes", hs.PostHandler.GetVotes)
+	router.GET(routerPrefix+"post/:"+postHandler.IdCtxKey+"/subtree", hs.PostHandler.GetSubtree)
+	router.GET(routerPrefix+"post/:"+postHandler.IdCtxKey+"/ancestors", hs.PostHandler.GetAncestors)
+	router.GET(routerPrefix+"post/:"+postHandler.IdCtxKey+"/context", hs.PostHandler.GetContext)
+	router.POST(routerPrefix+"post/:"+postHandler.IdCtxKey+"/reactions", hs.PostHandler.React)
+	router.DELETE(routerPrefix+"post/:"+postHandler.IdCtxKey+"/reactions/:"+postHandler.VoterCtxKey+"/:"+postHandler.ReactionCtxKey, hs.PostHandler.Unreact)
 
 	router.GET(routerPrefix+"service/status", hs.ServiceHandler.Status)
-	router.POST(routerPrefix+"service/clear", hs.ServiceHandler.ClearDB)
+	router.GET(routerPrefix+"service/status/forums", hs.ServiceHandler.ForumsStatus)
+	router.POST(routerPrefix+"service/clear", hs.ServiceHandler.ClearDB, admin)
+	router.POST(routerPrefix+"service/clear/votes", hs.ServiceHandler.ClearVotes, admin)
+	router.POST(routerPrefix+"service/clear/forum/:"+serviceHandler.SlugCtxKey, hs.ServiceHandler.ClearForum, admin)
+	router.GET(routerPrefix+"service/diagnostics", hs.ServiceHandler.Diagnostics, admin)
+	router.GET(routerPrefix+"service/maintenance", hs.MaintenanceHandler.Status, admin)
+	router.POST(routerPrefix+"service/maintenance/:"+maintenanceHandler.TaskCtxKey, hs.MaintenanceHandler.RunTask, admin)
+
+	v2 := hs.V2Handler
+	router.HTTPErrorHandler = apiv2.ErrorHandler(routerV2Prefix, router.HTTPErrorHandler)
+	router.POST(routerV2Prefix+"users", v2.CreateUser)
+	router.POST(routerV2Prefix+"users/bulk", v2.CreateUsers)
+	router.GET(routerV2Prefix+"users", v2.GetUsers)
+	router.GET(routerV2Prefix+"users/:"+apiv2.NickCtxKey, v2.GetUser)
+	router.PATCH(routerV2Prefix+"users/:"+apiv2.NickCtxKey, v2.UpdateUser)
+	router.POST(routerV2Prefix+"users/:"+apiv2.NickCtxKey+"/rename", v2.RenameUser)
+	router.GET(routerV2Prefix+"users/:"+apiv2.NickCtxKey+"/export", v2.ExportUser, admin)
+	router.GET(routerV2Prefix+"users/:"+apiv2.NickCtxKey+"/karma", v2.GetKarma)
+	router.DELETE(routerV2Prefix+"users/:"+apiv2.NickCtxKey, v2.DeleteUser, admin)
+	router.POST(routerV2Prefix+"forums", v2.CreateForum)
+	router.GET(routerV2Prefix+"forums/:"+apiv2.SlugCtxKey, v2.GetForum)
+	router.GET(routerV2Prefix+"forums/:"+apiv2.SlugCtxKey+"/threads", v2.GetForumThreads)
+	router.POST(routerV2Prefix+"forums/:"+apiv2.SlugCtxKey+"/threads", v2.CreateThread)
+	router.GET(routerV2Prefix+"forums/:"+apiv2.SlugCtxKey+"/users", v2.GetForumUsers)
+	router.GET(routerV2Prefix+"threads/:"+apiv2.IdCtxKey, v2.GetThread)
+	router.PATCH(routerV2Prefix+"threads/:"+apiv2.IdCtxKey, v2.UpdateThread)
+	router.GET(routerV2Prefix+"threads/slug/:"+apiv2.SlugCtxKey, v2.GetThreadBySlug)
+	router.PUT(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/votes/:"+apiv2.VoterCtxKey, v2.Vote)
+	router.DELETE(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/votes/:"+apiv2.VoterCtxKey, v2.Unvote)
+	router.GET(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/votes", v2.GetThreadVotes)
+	router.PUT(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/reactions/:"+apiv2.VoterCtxKey+"/:"+apiv2.ReactionCtxKey, v2.ReactToThread)
+	router.DELETE(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/reactions/:"+apiv2.VoterCtxKey+"/:"+apiv2.ReactionCtxKey, v2.UnreactToThread)
+	router.POST(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/posts", v2.CreatePosts)
+	router.GET(routerV2Prefix+"threads/:"+apiv2.IdCtxKey+"/posts", v2.GetThreadPosts)
+	router.GET(routerV2Prefix+"posts/:"+apiv2.IdCtxKey, v2.GetPost)
+	router.PATCH(routerV2Prefix+"posts/:"+apiv2.IdCtxKey, v2.UpdatePost)
+	router.PUT(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/votes/:"+apiv2.VoterCtxKey, v2.VotePost)
+	router.DELETE(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/votes/:"+apiv2.VoterCtxKey, v2.UnvotePost)
+	router.GET(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/votes", v2.GetPostVotes)
+	router.GET(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/subtree", v2.GetPostSubtree)
+	router.GET(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/ancestors", v2.GetPostAncestors)
+	router.GET(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/context", v2.GetPostContext)
+	router.PUT(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/reactions/:"+apiv2.VoterCtxKey+"/:"+apiv2.ReactionCtxKey, v2.ReactToPost)
+	router.DELETE(routerV2Prefix+"posts/:"+apiv2.IdCtxKey+"/reactions/:"+apiv2.VoterCtxKey+"/:"+apiv2.ReactionCtxKey, v2.UnreactToPost)
+
+	router.GET(routerPrefix+"openapi.json", hs.Spec.ServeJSON)
+	router.GET(routerPrefix+"docs", hs.Spec.ServeUI)
+
+	router.GET("/healthz", hs.ServiceHandler.Healthz)
+	router.GET("/readyz", hs.ServiceHandler.Readyz)
 }
@@ -0,0 +1,97 @@
+package configRouting
+
+import (
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/apiv2"
+	"github.com/Natali-Skv/technopark_db_forum/internal/forum"
+	forumHandler "github.com/Natali-Skv/technopark_db_forum/internal/forum/delivery/http"
+	forumRepository "github.com/Natali-Skv/technopark_db_forum/internal/forum/repo"
+	"github.com/Natali-Skv/technopark_db_forum/internal/maintenance"
+	maintenanceHandler "github.com/Natali-Skv/technopark_db_forum/internal/maintenance/delivery/http"
+	maintenanceRepository "github.com/Natali-Skv/technopark_db_forum/internal/maintenance/repo"
+	"github.com/Natali-Skv/technopark_db_forum/internal/memory"
+	postRepo "github.com/Natali-Skv/technopark_db_forum/internal/post"
+	postHandler "github.com/Natali-Skv/technopark_db_forum/internal/post/delivery/http"
+	postRepository "github.com/Natali-Skv/technopark_db_forum/internal/post/repo"
+	"github.com/Natali-Skv/technopark_db_forum/internal/service"
+	serviceHandler "github.com/Natali-Skv/technopark_db_forum/internal/service/delivery/http"
+	serviceRepository "github.com/Natali-Skv/technopark_db_forum/internal/service/repo"
+	"github.com/Natali-Skv/technopark_db_forum/internal/thread"
+	threadHandler "github.com/Natali-Skv/technopark_db_forum/internal/thread/delivery/http"
+	threadRepository "github.com/Natali-Skv/technopark_db_forum/internal/thread/repo"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/logger"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/openapi"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
+	"github.com/Natali-Skv/technopark_db_forum/internal/user"
+	userHandler "github.com/Natali-Skv/technopark_db_forum/internal/user/delivery/http"
+	userRepository "github.com/Natali-Skv/technopark_db_forum/internal/user/repo"
+	"github.com/labstack/echo-contrib/pprof"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog"
+)
+
+// Repos is the storage of the handlers, all from one backend.
+type Repos struct {
+	User    user.Repo
+	Forum   forum.Repo
+	Thread  thread.Repo
+	Post    postRepo.Repo
+	Service service.Repo
+
+	Maintenance maintenance.Repo
+}
+
+func PostgresRepos(connPool *dbconn.Pool) Repos {
+	return Repos{
+		User:    userRepository.NewRepo(connPool),
+		Forum:   forumRepository.NewRepo(connPool),
+		Thread:  threadRepository.NewRepo(connPool),
+		Post:    postRepository.NewRepo(connPool),
+		Service: serviceRepository.NewRepo(connPool),
+
+		Maintenance: maintenanceRepository.NewRepo(connPool),
+	}
+}
+
+func MemoryRepos() Repos {
+	store := memory.NewStore()
+	return Repos{
+		User:    memory.NewUserRepo(store),
+		Forum:   memory.NewForumRepo(store),
+		Thread:  memory.NewThreadRepo(store),
+		Post:    memory.NewPostRepo(store),
+		Service: memory.NewServiceRepo(store),
+
+		Maintenance: memory.NewMaintenanceRepo(store),
+	}
+}
+
+// NewServer builds the API as main serves it: the middleware chain and the handlers over repos with their routes.
+// The caller starts and stops the scheduler and marks the service handler ready.
+func NewServer(repos Repos, scheduler *maintenance.Scheduler, spec *openapi.Spec, appLogger zerolog.Logger) (*echo.Echo, *Handlers) {
+	e := echo.New()
+	pprof.Register(e)
+	e.Use(middleware.RequestID())
+	e.Use(tracing.Middleware())
+	e.Use(logger.Middleware(appLogger, config.LogConfig))
+	e.Use(deadline.Middleware(config.DeadlineConfig))
+	e.Use(spec.Middleware())
+	e.Use(middleware.Recover())
+
+	handlers := &Handlers{
+		UserHandler:    userHandler.NewHandler(repos.User),
+		ForumHandler:   forumHandler.NewHandler(repos.Forum),
+		ThreadHandler:  threadHandler.NewHandler(repos.Thread),
+		PostHandler:    postHandler.NewHandler(repos.Post),
+		ServiceHandler: serviceHandler.NewHandler(repos.Service),
+
+		MaintenanceHandler: maintenanceHandler.NewHandler(scheduler),
+		V2Handler:          apiv2.NewHandler(repos.User, repos.Forum, repos.Thread, repos.Post),
+		Spec:               spec,
+	}
+	handlers.ConfigureRouting(e)
+	return e, handlers
+}
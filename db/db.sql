@@ -8,6 +8,13 @@ DROP TABLE IF EXISTS forums CASCADE;
 DROP TABLE IF EXISTS threads CASCADE;
 DROP TABLE IF EXISTS posts CASCADE;
 DROP TABLE IF EXISTS votes CASCADE;
+DROP TABLE IF EXISTS post_votes CASCADE;
+DROP TABLE IF EXISTS post_reactions CASCADE;
+DROP TABLE IF EXISTS thread_reactions CASCADE;
+DROP TABLE IF EXISTS thread_participants CASCADE;
+DROP TABLE IF EXISTS user_renames CASCADE;
+DROP TABLE IF EXISTS schema_version CASCADE;
+DROP TABLE IF EXISTS stats CASCADE;
 DEALLOCATE ALL;
 
 CREATE UNLOGGED TABLE users
@@ -24,29 +31,46 @@ CREATE UNLOGGED TABLE forums
     id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     slug citext UNIQUE NOT NULL,
     title text,
-    author_nick citext COLLATE "C" REFERENCES users(nick) NOT NULL,
+    author_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
     threads integer DEFAULT 0,
-    posts integer DEFAULT 0
+    posts integer DEFAULT 0,
+    votes integer DEFAULT 0,
+    users integer DEFAULT 0,
+    edited_posts integer DEFAULT 0,
+    deleted_posts integer DEFAULT 0
 );
 
+-- the rank of sort=hot, kept in step with forum.Hotness
+CREATE OR REPLACE FUNCTION thread_hotness(votes integer, created timestamptz) RETURNS double precision AS
+$$
+    SELECT (sign(votes) * log(greatest(abs(votes), 1)) + extract(epoch FROM created) / 45000)::double precision
+$$ LANGUAGE sql IMMUTABLE;
+
 CREATE UNLOGGED TABLE threads 
 (
     id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     slug citext UNIQUE,
     title text,
-    author_nick citext COLLATE "C" REFERENCES users(nick) NOT NULL,
+    author_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
     forum_id BIGINT REFERENCES forums NOT NULL,
     forum_slug citext REFERENCES forums(slug) NOT NULL,
     message text,
     votes integer DEFAULT 0,
-    created timestamp with time zone DEFAULT now()
+    created timestamp with time zone DEFAULT now(),
+    -- the time of the last post, or of the thread itself until it has one
+    last_post_at timestamp with time zone,
+    hot double precision GENERATED ALWAYS AS (thread_hotness(votes, created)) STORED,
+    -- kept by insert_posts_tg, like last_post_at
+    posts_count integer NOT NULL DEFAULT 0,
+    last_post_author citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE,
+    participants_count integer NOT NULL DEFAULT 0
 );
 
 CREATE UNLOGGED TABLE posts
 (
     id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
     author_id BIGINT REFERENCES users NOT NULL,
-    author_nick citext COLLATE "C" REFERENCES users(nick) NOT NULL,
+    author_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
 	parent_id BIGINT REFERENCES posts,
     description text,
     message text,
@@ -56,26 +80,121 @@ CREATE UNLOGGED TABLE posts
     thread_id integer REFERENCES threads NOT NULL,
     thread_slug citext,
     created timestamp with time zone DEFAULT now(),
-    path BIGINT[] default array []::INTEGER[]
+    path BIGINT[] default array []::INTEGER[],
+    votes integer NOT NULL DEFAULT 0,
+    -- the direct replies, counted by insert_posts_tg; the posts under this one are counted by post_descendants
+    children integer NOT NULL DEFAULT 0,
+    -- the highest position among its siblings of any reply on the path down to this post, roots are 0;
+    -- a tree cut at n children per post keeps the posts with branch_rank <= n. The positions follow the order the
+    -- replies took the parent lock in, so under concurrent replies they may not follow id or created
+    branch_rank integer NOT NULL DEFAULT 0
 );
 
+-- the size of the subtree under a post: its posts sort between path and path || max bigint,
+-- so this is a range count over post_thread_path_idx instead of a counter every reply locks up to the root
+CREATE OR REPLACE FUNCTION post_descendants(thread_id integer, path bigint[]) RETURNS integer AS
+$$
+    SELECT count(*)::integer FROM posts p WHERE p.thread_id = $1 AND p.path > $2 AND p.path < $2 || 9223372036854775807::bigint
+$$ LANGUAGE sql STABLE;
+
 CREATE UNLOGGED TABLE votes 
 (
-    user_nick citext COLLATE "C" REFERENCES users(nick) NOT NULL,
+    user_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
 	thread_id BIGINT REFERENCES threads NOT NULL,
-    vote integer NOT NULL,
+    vote integer NOT NULL CHECK (vote IN (-1, 1)),
     UNIQUE (user_nick, thread_id)
 );
 
+CREATE UNLOGGED TABLE post_votes
+(
+    user_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
+    post_id BIGINT REFERENCES posts NOT NULL,
+    vote integer NOT NULL CHECK (vote IN (-1, 1)),
+    UNIQUE (user_nick, post_id)
+);
+
+-- a user can give a post or a thread each reaction of the allowed set once; the counts are aggregated on read
+CREATE UNLOGGED TABLE post_reactions
+(
+    user_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
+    post_id BIGINT REFERENCES posts NOT NULL,
+    reaction text NOT NULL,
+    UNIQUE (post_id, reaction, user_nick)
+);
+
+CREATE UNLOGGED TABLE thread_reactions
+(
+    user_nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
+    thread_id BIGINT REFERENCES threads NOT NULL,
+    reaction text NOT NULL,
+    UNIQUE (thread_id, reaction, user_nick)
+);
+
+-- the distinct authors of the posts of each thread, behind threads.participants_count
+CREATE UNLOGGED TABLE thread_participants
+(
+    thread_id BIGINT REFERENCES threads NOT NULL,
+    nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
+    UNIQUE (thread_id, nick)
+);
+
+-- who wrote in a forum and how much; the profile itself is joined from users, so it is never stale
 CREATE UNLOGGED TABLE forum_users 
 (
-    nick citext COLLATE "C" REFERENCES users(nick) NOT NULL,
-    email citext NOT NULL,
-    name text,
-    about text,
+    nick citext COLLATE "C" REFERENCES users(nick) ON UPDATE CASCADE NOT NULL,
     forum_slug citext REFERENCES forums(slug) NOT NULL,
+    posts integer NOT NULL DEFAULT 0,
+    threads integer NOT NULL DEFAULT 0,
+    first_activity timestamp with time zone NOT NULL,
+    last_activity timestamp with time zone NOT NULL,
     UNIQUE (nick, forum_slug)
 );
+
+-- old nicknames of renamed users, so that lookups by them can be redirected
+CREATE UNLOGGED TABLE user_renames
+(
+    old_nick citext COLLATE "C" PRIMARY KEY,
+    user_id BIGINT REFERENCES users ON DELETE CASCADE NOT NULL
+);
+
+CREATE TABLE schema_version
+(
+    version integer NOT NULL
+);
+INSERT INTO schema_version(version) VALUES (13);
+
+-- global counters, maintained by the statement level triggers below. They are split over 16 slot rows summed on read,
+-- so that concurrent writers mostly update different rows: each session writes the slot of its backend pid.
+CREATE UNLOGGED TABLE stats
+(
+    slot smallint PRIMARY KEY CHECK (slot >= 0 AND slot < 16),
+    users bigint NOT NULL DEFAULT 0,
+    forums bigint NOT NULL DEFAULT 0,
+    threads bigint NOT NULL DEFAULT 0,
+    posts bigint NOT NULL DEFAULT 0,
+    votes bigint NOT NULL DEFAULT 0,
+    forum_users bigint NOT NULL DEFAULT 0,
+    edited_posts bigint NOT NULL DEFAULT 0,
+    deleted_posts bigint NOT NULL DEFAULT 0
+);
+INSERT INTO stats(slot) SELECT generate_series(0, 15);
+
+CREATE OR REPLACE FUNCTION stats_slot() RETURNS smallint AS
+$$
+    SELECT (pg_backend_pid() % 16)::smallint
+$$ LANGUAGE sql STABLE;
+
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    action text NOT NULL,
+    target text,
+    outcome text NOT NULL,
+    request_id text,
+    remote_addr text,
+    created timestamp with time zone DEFAULT now()
+);
+
 ---------------------------FUNCTIONS----------------------------
 CREATE OR REPLACE FUNCTION get_author_nick() RETURNS TRIGGER AS
 $$
@@ -101,8 +220,11 @@ $$ LANGUAGE plpgsql;
 
 CREATE OR REPLACE FUNCTION insert_vote_to_thread() RETURNS TRIGGER AS
 $$
+DECLARE
+thread_forum_id bigint;
 BEGIN
-    UPDATE threads SET votes = votes + NEW.vote WHERE id = NEW.thread_id;
+    UPDATE threads SET votes = votes + NEW.vote WHERE id = NEW.thread_id RETURNING forum_id INTO thread_forum_id;
+    UPDATE forums SET votes = votes + 1 WHERE id = thread_forum_id;
     RETURN NEW;
 END
 $$ LANGUAGE plpgsql;
@@ -115,15 +237,34 @@ BEGIN
 END
 $$ LANGUAGE plpgsql;
 
+-- the forum and global vote counters are kept by count_votes_tg
+CREATE OR REPLACE FUNCTION delete_vote_from_thread() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE threads SET votes = votes - OLD.vote WHERE id = OLD.thread_id;
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION post_votes_tg() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE posts SET votes = votes + NEW.vote WHERE id = NEW.post_id;
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE posts SET votes = votes - OLD.vote + NEW.vote WHERE id = NEW.post_id;
+    ELSE
+        UPDATE posts SET votes = votes - OLD.vote WHERE id = OLD.post_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
 
 CREATE OR REPLACE FUNCTION insert_threads_tg() RETURNS TRIGGER AS
 $$
-DECLARE
-author_email text;
-author_name text;
-author_about text;
 BEGIN
-    SELECT nick,email,name,about INTO NEW.author_nick,author_email,author_name,author_about FROM users WHERE nick=NEW.author_nick;
+    SELECT nick INTO NEW.author_nick FROM users WHERE nick=NEW.author_nick;
     IF NOT FOUND THEN
         RAISE EXCEPTION USING ERRCODE = 'AAAA1';
     END IF;
@@ -131,7 +272,11 @@ BEGIN
     IF NOT FOUND THEN
         RAISE EXCEPTION USING ERRCODE = 'AAAA3';
     END IF;
-    INSERT INTO forum_users(nick,email,name,about,forum_slug) VALUES(NEW.author_nick,author_email,author_name,author_about,NEW.forum_slug) ON CONFLICT DO NOTHING;
+    NEW.last_post_at = NEW.created;
+    INSERT INTO forum_users(nick,forum_slug,threads,first_activity,last_activity) VALUES(NEW.author_nick,NEW.forum_slug,1,NEW.created,NEW.created)
+    ON CONFLICT (nick, forum_slug) DO UPDATE SET threads = forum_users.threads + 1,
+        first_activity = LEAST(forum_users.first_activity, EXCLUDED.first_activity),
+        last_activity = GREATEST(forum_users.last_activity, EXCLUDED.last_activity);
    RETURN NEW;
 END
 $$ LANGUAGE plpgsql;
@@ -141,29 +286,41 @@ $$
 DECLARE
 parent_path bigint[];
 correct_parent boolean;
-author_email text;
-author_name text;
-author_about text;
+new_participant boolean;
 BEGIN
-    SELECT nick,email,name,about,id INTO NEW.author_nick,author_email,author_name,author_about,NEW.author_id FROM users WHERE nick=NEW.author_nick;
+    SELECT nick,id INTO NEW.author_nick,NEW.author_id FROM users WHERE nick=NEW.author_nick;
     IF NOT FOUND THEN
         RAISE EXCEPTION USING ERRCODE = 'AAAA1';
         RETURN NULL;
     END IF;
-    UPDATE forums SET posts = posts + 1 WHERE id = NEW.forum_id;
+    INSERT INTO thread_participants(thread_id, nick) VALUES (NEW.thread_id, NEW.author_nick) ON CONFLICT DO NOTHING;
+    new_participant = FOUND;
+    -- the locks go thread, parent post, forum: the thread before its forum as in insert_vote_to_thread,
+    -- the post before its forum as in update_posts_tg
+    UPDATE threads SET posts_count = posts_count + 1,
+        participants_count = participants_count + new_participant::integer,
+        last_post_author = CASE WHEN NEW.created >= last_post_at THEN NEW.author_nick ELSE last_post_author END,
+        last_post_at = GREATEST(last_post_at, NEW.created)
+    WHERE id = NEW.thread_id;
     IF NEW.parent_id != 0 THEN 
         SELECT thread_id=NEW.thread_id INTO correct_parent FROM posts WHERE id = NEW.parent_id;
         IF NOT FOUND OR NOT correct_parent  THEN
             RAISE EXCEPTION USING ERRCODE = 'AAAA0';
             RETURN NULL;
         END IF; 
-        SELECT path FROM posts WHERE id=NEW.parent_id INTO parent_path;
+        -- the parent row lock orders concurrent replies, so each gets its own position among the siblings
+        UPDATE posts SET children = children + 1 WHERE id = NEW.parent_id
+        RETURNING path, GREATEST(branch_rank, children) INTO parent_path, NEW.branch_rank;
         NEW.path = parent_path || NEW.id;
     ELSE    
         NEW.parent_id=NULL;
         NEW.path = ARRAY[NEW.id];
     END IF;
-    INSERT INTO forum_users(nick,email,name,about,forum_slug) VALUES(NEW.author_nick,author_email,author_name,author_about,NEW.forum_slug) ON CONFLICT DO NOTHING;
+    UPDATE forums SET posts = posts + 1 WHERE id = NEW.forum_id;
+    INSERT INTO forum_users(nick,forum_slug,posts,first_activity,last_activity) VALUES(NEW.author_nick,NEW.forum_slug,1,NEW.created,NEW.created)
+    ON CONFLICT (nick, forum_slug) DO UPDATE SET posts = forum_users.posts + 1,
+        first_activity = LEAST(forum_users.first_activity, EXCLUDED.first_activity),
+        last_activity = GREATEST(forum_users.last_activity, EXCLUDED.last_activity);
     RETURN NEW;
 END;
 $$ LANGUAGE plpgsql;
@@ -172,13 +329,150 @@ $$ LANGUAGE plpgsql;
 CREATE OR REPLACE FUNCTION update_posts_tg() RETURNS TRIGGER AS
 $$
 BEGIN
+    -- NEW, not OLD: a nickname rename cascades here and must not be dropped
     IF OLD.message = NEW.message THEN
-        RETURN OLD;
+        NEW.is_edited = OLD.is_edited;
+        RETURN NEW;
+    END IF;
+    IF NEW.is_edited AND NOT OLD.is_edited THEN
+        UPDATE forums SET edited_posts = edited_posts + 1 WHERE id = NEW.forum_id;
+        UPDATE stats SET edited_posts = edited_posts + 1 WHERE slot = stats_slot();
     END IF;
     RETURN NEW;
 END
 $$ LANGUAGE plpgsql;
 
+-- Statement level counters: one stats update per INSERT/DELETE statement that changed rows, not per row.
+CREATE OR REPLACE FUNCTION count_users_tg() RETURNS TRIGGER AS
+$$
+DECLARE
+cnt bigint;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) INTO cnt FROM new_rows;
+    ELSE
+        SELECT -count(*) INTO cnt FROM old_rows;
+    END IF;
+    IF cnt = 0 THEN
+        RETURN NULL;
+    END IF;
+    UPDATE stats SET users = users + cnt WHERE slot = stats_slot();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION count_forums_tg() RETURNS TRIGGER AS
+$$
+DECLARE
+cnt bigint;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) INTO cnt FROM new_rows;
+    ELSE
+        SELECT -count(*) INTO cnt FROM old_rows;
+    END IF;
+    IF cnt = 0 THEN
+        RETURN NULL;
+    END IF;
+    UPDATE stats SET forums = forums + cnt WHERE slot = stats_slot();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION count_threads_tg() RETURNS TRIGGER AS
+$$
+DECLARE
+cnt bigint;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) INTO cnt FROM new_rows;
+    ELSE
+        SELECT -count(*) INTO cnt FROM old_rows;
+    END IF;
+    IF cnt = 0 THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        UPDATE forums f SET threads = f.threads - d.cnt
+        FROM (SELECT forum_id, count(*) AS cnt FROM old_rows GROUP BY forum_id) d WHERE f.id = d.forum_id;
+    END IF;
+    UPDATE stats SET threads = threads + cnt WHERE slot = stats_slot();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION count_posts_tg() RETURNS TRIGGER AS
+$$
+DECLARE
+cnt bigint;
+edited bigint;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) INTO cnt FROM new_rows;
+        IF cnt != 0 THEN
+            UPDATE stats SET posts = posts + cnt WHERE slot = stats_slot();
+        END IF;
+        RETURN NULL;
+    END IF;
+    SELECT count(*), count(*) FILTER (WHERE is_edited) INTO cnt, edited FROM old_rows;
+    IF cnt = 0 THEN
+        RETURN NULL;
+    END IF;
+    UPDATE forums f SET posts = f.posts - d.cnt, edited_posts = f.edited_posts - d.edited, deleted_posts = f.deleted_posts + d.cnt
+    FROM (SELECT forum_id, count(*) AS cnt, count(*) FILTER (WHERE is_edited) AS edited FROM old_rows GROUP BY forum_id) d WHERE f.id = d.forum_id;
+    UPDATE stats SET posts = posts - cnt, edited_posts = edited_posts - edited, deleted_posts = deleted_posts + cnt
+    WHERE slot = stats_slot();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION count_votes_tg() RETURNS TRIGGER AS
+$$
+DECLARE
+cnt bigint;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) INTO cnt FROM new_rows;
+    ELSE
+        SELECT -count(*) INTO cnt FROM old_rows;
+    END IF;
+    IF cnt = 0 THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        UPDATE forums f SET votes = f.votes - d.cnt
+        FROM (SELECT t.forum_id, count(*) AS cnt FROM old_rows o JOIN threads t ON t.id = o.thread_id GROUP BY t.forum_id) d WHERE f.id = d.forum_id;
+    END IF;
+    UPDATE stats SET votes = votes + cnt WHERE slot = stats_slot();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION count_forum_users_tg() RETURNS TRIGGER AS
+$$
+DECLARE
+cnt bigint;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        SELECT count(*) INTO cnt FROM new_rows;
+        IF cnt = 0 THEN
+            RETURN NULL;
+        END IF;
+        UPDATE forums f SET users = f.users + n.cnt
+        FROM (SELECT forum_slug, count(*) AS cnt FROM new_rows GROUP BY forum_slug) n WHERE f.slug = n.forum_slug;
+    ELSE
+        SELECT -count(*) INTO cnt FROM old_rows;
+        IF cnt = 0 THEN
+            RETURN NULL;
+        END IF;
+        UPDATE forums f SET users = f.users - d.cnt
+        FROM (SELECT forum_slug, count(*) AS cnt FROM old_rows GROUP BY forum_slug) d WHERE f.slug = d.forum_slug;
+    END IF;
+    UPDATE stats SET forum_users = forum_users + cnt WHERE slot = stats_slot();
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
 ---------------------------TRIGGERS-----------------------------
 CREATE TRIGGER insert_vote_to_thread_tg AFTER INSERT ON votes
 FOR EACH ROW EXECUTE FUNCTION insert_vote_to_thread();
@@ -186,9 +480,24 @@ FOR EACH ROW EXECUTE FUNCTION insert_vote_to_thread();
 CREATE TRIGGER update_vote_to_thread_tg AFTER UPDATE ON votes
 FOR EACH ROW EXECUTE FUNCTION update_vote_to_thread();
 
+CREATE TRIGGER delete_vote_from_thread_tg AFTER DELETE ON votes
+FOR EACH ROW EXECUTE FUNCTION delete_vote_from_thread();
+
 CREATE TRIGGER get_user_nick_tg BEFORE INSERT ON votes
 FOR EACH ROW EXECUTE FUNCTION get_user_nick();
 
+CREATE TRIGGER get_post_voter_nick_tg BEFORE INSERT ON post_votes
+FOR EACH ROW EXECUTE FUNCTION get_user_nick();
+
+CREATE TRIGGER post_votes_tg AFTER INSERT OR UPDATE OR DELETE ON post_votes
+FOR EACH ROW EXECUTE FUNCTION post_votes_tg();
+
+CREATE TRIGGER get_post_reactor_nick_tg BEFORE INSERT ON post_reactions
+FOR EACH ROW EXECUTE FUNCTION get_user_nick();
+
+CREATE TRIGGER get_thread_reactor_nick_tg BEFORE INSERT ON thread_reactions
+FOR EACH ROW EXECUTE FUNCTION get_user_nick();
+
 CREATE TRIGGER threads_tg BEFORE INSERT ON threads
 FOR EACH ROW EXECUTE FUNCTION insert_threads_tg();
 
@@ -201,6 +510,36 @@ FOR EACH ROW EXECUTE FUNCTION update_posts_tg();
 CREATE TRIGGER get_author_nick_tg BEFORE INSERT ON forums
 FOR EACH ROW EXECUTE FUNCTION get_author_nick();
 
+CREATE TRIGGER count_users_insert_tg AFTER INSERT ON users
+REFERENCING NEW TABLE AS new_rows FOR EACH STATEMENT EXECUTE FUNCTION count_users_tg();
+CREATE TRIGGER count_users_delete_tg AFTER DELETE ON users
+REFERENCING OLD TABLE AS old_rows FOR EACH STATEMENT EXECUTE FUNCTION count_users_tg();
+
+CREATE TRIGGER count_forums_insert_tg AFTER INSERT ON forums
+REFERENCING NEW TABLE AS new_rows FOR EACH STATEMENT EXECUTE FUNCTION count_forums_tg();
+CREATE TRIGGER count_forums_delete_tg AFTER DELETE ON forums
+REFERENCING OLD TABLE AS old_rows FOR EACH STATEMENT EXECUTE FUNCTION count_forums_tg();
+
+CREATE TRIGGER count_threads_insert_tg AFTER INSERT ON threads
+REFERENCING NEW TABLE AS new_rows FOR EACH STATEMENT EXECUTE FUNCTION count_threads_tg();
+CREATE TRIGGER count_threads_delete_tg AFTER DELETE ON threads
+REFERENCING OLD TABLE AS old_rows FOR EACH STATEMENT EXECUTE FUNCTION count_threads_tg();
+
+CREATE TRIGGER count_posts_insert_tg AFTER INSERT ON posts
+REFERENCING NEW TABLE AS new_rows FOR EACH STATEMENT EXECUTE FUNCTION count_posts_tg();
+CREATE TRIGGER count_posts_delete_tg AFTER DELETE ON posts
+REFERENCING OLD TABLE AS old_rows FOR EACH STATEMENT EXECUTE FUNCTION count_posts_tg();
+
+CREATE TRIGGER count_votes_insert_tg AFTER INSERT ON votes
+REFERENCING NEW TABLE AS new_rows FOR EACH STATEMENT EXECUTE FUNCTION count_votes_tg();
+CREATE TRIGGER count_votes_delete_tg AFTER DELETE ON votes
+REFERENCING OLD TABLE AS old_rows FOR EACH STATEMENT EXECUTE FUNCTION count_votes_tg();
+
+CREATE TRIGGER count_forum_users_insert_tg AFTER INSERT ON forum_users
+REFERENCING NEW TABLE AS new_rows FOR EACH STATEMENT EXECUTE FUNCTION count_forum_users_tg();
+CREATE TRIGGER count_forum_users_delete_tg AFTER DELETE ON forum_users
+REFERENCING OLD TABLE AS old_rows FOR EACH STATEMENT EXECUTE FUNCTION count_forum_users_tg();
+
 ---------------------------INDEXES-----------------------------
 
 CREATE INDEX IF NOT EXISTS user_nick_idx ON users (nick);
@@ -211,16 +550,30 @@ CREATE INDEX IF NOT EXISTS thread_slug_idx ON threads USING hash(slug);
 
 CREATE INDEX IF NOT EXISTS thread_forum_slug_idx ON threads (forum_slug); 
 CREATE INDEX IF NOT EXISTS thread_forum_created_idx ON threads (forum_slug, created);
+-- keyset pages of the hot, top and active sorts; top over a short period may rather filter thread_forum_created_idx
+CREATE INDEX IF NOT EXISTS thread_forum_hot_idx ON threads (forum_slug, hot, id);
+CREATE INDEX IF NOT EXISTS thread_forum_top_idx ON threads (forum_slug, votes, id);
+CREATE INDEX IF NOT EXISTS thread_forum_active_idx ON threads (forum_slug, last_post_at, id);
+-- renames, exports and deletions of users find what they wrote by author
+CREATE INDEX IF NOT EXISTS thread_author_idx ON threads (author_nick);
+CREATE INDEX IF NOT EXISTS thread_last_post_author_idx ON threads (last_post_author);
+CREATE INDEX IF NOT EXISTS thread_participant_nick_idx ON thread_participants (nick);
 
 CREATE INDEX IF NOT EXISTS post_thread_idx ON posts (thread_id);
 CREATE INDEX IF NOT EXISTS post_created_id_idx ON posts (created,id);
 CREATE INDEX IF NOT EXISTS post_thread_created_id_idx ON posts (thread_id,created,id);
 CREATE INDEX IF NOT EXISTS post_thread_path_idx ON posts (thread_id, path);
 CREATE INDEX IF NOT EXISTS post_thread_path1_idx ON posts (thread_id,(path[1]), path);
+CREATE INDEX IF NOT EXISTS post_author_idx ON posts (author_nick);
 
 CREATE INDEX IF NOT EXISTS forum_users_idx ON forum_users (forum_slug, nick);
+CREATE INDEX IF NOT EXISTS forum_users_activity_idx ON forum_users (forum_slug, last_activity, nick);
 
 CREATE UNIQUE INDEX IF NOT EXISTS vote ON votes (user_nick, thread_id);
 CREATE UNIQUE INDEX IF NOT EXISTS vote_full ON votes (user_nick, thread_id, vote); 
+CREATE INDEX IF NOT EXISTS vote_thread_idx ON votes (thread_id, user_nick);
+CREATE INDEX IF NOT EXISTS post_vote_post_idx ON post_votes (post_id, user_nick);
+CREATE INDEX IF NOT EXISTS post_reaction_user_idx ON post_reactions (user_nick);
+CREATE INDEX IF NOT EXISTS thread_reaction_user_idx ON thread_reactions (user_nick);
 
 VACUUM;
\ No newline at end of file
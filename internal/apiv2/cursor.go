@@ -0,0 +1,37 @@
+package apiv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+)
+
+// cursor is where the next page starts, in terms of the since parameter the repos take.
+// Skip is for lists ordered by a value that is not unique: the items at the since value
+// that earlier pages already returned.
+type cursor struct {
+	Since string `json:"s"`
+	Skip  int    `json:"k,omitempty"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	c := cursor{}
+	if value == "" {
+		return c, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Skip < 0 {
+		return cursor{}, newError(http.StatusBadRequest, CodeInvalidCursor, errors.BAD_CURSOR)
+	}
+	return c, nil
+}
@@ -0,0 +1,148 @@
+package apiv2
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/labstack/echo/v4"
+)
+
+// Error codes of v2. Clients switch on the code; the message is for humans.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidCursor    = "invalid_cursor"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUserNotFound     = "user_not_found"
+	CodeForumNotFound    = "forum_not_found"
+	CodeThreadNotFound   = "thread_not_found"
+	CodePostNotFound     = "post_not_found"
+	CodeUserConflict     = "user_conflict"
+	CodeEmailConflict    = "email_conflict"
+	CodeForumConflict    = "forum_conflict"
+	CodeThreadConflict   = "thread_conflict"
+	CodeParentConflict   = "parent_conflict"
+	CodeConflict         = "conflict"
+	CodeDeadlineExceeded = "deadline_exceeded"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+// Error is what v2 handlers return instead of echo.HTTPError; ErrorHandler renders it.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Existing carries the stored objects a create conflicted with.
+	Existing interface{}
+	// Fields are the invalid fields of a validation_failed error.
+	Fields []models.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func newError(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code     string              `json:"code"`
+	Message  string              `json:"message"`
+	Existing interface{}         `json:"existing,omitempty"`
+	Fields   []models.FieldError `json:"fields,omitempty"`
+}
+
+// statusCodes types the errors that come from middleware and from deadline.HTTPError.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          CodeBadRequest,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusMethodNotAllowed:    CodeMethodNotAllowed,
+	http.StatusConflict:            CodeConflict,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+	http.StatusGatewayTimeout:      CodeDeadlineExceeded,
+	http.StatusInternalServerError: CodeInternal,
+}
+
+// ErrorHandler renders the errors of requests under prefix as ErrorBody and leaves the others to next,
+// so the responses of /api stay as they were.
+func ErrorHandler(prefix string, next echo.HTTPErrorHandler) echo.HTTPErrorHandler {
+	return func(err error, ctx echo.Context) {
+		if !strings.HasPrefix(ctx.Request().URL.Path, prefix) || ctx.Response().Committed {
+			next(err, ctx)
+			return
+		}
+		v2Err, ok := err.(*Error)
+		if !ok {
+			v2Err = fromHTTPError(err)
+		}
+		body := ErrorBody{Error: ErrorDetail{Code: v2Err.Code, Message: v2Err.Message, Existing: v2Err.Existing, Fields: v2Err.Fields}}
+		var writeErr error
+		if ctx.Request().Method == http.MethodHead {
+			writeErr = ctx.NoContent(v2Err.Status)
+		} else {
+			writeErr = ctx.JSON(v2Err.Status, body)
+		}
+		if writeErr != nil {
+			ctx.Logger().Error(writeErr)
+		}
+	}
+}
+
+func fromHTTPError(err error) *Error {
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		return newError(http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+	}
+	if validationErr, ok := httpErr.Message.(*models.ValidationError); ok {
+		return &Error{Status: httpErr.Code, Code: CodeValidation, Message: validationErr.Message, Fields: validationErr.Fields}
+	}
+	code, ok := statusCodes[httpErr.Code]
+	if !ok {
+		code = strings.ToLower(strings.ReplaceAll(http.StatusText(httpErr.Code), " ", "_"))
+	}
+	return newError(httpErr.Code, code, fmt.Sprint(httpErr.Message))
+}
+
+// v2Fields are the v2 names of the fields validate reports by their /api names.
+var v2Fields = map[string]string{
+	"user":    "author",
+	"created": "createdAt",
+	"parent":  "parentId",
+}
+
+// invalid turns an error of validate into a validation_failed error. renames override v2Fields.
+func invalid(err error, renames map[string]string) *Error {
+	v2Err := &Error{Status: http.StatusBadRequest, Code: CodeValidation, Message: errors.INVALID_INPUT + err.Error()}
+	validationErr, ok := err.(*validate.Error)
+	if !ok {
+		return v2Err
+	}
+	for _, field := range validationErr.Fields {
+		prefix, name := "", field.Field
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			prefix, name = name[:i+1], name[i+1:]
+		}
+		if renamed, ok := renames[name]; ok {
+			name = renamed
+		} else if renamed, ok := v2Fields[name]; ok {
+			name = renamed
+		}
+		v2Err.Fields = append(v2Err.Fields, models.FieldError{Field: prefix + name, Reason: field.Reason})
+	}
+	return v2Err
+}
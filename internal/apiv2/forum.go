@@ -0,0 +1,197 @@
+package apiv2
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	forumRepo "github.com/Natali-Skv/technopark_db_forum/internal/forum"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/go-openapi/strfmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sortNickname = "nickname"
+	sortActivity = "activity"
+	sortCreated  = "created"
+	sortHot      = "hot"
+	sortTop      = "top"
+	sortActive   = "active"
+)
+
+func (h *Handler) CreateForum(ctx echo.Context) error {
+	newForum := NewForum{}
+	if err := ctx.Bind(&newForum); err != nil {
+		return badBody()
+	}
+	forum := &models.Forum{Slug: newForum.Slug, Title: newForum.Title, UserNick: newForum.Author}
+	if err := validate.Forum(forum); err != nil {
+		return invalid(err, nil)
+	}
+	created, err := h.Forums.Create(ctx.Request().Context(), forum)
+	if err != nil {
+		switch dbconn.ErrorCode(err) {
+		case "23505":
+			conflictForum, err := h.Forums.GetBySlug(ctx.Request().Context(), forum.Slug)
+			if err != nil || conflictForum == nil {
+				return repoError(err)
+			}
+			return &Error{Status: http.StatusConflict, Code: CodeForumConflict, Message: "forum already exists: " + forum.Slug, Existing: forumView(conflictForum)}
+		case "AAAA1":
+			return userNotFound(forum.UserNick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusCreated, forumView(created))
+}
+
+func (h *Handler) GetForum(ctx echo.Context) error {
+	slug := ctx.Param(SlugCtxKey)
+	forum, err := h.Forums.GetBySlug(ctx.Request().Context(), slug)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return forumNotFound(slug)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, forumView(forum))
+}
+
+// GetForumThreads pages by createdAt, which is not unique: the cursor counts the threads
+// created at the same time as the last one that were already returned. The hot, top and
+// active sorts break ties by id, so their cursor is just the id of the last thread.
+func (h *Handler) GetForumThreads(ctx echo.Context) error {
+	slug := ctx.Param(SlugCtxKey)
+	params, err := parseListParams(ctx)
+	if err != nil {
+		return err
+	}
+	switch sort := ctx.QueryParam(SortParam); sort {
+	case sortCreated, "":
+	case sortHot, sortTop, sortActive:
+		return h.getRankedForumThreads(ctx, slug, params, sort)
+	default:
+		return newError(http.StatusBadRequest, CodeBadRequest, errors.UNKNOWN_SORT_TYPE)
+	}
+	since, err := threadsSince(params)
+	if err != nil {
+		return err
+	}
+	threads, err := h.Forums.GetForumThreads(ctx.Request().Context(), slug, params.desc, params.limit+params.cursor.Skip+1, since, sortCreated, "")
+	if err != nil {
+		return repoError(err)
+	}
+	if len(threads) == 0 {
+		return h.emptyForumPage(ctx, slug)
+	}
+
+	page := Page[Thread]{}
+	if len(threads) > params.cursor.Skip {
+		threads = threads[params.cursor.Skip:]
+	} else {
+		threads = threads[:0]
+	}
+	more := len(threads) > params.limit
+	if more {
+		threads = threads[:params.limit]
+		last := threads[len(threads)-1].Created
+		next := cursor{Since: last}
+		for _, thread := range threads {
+			if thread.Created == last {
+				next.Skip++
+			}
+		}
+		if last == params.cursor.Since {
+			next.Skip += params.cursor.Skip
+		}
+		page.NextCursor = next.encode()
+	}
+	page.Items = threadsView(threads)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) getRankedForumThreads(ctx echo.Context, slug string, params listParams, sort string) error {
+	if params.cursor.Since != "" {
+		if _, err := strconv.Atoi(params.cursor.Since); err != nil {
+			return newError(http.StatusBadRequest, CodeInvalidCursor, errors.BAD_CURSOR)
+		}
+	}
+	period := ctx.QueryParam(PeriodParam)
+	if _, err := forumRepo.PeriodStart(period, time.Now()); err != nil {
+		return newError(http.StatusBadRequest, CodeBadRequest, err.Error())
+	}
+	threads, err := h.Forums.GetForumThreads(ctx.Request().Context(), slug, params.desc, params.limit+1, params.cursor.Since, sort, period)
+	if err != nil {
+		return repoError(err)
+	}
+	if len(threads) == 0 {
+		return h.emptyForumPage(ctx, slug)
+	}
+	page := Page[Thread]{}
+	if len(threads) > params.limit {
+		threads = threads[:params.limit]
+		page.NextCursor = cursor{Since: strconv.Itoa(threads[len(threads)-1].Id)}.encode()
+	}
+	page.Items = threadsView(threads)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+// threadsSince returns the since parameter of the repo for the cursor. createdAt is shown to
+// the millisecond, so when going back in time the cursor covers the rest of its millisecond.
+func threadsSince(params listParams) (string, error) {
+	if params.cursor.Since == "" || !params.desc {
+		return params.cursor.Since, nil
+	}
+	since, err := strfmt.ParseDateTime(params.cursor.Since)
+	if err != nil {
+		return "", newError(http.StatusBadRequest, CodeInvalidCursor, errors.BAD_CURSOR)
+	}
+	return time.Time(since).Add(time.Millisecond - time.Microsecond).Format(time.RFC3339Nano), nil
+}
+
+// GetForumUsers pages by nickname or by last activity; either way the cursor holds the
+// nickname of the last user, which is unique.
+func (h *Handler) GetForumUsers(ctx echo.Context) error {
+	slug := ctx.Param(SlugCtxKey)
+	params, err := parseListParams(ctx)
+	if err != nil {
+		return err
+	}
+	sort := ctx.QueryParam(SortParam)
+	if sort == "" {
+		sort = sortNickname
+	}
+	if sort != sortNickname && sort != sortActivity {
+		return newError(http.StatusBadRequest, CodeBadRequest, errors.UNKNOWN_SORT_TYPE)
+	}
+	users, err := h.Forums.GetForumUsers(ctx.Request().Context(), slug, params.desc, params.limit+1, params.cursor.Since, sort)
+	if err != nil {
+		return repoError(err)
+	}
+	if len(users) == 0 {
+		return h.emptyForumPage(ctx, slug)
+	}
+	page := Page[ForumUser]{}
+	if len(users) > params.limit {
+		users = users[:params.limit]
+		page.NextCursor = cursor{Since: users[len(users)-1].Nick}.encode()
+	}
+	page.Items = forumUsersView(users)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) emptyForumPage(ctx echo.Context, slug string) error {
+	exists, err := h.Forums.CheckBySlug(ctx.Request().Context(), slug)
+	if err != nil {
+		return repoError(err)
+	}
+	if !exists {
+		return forumNotFound(slug)
+	}
+	return ctx.JSON(http.StatusOK, Page[struct{}]{Items: []struct{}{}})
+}
@@ -0,0 +1,112 @@
+// Package apiv2 serves /api/v2: the same data and repos as /api, with a contract that is easier to
+// program against. Resources are addressed by the id they are referenced with, lists are paged with
+// opaque cursors, related objects are embedded on request and errors carry a typed code.
+package apiv2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	forumRepo "github.com/Natali-Skv/technopark_db_forum/internal/forum"
+	postRepo "github.com/Natali-Skv/technopark_db_forum/internal/post"
+	threadRepo "github.com/Natali-Skv/technopark_db_forum/internal/thread"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	userRepo "github.com/Natali-Skv/technopark_db_forum/internal/user"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	NickCtxKey      = "nickname"
+	SlugCtxKey      = "slug"
+	IdCtxKey        = "id"
+	VoterCtxKey     = "voter"
+	ReactionCtxKey  = "reaction"
+	LimitParam      = "limit"
+	CursorParam     = "cursor"
+	DescParam       = "desc"
+	SortParam       = "sort"
+	IncludeParam    = "include"
+	FormatParam     = "format"
+	NicknamesParam  = "nicknames"
+	PeriodParam     = "period"
+	DepthParam      = "depth"
+	SiblingsParam   = "siblings"
+	MaxDepthParam   = "max_depth"
+	ChildLimitParam = "child_limit"
+	NestedParam     = "nested"
+
+	defaultLimit    = 100
+	defaultSiblings = 5
+)
+
+type Handler struct {
+	Users   userRepo.Repo
+	Forums  forumRepo.Repo
+	Threads threadRepo.Repo
+	Posts   postRepo.Repo
+}
+
+func NewHandler(users userRepo.Repo, forums forumRepo.Repo, threads threadRepo.Repo, posts postRepo.Repo) *Handler {
+	return &Handler{Users: users, Forums: forums, Threads: threads, Posts: posts}
+}
+
+// listParams are the query parameters every list takes.
+type listParams struct {
+	limit  int
+	desc   bool
+	cursor cursor
+}
+
+func parseListParams(ctx echo.Context) (listParams, error) {
+	params := listParams{limit: defaultLimit, desc: ctx.QueryParam(DescParam) == "true"}
+	if limit := ctx.QueryParam(LimitParam); limit != "" {
+		var err error
+		if params.limit, err = strconv.Atoi(limit); err != nil || params.limit <= 0 {
+			return params, newError(http.StatusBadRequest, CodeBadRequest, errors.BAD_BODY)
+		}
+	}
+	var err error
+	params.cursor, err = decodeCursor(ctx.QueryParam(CursorParam))
+	return params, err
+}
+
+func badBody() *Error {
+	return newError(http.StatusBadRequest, CodeBadRequest, errors.BAD_BODY)
+}
+
+func userNotFound(nick string) *Error {
+	return newError(http.StatusNotFound, CodeUserNotFound, errors.NOT_FOUND_USER_BY_NICK+nick)
+}
+
+// checkNotGhost refuses to change the account deleted users are handed over to.
+func checkNotGhost(nick string) error {
+	if strings.EqualFold(nick, userRepo.Ghost.Nick) {
+		return newError(http.StatusForbidden, CodeForbidden, errors.GHOST_USER)
+	}
+	return nil
+}
+
+func forumNotFound(slug string) *Error {
+	return newError(http.StatusNotFound, CodeForumNotFound, errors.NOT_FOUND_FORUM+slug)
+}
+
+func threadNotFound(slugOrId string) *Error {
+	return newError(http.StatusNotFound, CodeThreadNotFound, errors.NOT_FOUND_THREAD+slugOrId)
+}
+
+func postNotFound(id string) *Error {
+	return newError(http.StatusNotFound, CodePostNotFound, errors.NO_POST+id)
+}
+
+// pathId returns the id path parameter, and ok=false if it is not an id that can exist.
+func pathId(ctx echo.Context) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param(IdCtxKey))
+	return id, err == nil && id > 0
+}
+
+// repoError types an unexpected repo error the same way deadline.HTTPError does for /api.
+func repoError(err error) *Error {
+	return fromHTTPError(deadline.HTTPError(err))
+}
@@ -0,0 +1,320 @@
+package apiv2
+
+import "github.com/Natali-Skv/technopark_db_forum/internal/models"
+
+// The v2 resources are views of the shared models: every resource is referenced by the id it is
+// addressed with, a missing value is null rather than a zero sentinel, and times are createdAt.
+
+type User struct {
+	Nickname string `json:"nickname"`
+	Fullname string `json:"fullname"`
+	About    string `json:"about"`
+	Email    string `json:"email"`
+}
+
+type ForumUser struct {
+	User
+	Posts           int    `json:"posts"`
+	Threads         int    `json:"threads"`
+	FirstActivityAt string `json:"firstActivityAt"`
+	LastActivityAt  string `json:"lastActivityAt"`
+}
+
+type Forum struct {
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Author  string `json:"author"`
+	Posts   int    `json:"posts"`
+	Threads int    `json:"threads"`
+}
+
+type Thread struct {
+	Id                int            `json:"id"`
+	Slug              *string        `json:"slug"`
+	Title             string         `json:"title"`
+	Author            string         `json:"author"`
+	Forum             string         `json:"forum"`
+	Message           string         `json:"message"`
+	Votes             int            `json:"votes"`
+	CreatedAt         string         `json:"createdAt"`
+	PostsCount        int            `json:"postsCount"`
+	LastPostAt        *string        `json:"lastPostAt"`
+	LastPostAuthor    *string        `json:"lastPostAuthor"`
+	ParticipantsCount int            `json:"participantsCount"`
+	Reactions         map[string]int `json:"reactions,omitempty"`
+}
+
+type Post struct {
+	Id          int            `json:"id"`
+	ParentId    *int           `json:"parentId"`
+	Author      string         `json:"author"`
+	Message     string         `json:"message"`
+	Edited      bool           `json:"edited"`
+	Forum       string         `json:"forum"`
+	ThreadId    int            `json:"threadId"`
+	Votes       int            `json:"votes"`
+	CreatedAt   string         `json:"createdAt"`
+	Children    int            `json:"children"`
+	Descendants int            `json:"descendants"`
+	Depth       int            `json:"depth"`
+	MoreReplies int            `json:"moreReplies,omitempty"`
+	Replies     []Post         `json:"replies,omitempty"`
+	Reactions   map[string]int `json:"reactions,omitempty"`
+	Embedded    *Embedded      `json:"embedded,omitempty"`
+}
+
+// PostContext is a post with the chain of posts it answers and its neighbouring siblings, all in tree order.
+type PostContext struct {
+	Ancestors []Post `json:"ancestors"`
+	Before    []Post `json:"before"`
+	Post      Post   `json:"post"`
+	After     []Post `json:"after"`
+}
+
+type Vote struct {
+	ThreadId int `json:"threadId"`
+	Voice    int `json:"voice"`
+}
+
+type PostVote struct {
+	PostId int `json:"postId"`
+	Voice  int `json:"voice"`
+}
+
+// Reaction is given either to a post or to a thread.
+type Reaction struct {
+	PostId   int    `json:"postId,omitempty"`
+	ThreadId int    `json:"threadId,omitempty"`
+	Reaction string `json:"reaction"`
+}
+
+// Voter is an item of the vote list of a thread or a post.
+type Voter struct {
+	Nickname string `json:"nickname"`
+	Voice    int    `json:"voice"`
+}
+
+type Karma struct {
+	Nickname    string `json:"nickname"`
+	Karma       int    `json:"karma"`
+	ThreadVotes int    `json:"threadVotes"`
+	PostVotes   int    `json:"postVotes"`
+}
+
+type ForumMembership struct {
+	Forum           string `json:"forum"`
+	Posts           int    `json:"posts"`
+	Threads         int    `json:"threads"`
+	FirstActivityAt string `json:"firstActivityAt"`
+	LastActivityAt  string `json:"lastActivityAt"`
+}
+
+type UserExport struct {
+	User      User              `json:"user"`
+	Threads   []Thread          `json:"threads"`
+	Posts     []Post            `json:"posts"`
+	Votes     []Vote            `json:"votes"`
+	PostVotes []PostVote        `json:"postVotes"`
+	Reactions []Reaction        `json:"reactions"`
+	Forums    []ForumMembership `json:"forums"`
+}
+
+// Embedded holds the objects requested with include=author,forum,thread.
+type Embedded struct {
+	Author *User   `json:"author,omitempty"`
+	Forum  *Forum  `json:"forum,omitempty"`
+	Thread *Thread `json:"thread,omitempty"`
+}
+
+// Page is a slice of a list. NextCursor is set if there may be more items: pass it as cursor to get them.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type NewUser struct {
+	Nickname string `json:"nickname"`
+	Fullname string `json:"fullname"`
+	About    string `json:"about"`
+	Email    string `json:"email"`
+}
+
+// BulkUserResult is the outcome of one user of a bulk create: the created user, or the error
+// creating it alone would have failed with.
+type BulkUserResult struct {
+	Status int          `json:"status"`
+	User   *User        `json:"user,omitempty"`
+	Error  *ErrorDetail `json:"error,omitempty"`
+}
+
+type UserRename struct {
+	Nickname string `json:"nickname"`
+}
+
+// UserUpdate, ThreadUpdate and PostUpdate keep the fields that are missing or empty.
+type UserUpdate struct {
+	Fullname string `json:"fullname"`
+	About    string `json:"about"`
+	Email    string `json:"email"`
+}
+
+type NewForum struct {
+	Slug   string `json:"slug"`
+	Title  string `json:"title"`
+	Author string `json:"author"`
+}
+
+type NewThread struct {
+	Slug      string `json:"slug"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	Message   string `json:"message"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+type ThreadUpdate struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+type NewVote struct {
+	Voice int `json:"voice"`
+}
+
+type NewPost struct {
+	ParentId *int   `json:"parentId"`
+	Author   string `json:"author"`
+	Message  string `json:"message"`
+}
+
+type PostUpdate struct {
+	Message string `json:"message"`
+}
+
+func userView(u *models.User) *User {
+	if u == nil {
+		return nil
+	}
+	return &User{Nickname: u.Nick, Fullname: u.Name, About: u.About, Email: u.Email}
+}
+
+func usersView(users []models.User) []User {
+	views := make([]User, 0, len(users))
+	for i := range users {
+		views = append(views, *userView(&users[i]))
+	}
+	return views
+}
+
+func forumUsersView(users []models.ForumUser) []ForumUser {
+	views := make([]ForumUser, 0, len(users))
+	for i := range users {
+		u := &users[i]
+		views = append(views, ForumUser{User: *userView(&u.User), Posts: u.Posts, Threads: u.Threads,
+			FirstActivityAt: u.FirstActivity, LastActivityAt: u.LastActivity})
+	}
+	return views
+}
+
+func userExportView(e *models.UserExport) *UserExport {
+	view := &UserExport{
+		User:      *userView(&e.User),
+		Threads:   threadsView(e.Threads),
+		Posts:     postsView(e.Posts),
+		Votes:     make([]Vote, 0, len(e.Votes)),
+		PostVotes: make([]PostVote, 0, len(e.PostVotes)),
+		Reactions: make([]Reaction, 0, len(e.Reactions)),
+		Forums:    make([]ForumMembership, 0, len(e.Forums)),
+	}
+	for _, v := range e.Votes {
+		view.Votes = append(view.Votes, Vote{ThreadId: v.ThreadId, Voice: v.Voice})
+	}
+	for _, v := range e.PostVotes {
+		view.PostVotes = append(view.PostVotes, PostVote{PostId: v.PostId, Voice: v.Voice})
+	}
+	for _, r := range e.Reactions {
+		view.Reactions = append(view.Reactions, Reaction{PostId: r.PostId, ThreadId: r.ThreadId, Reaction: r.Reaction})
+	}
+	for _, f := range e.Forums {
+		view.Forums = append(view.Forums, ForumMembership{Forum: f.Forum, Posts: f.Posts, Threads: f.Threads,
+			FirstActivityAt: f.FirstActivity, LastActivityAt: f.LastActivity})
+	}
+	return view
+}
+
+func forumView(f *models.Forum) *Forum {
+	if f == nil {
+		return nil
+	}
+	return &Forum{Slug: f.Slug, Title: f.Title, Author: f.UserNick, Posts: f.Posts, Threads: f.Threads}
+}
+
+func threadView(t *models.Thread) *Thread {
+	if t == nil {
+		return nil
+	}
+	view := &Thread{Id: t.Id, Title: t.Title, Author: t.AuthorNick, Forum: t.ForumSlug, Message: t.Message, Votes: t.Votes, CreatedAt: t.Created,
+		PostsCount: t.PostsCount, ParticipantsCount: t.ParticipantsCount, Reactions: t.Reactions}
+	if t.Slug != "" {
+		slug := t.Slug
+		view.Slug = &slug
+	}
+	if t.LastPostAt != "" {
+		lastPostAt, lastPostAuthor := t.LastPostAt, t.LastPostAuthor
+		view.LastPostAt, view.LastPostAuthor = &lastPostAt, &lastPostAuthor
+	}
+	return view
+}
+
+func threadsView(threads []models.Thread) []Thread {
+	views := make([]Thread, 0, len(threads))
+	for i := range threads {
+		views = append(views, *threadView(&threads[i]))
+	}
+	return views
+}
+
+func postView(p *models.Post) *Post {
+	view := &Post{Id: p.Id, Author: p.AuthorNick, Message: p.Message, Edited: p.IsEdited, Forum: p.ForumSlug, ThreadId: p.ThreadId, Votes: p.Votes, CreatedAt: p.Created,
+		Children: p.Children, Descendants: p.Descendants, Depth: p.Depth, MoreReplies: p.MoreReplies, Reactions: p.Reactions}
+	if p.ParentId != 0 {
+		parent := p.ParentId
+		view.ParentId = &parent
+	}
+	if len(p.Replies) > 0 {
+		view.Replies = postsView(p.Replies)
+	}
+	return view
+}
+
+func postsView(posts []models.Post) []Post {
+	views := make([]Post, 0, len(posts))
+	for i := range posts {
+		views = append(views, *postView(&posts[i]))
+	}
+	return views
+}
+
+func postContextView(c *models.PostContext) *PostContext {
+	return &PostContext{Ancestors: postsView(c.Ancestors), Before: postsView(c.Before), Post: *postView(&c.Post), After: postsView(c.After)}
+}
+
+func threadVotersView(votes []models.Vote) []Voter {
+	views := make([]Voter, 0, len(votes))
+	for _, v := range votes {
+		views = append(views, Voter{Nickname: v.Nick, Voice: v.Voice})
+	}
+	return views
+}
+
+func postVotersView(votes []models.PostVote) []Voter {
+	views := make([]Voter, 0, len(votes))
+	for _, v := range votes {
+		views = append(views, Voter{Nickname: v.Nick, Voice: v.Voice})
+	}
+	return views
+}
+
+func karmaView(k *models.Karma) *Karma {
+	return &Karma{Nickname: k.Nick, Karma: k.Karma, ThreadVotes: k.ThreadVotes, PostVotes: k.PostVotes}
+}
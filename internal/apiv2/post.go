@@ -0,0 +1,387 @@
+package apiv2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	postRepo "github.com/Natali-Skv/technopark_db_forum/internal/post"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sortFlat       = "flat"
+	sortTree       = "tree"
+	sortParentTree = "parent_tree"
+)
+
+// includeRelated maps the include values to the related values of the post repo.
+var includeRelated = map[string]string{
+	"author":    "user",
+	"forum":     "forum",
+	"thread":    "thread",
+	"reactions": "reactions",
+}
+
+// includeThreadPosts are the include values of a page of posts.
+var includeThreadPosts = map[string]string{
+	"reactions": "reactions",
+}
+
+// parseInclude turns the comma separated include parameter into related values of the post repo.
+func parseInclude(ctx echo.Context, allowed map[string]string) ([]string, error) {
+	related := make([]string, 0, len(allowed))
+	if include := ctx.QueryParam(IncludeParam); include != "" {
+		for _, name := range strings.Split(include, ",") {
+			value, ok := allowed[name]
+			if !ok {
+				return nil, newError(http.StatusBadRequest, CodeBadRequest, errors.UNKNOWN_INCLUDE+name)
+			}
+			related = append(related, value)
+		}
+	}
+	return related, nil
+}
+
+func (h *Handler) CreatePosts(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	newPosts := []NewPost{}
+	if err := ctx.Bind(&newPosts); err != nil {
+		return badBody()
+	}
+	posts := make([]models.Post, 0, len(newPosts))
+	for _, newPost := range newPosts {
+		post := models.Post{AuthorNick: newPost.Author, Message: newPost.Message}
+		if newPost.ParentId != nil {
+			post.ParentId = *newPost.ParentId
+		}
+		posts = append(posts, post)
+	}
+	if err := validate.Posts(posts); err != nil {
+		return invalid(err, nil)
+	}
+	created, err := h.Posts.Create(ctx.Request().Context(), "", id, posts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+		switch dbconn.ErrorCode(err) {
+		case "AAAA0":
+			return newError(http.StatusConflict, CodeParentConflict, errors.NO_PARENT_POST)
+		case "AAAA1":
+			return newError(http.StatusNotFound, CodeUserNotFound, errors.NOT_FOUND_POST_AUTHOR_BY_NICK+posts[0].AuthorNick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusCreated, postsView(created))
+}
+
+// GetThreadPosts pages by post id for the flat and tree sorts and by root post for parent_tree,
+// where a page holds limit whole root subtrees. A nested page is cut first and then nested.
+func (h *Handler) GetThreadPosts(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	params, err := parseListParams(ctx)
+	if err != nil {
+		return err
+	}
+	sort := ctx.QueryParam(SortParam)
+	if sort == "" {
+		sort = sortFlat
+	}
+	if sort != sortFlat && sort != sortTree && sort != sortParentTree {
+		return newError(http.StatusBadRequest, CodeBadRequest, errors.UNKNOWN_SORT_TYPE)
+	}
+	related, err := parseInclude(ctx, includeThreadPosts)
+	if err != nil {
+		return err
+	}
+	since := 0
+	if params.cursor.Since != "" {
+		if since, err = strconv.Atoi(params.cursor.Since); err != nil {
+			return newError(http.StatusBadRequest, CodeInvalidCursor, errors.BAD_CURSOR)
+		}
+	}
+	maxDepth, _ := strconv.Atoi(ctx.QueryParam(MaxDepthParam))
+	childLimit, _ := strconv.Atoi(ctx.QueryParam(ChildLimitParam))
+	posts, err := h.Posts.GetThreadPosts(ctx.Request().Context(), "", id, params.desc, params.limit+1, since, sort, maxDepth, childLimit, related)
+	if err != nil {
+		return repoError(err)
+	}
+	if len(posts) == 0 {
+		exists, err := h.Posts.CheckThreadBySlugOrId(ctx.Request().Context(), "", id)
+		if err != nil {
+			return repoError(err)
+		}
+		if !exists {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+	}
+
+	page := Page[Post]{}
+	cut := len(posts)
+	if sort == sortParentTree {
+		roots := 0
+		for i, post := range posts {
+			if post.ParentId == 0 {
+				if roots == params.limit {
+					cut = i
+					break
+				}
+				roots++
+			}
+		}
+	} else if len(posts) > params.limit {
+		cut = params.limit
+	}
+	if cut < len(posts) {
+		posts = posts[:cut]
+		page.NextCursor = cursor{Since: strconv.Itoa(posts[len(posts)-1].Id)}.encode()
+	}
+	if ctx.QueryParam(NestedParam) == "true" {
+		posts = postRepo.Nest(posts)
+	}
+	page.Items = postsView(posts)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) GetPost(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	related, err := parseInclude(ctx, includeRelated)
+	if err != nil {
+		return err
+	}
+	post, user, forum, thread, err := h.Posts.GetPostByIdRelated(ctx.Request().Context(), id, related)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	if post == nil {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	view := postView(post)
+	if user != nil || forum != nil || thread != nil {
+		view.Embedded = &Embedded{Author: userView(user), Forum: forumView(forum), Thread: threadView(thread)}
+	}
+	return ctx.JSON(http.StatusOK, view)
+}
+
+func (h *Handler) UpdatePost(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	update := PostUpdate{}
+	if err := ctx.Bind(&update); err != nil {
+		return badBody()
+	}
+	post := &models.Post{Id: id, Message: update.Message}
+	if err := validate.PostUpdate(post); err != nil {
+		return invalid(err, nil)
+	}
+	post, err := h.Posts.UpdatePost(ctx.Request().Context(), post)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postView(post))
+}
+
+// VotePost sets the voice of the user for the post, replacing the one they gave before.
+func (h *Handler) VotePost(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	newVote := NewVote{}
+	if err := ctx.Bind(&newVote); err != nil {
+		return badBody()
+	}
+	vote := &models.PostVote{Nick: ctx.Param(VoterCtxKey), Voice: newVote.Voice, PostId: id}
+	if err := validate.PostVote(vote); err != nil {
+		return invalid(err, map[string]string{"nickname": VoterCtxKey})
+	}
+	post, err := h.Posts.Vote(ctx.Request().Context(), vote)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		switch dbconn.ErrorCode(err) {
+		case "23502", "23503":
+			return postNotFound(ctx.Param(IdCtxKey))
+		case "AAAA1":
+			return userNotFound(vote.Nick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postView(post))
+}
+
+// UnvotePost retracts the voice of the user for the post; the post is returned unchanged if they did not vote.
+func (h *Handler) UnvotePost(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	post, err := h.Posts.Unvote(ctx.Request().Context(), &models.PostVote{Nick: ctx.Param(VoterCtxKey), PostId: id})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postView(post))
+}
+
+// GetPostVotes pages the voters of the post by nickname; the cursor holds the last one.
+func (h *Handler) GetPostVotes(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	params, err := parseListParams(ctx)
+	if err != nil {
+		return err
+	}
+	votes, err := h.Posts.GetVotes(ctx.Request().Context(), id, params.desc, params.limit+1, params.cursor.Since)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	page := Page[Voter]{}
+	if len(votes) > params.limit {
+		votes = votes[:params.limit]
+		page.NextCursor = cursor{Since: votes[len(votes)-1].Nick}.encode()
+	}
+	page.Items = postVotersView(votes)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+// GetPostSubtree pages the replies under the post in tree order; the cursor holds the id of the last one.
+func (h *Handler) GetPostSubtree(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	params, err := parseListParams(ctx)
+	if err != nil {
+		return err
+	}
+	depth, _ := strconv.Atoi(ctx.QueryParam(DepthParam))
+	since := 0
+	if params.cursor.Since != "" {
+		if since, err = strconv.Atoi(params.cursor.Since); err != nil {
+			return newError(http.StatusBadRequest, CodeInvalidCursor, errors.BAD_CURSOR)
+		}
+	}
+	posts, err := h.Posts.GetSubtree(ctx.Request().Context(), id, depth, params.limit+1, since)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	page := Page[Post]{}
+	if len(posts) > params.limit {
+		posts = posts[:params.limit]
+		page.NextCursor = cursor{Since: strconv.Itoa(posts[len(posts)-1].Id)}.encode()
+	}
+	page.Items = postsView(posts)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) GetPostAncestors(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	posts, err := h.Posts.GetAncestors(ctx.Request().Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postsView(posts))
+}
+
+// GetPostContext returns the post with its ancestors and up to siblings replies to the same parent on each side.
+func (h *Handler) GetPostContext(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	siblings, err := validate.Siblings(ctx.QueryParam(SiblingsParam), defaultSiblings)
+	if err != nil {
+		return invalid(err, nil)
+	}
+	postContext, err := h.Posts.GetContext(ctx.Request().Context(), id, siblings)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postContextView(postContext))
+}
+
+// ReactToPost adds the reaction of the user to the post; adding it again changes nothing.
+func (h *Handler) ReactToPost(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	reaction := &models.Reaction{Nick: ctx.Param(VoterCtxKey), Reaction: ctx.Param(ReactionCtxKey), PostId: id}
+	if err := validate.Reaction(reaction); err != nil {
+		return invalid(err, map[string]string{"nickname": VoterCtxKey})
+	}
+	post, err := h.Posts.React(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		switch dbconn.ErrorCode(err) {
+		case "23502", "23503":
+			return postNotFound(ctx.Param(IdCtxKey))
+		case "AAAA1":
+			return userNotFound(reaction.Nick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postView(post))
+}
+
+func (h *Handler) UnreactToPost(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return postNotFound(ctx.Param(IdCtxKey))
+	}
+	reaction := &models.Reaction{Nick: ctx.Param(VoterCtxKey), Reaction: ctx.Param(ReactionCtxKey), PostId: id}
+	post, err := h.Posts.Unreact(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return postNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, postView(post))
+}
@@ -0,0 +1,202 @@
+package apiv2
+
+import (
+	"net/http"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) CreateThread(ctx echo.Context) error {
+	newThread := NewThread{}
+	if err := ctx.Bind(&newThread); err != nil {
+		return badBody()
+	}
+	thread := &models.Thread{Slug: newThread.Slug, Title: newThread.Title, AuthorNick: newThread.Author,
+		ForumSlug: ctx.Param(SlugCtxKey), Message: newThread.Message, Created: newThread.CreatedAt}
+	if err := validate.Thread(thread); err != nil {
+		return invalid(err, nil)
+	}
+	created, err := h.Threads.Create(ctx.Request().Context(), thread)
+	if err != nil {
+		switch dbconn.ErrorCode(err) {
+		case "23505":
+			conflictThread, err := h.Threads.GetBySlugOrId(ctx.Request().Context(), thread.Slug, 0)
+			if err != nil || conflictThread == nil {
+				return repoError(err)
+			}
+			return &Error{Status: http.StatusConflict, Code: CodeThreadConflict, Message: "thread already exists: " + thread.Slug, Existing: threadView(conflictThread)}
+		case "AAAA1":
+			return userNotFound(thread.AuthorNick)
+		case "AAAA3":
+			return forumNotFound(thread.ForumSlug)
+		}
+		return repoError(err)
+	}
+	// the repo fills in the ids only, the rest is read back so that createdAt is the stored one
+	stored, err := h.Threads.GetBySlugOrId(ctx.Request().Context(), "", created.Id)
+	if err != nil {
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusCreated, threadView(stored))
+}
+
+func (h *Handler) GetThread(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	return h.getThread(ctx, "", id)
+}
+
+func (h *Handler) GetThreadBySlug(ctx echo.Context) error {
+	return h.getThread(ctx, ctx.Param(SlugCtxKey), 0)
+}
+
+func (h *Handler) getThread(ctx echo.Context, slug string, id int) error {
+	thread, err := h.Threads.GetBySlugOrId(ctx.Request().Context(), slug, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(slug + ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	if thread.Reactions, err = h.Threads.GetReactions(ctx.Request().Context(), thread.Id); err != nil {
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, threadView(thread))
+}
+
+func (h *Handler) UpdateThread(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	update := ThreadUpdate{}
+	if err := ctx.Bind(&update); err != nil {
+		return badBody()
+	}
+	thread := &models.Thread{Id: id, Title: update.Title, Message: update.Message}
+	if err := validate.ThreadUpdate(thread); err != nil {
+		return invalid(err, nil)
+	}
+	thread, err := h.Threads.UpdateThread(ctx.Request().Context(), thread)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, threadView(thread))
+}
+
+// Vote sets the voice of the user in the thread, replacing the one they gave before.
+func (h *Handler) Vote(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	newVote := NewVote{}
+	if err := ctx.Bind(&newVote); err != nil {
+		return badBody()
+	}
+	vote := &models.Vote{Nick: ctx.Param(VoterCtxKey), Voice: newVote.Voice, ThreadId: id}
+	if err := validate.Vote(vote); err != nil {
+		return invalid(err, map[string]string{"nickname": VoterCtxKey})
+	}
+	thread, err := h.Threads.Vote(ctx.Request().Context(), vote)
+	if err != nil {
+		switch dbconn.ErrorCode(err) {
+		case "23502", "23503":
+			return threadNotFound(ctx.Param(IdCtxKey))
+		case "AAAA1":
+			return userNotFound(vote.Nick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, threadView(thread))
+}
+
+// Unvote retracts the voice of the user in the thread; the thread is returned unchanged if they did not vote.
+func (h *Handler) Unvote(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	thread, err := h.Threads.Unvote(ctx.Request().Context(), &models.Vote{Nick: ctx.Param(VoterCtxKey), ThreadId: id})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, threadView(thread))
+}
+
+// GetThreadVotes pages the voters of the thread by nickname; the cursor holds the last one.
+func (h *Handler) GetThreadVotes(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	params, err := parseListParams(ctx)
+	if err != nil {
+		return err
+	}
+	votes, err := h.Threads.GetVotes(ctx.Request().Context(), "", id, params.desc, params.limit+1, params.cursor.Since)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	page := Page[Voter]{}
+	if len(votes) > params.limit {
+		votes = votes[:params.limit]
+		page.NextCursor = cursor{Since: votes[len(votes)-1].Nick}.encode()
+	}
+	page.Items = threadVotersView(votes)
+	return ctx.JSON(http.StatusOK, page)
+}
+
+// ReactToThread adds the reaction of the user to the thread; adding it again changes nothing.
+func (h *Handler) ReactToThread(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	reaction := &models.Reaction{Nick: ctx.Param(VoterCtxKey), Reaction: ctx.Param(ReactionCtxKey), ThreadId: id}
+	if err := validate.Reaction(reaction); err != nil {
+		return invalid(err, map[string]string{"nickname": VoterCtxKey})
+	}
+	thread, err := h.Threads.React(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+		if dbconn.ErrorCode(err) == "AAAA1" {
+			return userNotFound(reaction.Nick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, threadView(thread))
+}
+
+func (h *Handler) UnreactToThread(ctx echo.Context) error {
+	id, ok := pathId(ctx)
+	if !ok {
+		return threadNotFound(ctx.Param(IdCtxKey))
+	}
+	reaction := &models.Reaction{Nick: ctx.Param(VoterCtxKey), Reaction: ctx.Param(ReactionCtxKey), ThreadId: id}
+	thread, err := h.Threads.Unreact(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return threadNotFound(ctx.Param(IdCtxKey))
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, threadView(thread))
+}
@@ -0,0 +1,244 @@
+package apiv2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func (h *Handler) CreateUser(ctx echo.Context) error {
+	newUser := NewUser{}
+	if err := ctx.Bind(&newUser); err != nil {
+		return badBody()
+	}
+	user := &models.User{Nick: newUser.Nickname, Name: newUser.Fullname, About: newUser.About, Email: newUser.Email}
+	if err := validate.User(user); err != nil {
+		return invalid(err, nil)
+	}
+	created, err := h.Users.Create(ctx.Request().Context(), user)
+	if err != nil {
+		conflictUsers, err := h.Users.GetByEmailOrNick(ctx.Request().Context(), user)
+		if err != nil || len(conflictUsers) == 0 {
+			return repoError(err)
+		}
+		return &Error{Status: http.StatusConflict, Code: CodeUserConflict, Message: errors.CONFLICT_USER, Existing: usersView(conflictUsers)}
+	}
+	return ctx.JSON(http.StatusCreated, userView(created))
+}
+
+// CreateUsers creates the users of the body in order and answers with the result of each.
+func (h *Handler) CreateUsers(ctx echo.Context) error {
+	newUsers := []NewUser{}
+	if err := ctx.Bind(&newUsers); err != nil {
+		return badBody()
+	}
+	users := make([]models.User, 0, len(newUsers))
+	for _, newUser := range newUsers {
+		users = append(users, models.User{Nick: newUser.Nickname, Name: newUser.Fullname, About: newUser.About, Email: newUser.Email})
+	}
+	if err := validate.Users(users); err != nil {
+		return invalid(err, nil)
+	}
+	results := make([]BulkUserResult, 0, len(users))
+	if len(users) == 0 {
+		return ctx.JSON(http.StatusOK, results)
+	}
+	conflicts, err := h.Users.CreateBulk(ctx.Request().Context(), users)
+	if err != nil {
+		return repoError(err)
+	}
+	for i := range users {
+		if len(conflicts[i]) > 0 {
+			results = append(results, BulkUserResult{Status: http.StatusConflict, Error: &ErrorDetail{Code: CodeUserConflict, Message: errors.CONFLICT_USER, Existing: usersView(conflicts[i])}})
+		} else {
+			results = append(results, BulkUserResult{Status: http.StatusCreated, User: userView(&users[i])})
+		}
+	}
+	return ctx.JSON(http.StatusOK, results)
+}
+
+// GetUsers looks up the comma separated nicknames; the unknown ones are left out.
+func (h *Handler) GetUsers(ctx echo.Context) error {
+	users, err := h.Users.GetByNicks(ctx.Request().Context(), strings.Split(ctx.QueryParam(NicknamesParam), ","))
+	if err != nil {
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, usersView(users))
+}
+
+func (h *Handler) GetUser(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	user, err := h.Users.GetByNick(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return h.redirectRenamed(ctx, nick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, userView(user))
+}
+
+// redirectRenamed sends the lookups by the nickname a user had before a rename to the current one.
+func (h *Handler) redirectRenamed(ctx echo.Context, nick string) error {
+	newNick, err := h.Users.GetRenamed(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return userNotFound(nick)
+		}
+		return repoError(err)
+	}
+	path := strings.Replace(ctx.Path(), ":"+NickCtxKey, url.PathEscape(newNick), 1)
+	if query := ctx.QueryString(); query != "" {
+		path += "?" + query
+	}
+	return ctx.Redirect(http.StatusPermanentRedirect, path)
+}
+
+func (h *Handler) RenameUser(ctx echo.Context) error {
+	rename := UserRename{}
+	if err := ctx.Bind(&rename); err != nil {
+		return badBody()
+	}
+	if err := validate.UserRename(&models.UserRename{Nick: rename.Nickname}); err != nil {
+		return invalid(err, nil)
+	}
+	nick := ctx.Param(NickCtxKey)
+	if err := checkNotGhost(nick); err != nil {
+		return err
+	}
+	renamed, err := h.Users.Rename(ctx.Request().Context(), nick, rename.Nickname)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return userNotFound(nick)
+		}
+		if dbconn.ErrorCode(err) == "23505" {
+			conflictUser, err := h.Users.GetByNick(ctx.Request().Context(), rename.Nickname)
+			if err != nil {
+				return repoError(err)
+			}
+			return &Error{Status: http.StatusConflict, Code: CodeUserConflict, Message: errors.NICKNAME_TAKEN + conflictUser.Nick, Existing: usersView([]models.User{*conflictUser})}
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, userView(renamed))
+}
+
+func (h *Handler) UpdateUser(ctx echo.Context) error {
+	update := UserUpdate{}
+	if err := ctx.Bind(&update); err != nil {
+		return badBody()
+	}
+	user := &models.User{Nick: ctx.Param(NickCtxKey), Name: update.Fullname, About: update.About, Email: update.Email}
+	if err := validate.UserUpdate(user); err != nil {
+		return invalid(err, nil)
+	}
+	if err := checkNotGhost(user.Nick); err != nil {
+		return err
+	}
+	updated, err := h.Users.Update(ctx.Request().Context(), user)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return userNotFound(user.Nick)
+		}
+		owner, err := h.Users.GetByEmail(ctx.Request().Context(), user.Email)
+		if err != nil {
+			return repoError(err)
+		}
+		return newError(http.StatusConflict, CodeEmailConflict, errors.EMAIL_ALREADY_IN_USE+owner)
+	}
+	return ctx.JSON(http.StatusOK, userView(updated))
+}
+
+// ExportUser answers with everything stored about the user as one JSON document or,
+// with format=ndjson, as one {"type", "data"} record per line.
+func (h *Handler) ExportUser(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	export, err := h.Users.Export(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return h.redirectRenamed(ctx, nick)
+		}
+		return repoError(err)
+	}
+	view := userExportView(export)
+	if ctx.QueryParam(FormatParam) != "ndjson" {
+		ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+view.User.Nickname+`.json"`)
+		return ctx.JSON(http.StatusOK, view)
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+view.User.Nickname+`.ndjson"`)
+	ctx.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	ctx.Response().WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(ctx.Response())
+	write := func(kind string, data interface{}) error {
+		return enc.Encode(models.ExportRecord{Type: kind, Data: data})
+	}
+	if err := write("user", view.User); err != nil {
+		return err
+	}
+	for _, thread := range view.Threads {
+		if err := write("thread", thread); err != nil {
+			return err
+		}
+	}
+	for _, post := range view.Posts {
+		if err := write("post", post); err != nil {
+			return err
+		}
+	}
+	for _, vote := range view.Votes {
+		if err := write("vote", vote); err != nil {
+			return err
+		}
+	}
+	for _, vote := range view.PostVotes {
+		if err := write("post_vote", vote); err != nil {
+			return err
+		}
+	}
+	for _, reaction := range view.Reactions {
+		if err := write("reaction", reaction); err != nil {
+			return err
+		}
+	}
+	for _, forum := range view.Forums {
+		if err := write("forum", forum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetKarma sums up the voices given to the threads and posts of the user.
+func (h *Handler) GetKarma(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	karma, err := h.Users.Karma(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return h.redirectRenamed(ctx, nick)
+		}
+		return repoError(err)
+	}
+	return ctx.JSON(http.StatusOK, karmaView(karma))
+}
+
+func (h *Handler) DeleteUser(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	if err := checkNotGhost(nick); err != nil {
+		return err
+	}
+	if err := h.Users.Delete(ctx.Request().Context(), nick); err != nil {
+		if err == pgx.ErrNoRows {
+			return userNotFound(nick)
+		}
+		return repoError(err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+// Package bench fills a running server with a generated dataset and replays a read/write mix against it.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	serviceDelivery "github.com/Natali-Skv/technopark_db_forum/internal/service/delivery/http"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/auth"
+)
+
+const (
+	fillPhase   = "fill"
+	replayPhase = "replay"
+	confirmAll  = "all"
+)
+
+type bench struct {
+	cfg    config.BenchConfigStruct
+	data   *dataset
+	client *client
+}
+
+// Run fills the server behind cfg.URL and replays the mix, returning a report per phase.
+// The replay is skipped if neither Duration nor Requests is set.
+func Run(ctx context.Context, cfg config.BenchConfigStruct) ([]*Report, error) {
+	if cfg.Users < 1 || cfg.Forums < 1 || cfg.Threads < 1 {
+		return nil, errors.New("bench needs at least one user, forum and thread")
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PostBatch < 1 {
+		cfg.PostBatch = 1
+	}
+	if cfg.UserBatch < 1 {
+		cfg.UserBatch = 1
+	}
+	b := &bench{cfg: cfg, data: plan(cfg), client: newClient(cfg.URL, cfg.Concurrency)}
+	if cfg.AdminToken != "" {
+		b.client.headers.Set(auth.AdminTokenHeader, cfg.AdminToken)
+	}
+	if _, _, err := parseMix(cfg.Mix); err != nil {
+		return nil, err
+	}
+
+	if cfg.Clear {
+		b.client.headers.Set(serviceDelivery.ConfirmClearHeader, confirmAll)
+		err := b.client.call(ctx, "clear", http.MethodPost, "/service/clear", nil, nil, http.StatusOK)
+		b.client.headers.Del(serviceDelivery.ConfirmClearHeader)
+		if err != nil {
+			return nil, fmt.Errorf("clear: %w", err)
+		}
+	}
+
+	reports := make([]*Report, 0, 2)
+	b.client.rec = newRecorder()
+	start := time.Now()
+	if err := b.fill(ctx); err != nil {
+		return nil, err
+	}
+	reports = append(reports, b.client.rec.report(fillPhase, time.Since(start)))
+
+	if cfg.Duration <= 0 && cfg.Requests <= 0 {
+		return reports, nil
+	}
+	replayCtx := ctx
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		replayCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	b.client.rec = newRecorder()
+	start = time.Now()
+	if err := b.replay(replayCtx); err != nil {
+		return reports, err
+	}
+	return append(reports, b.client.rec.report(replayPhase, time.Since(start))), nil
+}
+
+// Main is the entry point of the bench subcommand. Flags default to config.BenchConfig.
+func Main(args []string) int {
+	cfg := config.BenchConfig
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.StringVar(&cfg.URL, "url", cfg.URL, "API root of the server")
+	flags.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "admin token for -clear")
+	flags.BoolVar(&cfg.Clear, "clear", cfg.Clear, "clear the database before filling it")
+	flags.Int64Var(&cfg.Seed, "seed", cfg.Seed, "seed of the generated data and of the replay")
+	flags.IntVar(&cfg.Users, "users", cfg.Users, "users to create")
+	flags.IntVar(&cfg.Forums, "forums", cfg.Forums, "forums to create")
+	flags.IntVar(&cfg.Threads, "threads", cfg.Threads, "threads to create")
+	flags.IntVar(&cfg.Posts, "posts", cfg.Posts, "posts to create")
+	flags.IntVar(&cfg.Votes, "votes", cfg.Votes, "votes to cast")
+	flags.IntVar(&cfg.PostBatch, "post-batch", cfg.PostBatch, "posts per create request")
+	flags.IntVar(&cfg.MaxDepth, "max-depth", cfg.MaxDepth, "maximum depth of post trees")
+	flags.IntVar(&cfg.UserBatch, "user-batch", cfg.UserBatch, "users per bulk create request")
+	flags.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "concurrent requests")
+	flags.DurationVar(&cfg.Duration, "duration", cfg.Duration, "replay duration, 0 to skip the replay")
+	flags.IntVar(&cfg.Requests, "requests", cfg.Requests, "stop the replay after that many requests")
+	flags.StringVar(&cfg.Mix, "mix", cfg.Mix, "replayed operations, op=weight,...")
+	asJSON := flags.Bool("json", false, "print the reports as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	reports, err := Run(ctx, cfg)
+	printReports(os.Stdout, reports, *asJSON)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bench:", err)
+		return 1
+	}
+	return 0
+}
+
+func printReports(out io.Writer, reports []*Report, asJSON bool) {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(reports)
+		return
+	}
+	for _, report := range reports {
+		report.Print(out)
+	}
+}
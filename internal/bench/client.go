@@ -0,0 +1,77 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type client struct {
+	http    *http.Client
+	baseURL string
+	headers http.Header
+	rec     *recorder
+}
+
+func newClient(baseURL string, concurrency int) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = concurrency
+	return &client{
+		http:    &http.Client{Transport: transport, Timeout: time.Minute},
+		baseURL: baseURL,
+		headers: http.Header{},
+		rec:     newRecorder(),
+	}
+}
+
+// call sends body as JSON and records the latency under op. Any status but the expected ones is an error;
+// out is decoded only for an expected status.
+func (c *client) call(ctx context.Context, op string, method string, path string, body interface{}, out interface{}, expect ...int) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	for key, values := range c.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.rec.record(op, time.Since(start), true)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	latency := time.Since(start)
+	if err != nil {
+		c.rec.record(op, latency, true)
+		return err
+	}
+	for _, status := range expect {
+		if resp.StatusCode == status {
+			c.rec.record(op, latency, false)
+			if out == nil {
+				return nil
+			}
+			return json.Unmarshal(respBody, out)
+		}
+	}
+	c.rec.record(op, latency, true)
+	return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(respBody))
+}
@@ -0,0 +1,209 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+var (
+	firstNames = []string{"alexander", "maria", "ivan", "olga", "dmitry", "anna", "sergey", "elena", "pavel", "natalia",
+		"mikhail", "tatiana", "andrey", "irina", "nikita", "daria", "egor", "polina", "artem", "sofia"}
+	lastNames = []string{"ivanov", "smirnov", "kuznetsov", "popov", "vasiliev", "petrov", "sokolov", "mikhailov",
+		"novikov", "fedorov", "morozov", "volkov", "alekseev", "lebedev", "semenov", "egorov"}
+	domains = []string{"mail.ru", "bk.ru", "yandex.ru", "gmail.com", "inbox.ru"}
+	words   = []string{"postgres", "index", "query", "forum", "thread", "vacuum", "cluster", "plan", "cache", "tree",
+		"path", "vote", "slug", "nickname", "trigger", "latency", "bench", "replica", "lock", "page", "tuple", "heap",
+		"btree", "hash", "join", "sort", "limit", "since", "cursor", "batch"}
+)
+
+const (
+	rootPostShare = 0.15
+	// deepReplyShare of replies answer one of the latest posts, which grows long branches.
+	deepReplyShare = 0.5
+	deepWindow     = 10
+	createdLayout  = "2006-01-02T15:04:05.000Z07:00"
+)
+
+type plannedThread struct {
+	forum  int
+	author int
+	thread models.Thread
+	posts  []plannedPost
+}
+
+type plannedPost struct {
+	// parent indexes the posts of the same thread, -1 is a root post
+	parent int
+	depth  int
+	author int
+	text   string
+}
+
+type plannedVote struct {
+	user   int
+	thread int
+	voice  int
+}
+
+// dataset is what the seed generates. The ids the server assigns are filled in as the data is created.
+type dataset struct {
+	users   []models.User
+	forums  []models.Forum
+	threads []plannedThread
+	votes   []plannedVote
+
+	threadIds []int
+	mu        sync.RWMutex
+	postIds   [][]int
+	allPosts  []int
+}
+
+// plan generates the whole dataset up front, so the same seed always yields the same data.
+func plan(cfg config.BenchConfigStruct) *dataset {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	data := &dataset{}
+
+	for i := 0; i < cfg.Users; i++ {
+		first, last := firstNames[rng.Intn(len(firstNames))], lastNames[rng.Intn(len(lastNames))]
+		nick := fmt.Sprintf("%s.%s.%d", first, last, i)
+		data.users = append(data.users, models.User{
+			Name:  capitalize(first) + " " + capitalize(last),
+			Nick:  nick,
+			Email: nick + "@" + domains[rng.Intn(len(domains))],
+			About: sentence(rng, 5, 20),
+		})
+	}
+
+	for i := 0; i < cfg.Forums; i++ {
+		data.forums = append(data.forums, models.Forum{
+			Slug:     fmt.Sprintf("%s-%d", words[rng.Intn(len(words))], i),
+			Title:    sentence(rng, 2, 6),
+			UserNick: data.users[rng.Intn(len(data.users))].Nick,
+		})
+	}
+
+	// a few forums and threads get most of the traffic
+	forumZipf := rand.NewZipf(rng, 1.2, 1, uint64(cfg.Forums-1))
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < cfg.Threads; i++ {
+		t := plannedThread{forum: int(forumZipf.Uint64()), author: rng.Intn(len(data.users))}
+		t.thread = models.Thread{
+			Title:   sentence(rng, 2, 8),
+			Message: sentence(rng, 10, 60),
+			Created: base.Add(time.Duration(rng.Intn(365*24*60)) * time.Minute).Format(createdLayout),
+		}
+		if rng.Float64() < 0.8 {
+			t.thread.Slug = fmt.Sprintf("%s-%s-%d", words[rng.Intn(len(words))], words[rng.Intn(len(words))], i)
+		}
+		data.threads = append(data.threads, t)
+	}
+
+	threadZipf := rand.NewZipf(rng, 1.1, 1, uint64(cfg.Threads-1))
+	for i := 0; i < cfg.Posts; i++ {
+		t := &data.threads[threadZipf.Uint64()]
+		t.posts = append(t.posts, plannedPost{author: rng.Intn(len(data.users)), text: sentence(rng, 3, 40)})
+	}
+	for i := range data.threads {
+		planTree(rng, data.threads[i].posts, cfg.PostBatch, cfg.MaxDepth)
+	}
+
+	for i := 0; i < cfg.Votes; i++ {
+		voice := 1
+		if rng.Intn(3) == 0 {
+			voice = -1
+		}
+		data.votes = append(data.votes, plannedVote{user: rng.Intn(len(data.users)), thread: int(threadZipf.Uint64()), voice: voice})
+	}
+
+	data.threadIds = make([]int, len(data.threads))
+	data.postIds = make([][]int, len(data.threads))
+	return data
+}
+
+// planTree picks the parents. A post can only answer a post of an earlier batch, whose id is known by then.
+func planTree(rng *rand.Rand, posts []plannedPost, batch int, maxDepth int) {
+	eligible := make([]int, 0, len(posts))
+	for start := 0; start < len(posts); start += batch {
+		end := start + batch
+		if end > len(posts) {
+			end = len(posts)
+		}
+		for i := start; i < end; i++ {
+			posts[i].parent = -1
+			if len(eligible) == 0 || rng.Float64() < rootPostShare {
+				continue
+			}
+			var parent int
+			if rng.Float64() < deepReplyShare {
+				window := deepWindow
+				if window > len(eligible) {
+					window = len(eligible)
+				}
+				parent = eligible[len(eligible)-1-rng.Intn(window)]
+			} else {
+				parent = eligible[rng.Intn(len(eligible))]
+			}
+			posts[i].parent = parent
+			posts[i].depth = posts[parent].depth + 1
+		}
+		for i := start; i < end; i++ {
+			if posts[i].depth < maxDepth {
+				eligible = append(eligible, i)
+			}
+		}
+	}
+}
+
+func sentence(rng *rand.Rand, min int, max int) string {
+	n := min + rng.Intn(max-min+1)
+	picked := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		picked = append(picked, words[rng.Intn(len(words))])
+	}
+	return strings.Join(picked, " ")
+}
+
+func capitalize(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func (d *dataset) addPosts(thread int, ids []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.postIds[thread] = append(d.postIds[thread], ids...)
+	d.allPosts = append(d.allPosts, ids...)
+}
+
+// randomPost returns 0 if there are no posts yet.
+func (d *dataset) randomPost(rng *rand.Rand) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if len(d.allPosts) == 0 {
+		return 0
+	}
+	return d.allPosts[rng.Intn(len(d.allPosts))]
+}
+
+func (d *dataset) randomThreadPost(rng *rand.Rand, thread int) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ids := d.postIds[thread]
+	if len(ids) == 0 {
+		return 0
+	}
+	return ids[rng.Intn(len(ids))]
+}
+
+// threadPath addresses a thread by slug or by id, whichever the coin says.
+func (d *dataset) threadPath(rng *rand.Rand, thread int) string {
+	if slug := d.threads[thread].thread.Slug; slug != "" && rng.Intn(2) == 0 {
+		return slug
+	}
+	return fmt.Sprint(d.threadIds[thread])
+}
@@ -0,0 +1,157 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+// parallel runs work(i) for i in [0, n) on the given number of workers and returns the first error.
+// The other workers stop at their next item once an error is seen.
+func parallel(ctx context.Context, workers int, n int, work func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	items := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				if err := work(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case items <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(items)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// fill creates the dataset on the server. Users, forums and slugged threads that already exist are reused,
+// so a run without Clear against a server filled with the same seed only adds posts and votes.
+func (b *bench) fill(ctx context.Context) error {
+	data := b.data
+	batches := (len(data.users) + b.cfg.UserBatch - 1) / b.cfg.UserBatch
+	err := parallel(ctx, b.cfg.Concurrency, batches, func(ctx context.Context, i int) error {
+		end := (i + 1) * b.cfg.UserBatch
+		if end > len(data.users) {
+			end = len(data.users)
+		}
+		return b.client.call(ctx, "fill_users", http.MethodPost, "/users/bulk", data.users[i*b.cfg.UserBatch:end], nil,
+			http.StatusOK)
+	})
+	if err != nil {
+		return fmt.Errorf("create users: %w", err)
+	}
+
+	err = parallel(ctx, b.cfg.Concurrency, len(data.forums), func(ctx context.Context, i int) error {
+		return b.client.call(ctx, "fill_forums", http.MethodPost, "/forum/create", data.forums[i], nil,
+			http.StatusCreated, http.StatusConflict)
+	})
+	if err != nil {
+		return fmt.Errorf("create forums: %w", err)
+	}
+
+	err = parallel(ctx, b.cfg.Concurrency, len(data.threads), func(ctx context.Context, i int) error {
+		planned := data.threads[i]
+		thread := planned.thread
+		thread.AuthorNick = data.users[planned.author].Nick
+		created := models.Thread{}
+		err := b.client.call(ctx, "fill_threads", http.MethodPost, "/forum/"+data.forums[planned.forum].Slug+"/create",
+			thread, &created, http.StatusCreated, http.StatusConflict)
+		if err != nil {
+			return err
+		}
+		data.threadIds[i] = created.Id
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("create threads: %w", err)
+	}
+
+	// the batches of one thread go in order, so every parent already has its id
+	workers := b.cfg.Concurrency
+	if workers > len(data.threads) {
+		workers = len(data.threads)
+	}
+	err = parallel(ctx, workers, workers, func(ctx context.Context, worker int) error {
+		for i := worker; i < len(data.threads); i += workers {
+			if err := b.fillThreadPosts(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("create posts: %w", err)
+	}
+
+	err = parallel(ctx, b.cfg.Concurrency, len(data.votes), func(ctx context.Context, i int) error {
+		vote := data.votes[i]
+		body := models.Vote{Nick: data.users[vote.user].Nick, Voice: vote.voice}
+		return b.client.call(ctx, "fill_votes", http.MethodPost, fmt.Sprintf("/thread/%d/vote", data.threadIds[vote.thread]),
+			body, nil, http.StatusOK)
+	})
+	if err != nil {
+		return fmt.Errorf("create votes: %w", err)
+	}
+	return nil
+}
+
+func (b *bench) fillThreadPosts(ctx context.Context, thread int) error {
+	planned := b.data.threads[thread].posts
+	ids := make([]int, 0, len(planned))
+	for start := 0; start < len(planned); start += b.cfg.PostBatch {
+		end := start + b.cfg.PostBatch
+		if end > len(planned) {
+			end = len(planned)
+		}
+		batch := make([]models.Post, 0, end-start)
+		for _, post := range planned[start:end] {
+			parent := 0
+			if post.parent >= 0 {
+				parent = ids[post.parent]
+			}
+			batch = append(batch, models.Post{AuthorNick: b.data.users[post.author].Nick, ParentId: parent, Message: post.text})
+		}
+		created := make([]models.Post, 0, len(batch))
+		err := b.client.call(ctx, "fill_posts", http.MethodPost, fmt.Sprintf("/thread/%d/create", b.data.threadIds[thread]),
+			batch, &created, http.StatusCreated)
+		if err != nil {
+			return err
+		}
+		if len(created) != len(batch) {
+			return fmt.Errorf("thread %d: created %d posts of %d", b.data.threadIds[thread], len(created), len(batch))
+		}
+		batchIds := make([]int, 0, len(created))
+		for _, post := range created {
+			batchIds = append(batchIds, post.Id)
+		}
+		ids = append(ids, batchIds...)
+		b.data.addPosts(thread, batchIds)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+var (
+	threadPostSorts = []string{"flat", "tree", "parent_tree"}
+	relatedOptions  = []string{"", "user", "forum", "thread", "user,thread", "user,forum,thread"}
+	pageLimits      = []int{10, 20, 50, 100}
+)
+
+type operation func(ctx context.Context, b *bench, rng *rand.Rand) error
+
+var operations = map[string]operation{
+	"thread_posts":   threadPosts,
+	"thread_details": threadDetails,
+	"post_details":   postDetails,
+	"forum_threads":  forumThreads,
+	"forum_users":    forumUsers,
+	"forum_details":  forumDetails,
+	"user_profile":   userProfile,
+	"status":         status,
+	"create_posts":   createPosts,
+	"vote":           vote,
+	"update_post":    updatePost,
+}
+
+type weightedOp struct {
+	name   string
+	weight int
+}
+
+// parseMix reads "op=weight,..." into the operations to pick from. Weights are relative.
+func parseMix(mix string) ([]weightedOp, int, error) {
+	ops := make([]weightedOp, 0)
+	total := 0
+	for _, item := range strings.Split(mix, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, weightStr, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, 0, fmt.Errorf("mix %q: expected op=weight", item)
+		}
+		if _, known := operations[name]; !known {
+			return nil, 0, fmt.Errorf("mix: unknown operation %q, known are %s", name, strings.Join(operationNames(), ", "))
+		}
+		weight, err := strconv.Atoi(weightStr)
+		if err != nil || weight < 0 {
+			return nil, 0, fmt.Errorf("mix %q: bad weight", item)
+		}
+		if weight == 0 {
+			continue
+		}
+		ops = append(ops, weightedOp{name: name, weight: weight})
+		total += weight
+	}
+	if total == 0 {
+		return nil, 0, fmt.Errorf("mix %q has no operations", mix)
+	}
+	return ops, total, nil
+}
+
+func operationNames() []string {
+	names := make([]string, 0, len(operations))
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// replay runs the mix until ctx is done or Requests requests have been sent. Failed requests are
+// only counted: a replay is expected to see a few conflicts and not-founds under concurrent writes.
+func (b *bench) replay(ctx context.Context) error {
+	ops, total, err := parseMix(b.cfg.Mix)
+	if err != nil {
+		return err
+	}
+	var sent int64
+	var wg sync.WaitGroup
+	for w := 0; w < b.cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(b.cfg.Seed + int64(worker) + 1))
+			for ctx.Err() == nil {
+				if b.cfg.Requests > 0 && atomic.AddInt64(&sent, 1) > int64(b.cfg.Requests) {
+					return
+				}
+				pick := rng.Intn(total)
+				for _, op := range ops {
+					if pick < op.weight {
+						// the error is already in the recorder
+						_ = operations[op.name](ctx, b, rng)
+						break
+					}
+					pick -= op.weight
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	return nil
+}
+
+func threadPosts(ctx context.Context, b *bench, rng *rand.Rand) error {
+	thread := rng.Intn(len(b.data.threads))
+	query := url.Values{}
+	query.Set("sort", threadPostSorts[rng.Intn(len(threadPostSorts))])
+	query.Set("limit", strconv.Itoa(pageLimits[rng.Intn(len(pageLimits))]))
+	if rng.Intn(2) == 0 {
+		query.Set("desc", "true")
+	}
+	if rng.Intn(3) == 0 {
+		if since := b.data.randomThreadPost(rng, thread); since != 0 {
+			query.Set("since", strconv.Itoa(since))
+		}
+	}
+	return b.client.call(ctx, "thread_posts", http.MethodGet, "/thread/"+b.data.threadPath(rng, thread)+"/posts?"+query.Encode(),
+		nil, nil, http.StatusOK)
+}
+
+func threadDetails(ctx context.Context, b *bench, rng *rand.Rand) error {
+	thread := rng.Intn(len(b.data.threads))
+	return b.client.call(ctx, "thread_details", http.MethodGet, "/thread/"+b.data.threadPath(rng, thread)+"/details",
+		nil, nil, http.StatusOK)
+}
+
+func postDetails(ctx context.Context, b *bench, rng *rand.Rand) error {
+	id := b.data.randomPost(rng)
+	if id == 0 {
+		return nil
+	}
+	path := fmt.Sprintf("/post/%d/details", id)
+	if related := relatedOptions[rng.Intn(len(relatedOptions))]; related != "" {
+		path += "?related=" + related
+	}
+	return b.client.call(ctx, "post_details", http.MethodGet, path, nil, nil, http.StatusOK)
+}
+
+func forumThreads(ctx context.Context, b *bench, rng *rand.Rand) error {
+	forum := b.data.forums[rng.Intn(len(b.data.forums))]
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(pageLimits[rng.Intn(len(pageLimits))]))
+	if rng.Intn(2) == 0 {
+		query.Set("desc", "true")
+	}
+	if rng.Intn(3) == 0 {
+		query.Set("since", b.data.threads[rng.Intn(len(b.data.threads))].thread.Created)
+	}
+	return b.client.call(ctx, "forum_threads", http.MethodGet, "/forum/"+forum.Slug+"/threads?"+query.Encode(),
+		nil, nil, http.StatusOK)
+}
+
+func forumUsers(ctx context.Context, b *bench, rng *rand.Rand) error {
+	forum := b.data.forums[rng.Intn(len(b.data.forums))]
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(pageLimits[rng.Intn(len(pageLimits))]))
+	if rng.Intn(2) == 0 {
+		query.Set("desc", "true")
+	}
+	if rng.Intn(3) == 0 {
+		query.Set("since", b.data.users[rng.Intn(len(b.data.users))].Nick)
+	}
+	if rng.Intn(4) == 0 {
+		query.Set("sort", "activity")
+	}
+	return b.client.call(ctx, "forum_users", http.MethodGet, "/forum/"+forum.Slug+"/users?"+query.Encode(),
+		nil, nil, http.StatusOK)
+}
+
+func forumDetails(ctx context.Context, b *bench, rng *rand.Rand) error {
+	forum := b.data.forums[rng.Intn(len(b.data.forums))]
+	return b.client.call(ctx, "forum_details", http.MethodGet, "/forum/"+forum.Slug+"/details", nil, nil, http.StatusOK)
+}
+
+func userProfile(ctx context.Context, b *bench, rng *rand.Rand) error {
+	user := b.data.users[rng.Intn(len(b.data.users))]
+	return b.client.call(ctx, "user_profile", http.MethodGet, "/user/"+user.Nick+"/profile", nil, nil, http.StatusOK)
+}
+
+func status(ctx context.Context, b *bench, rng *rand.Rand) error {
+	return b.client.call(ctx, "status", http.MethodGet, "/service/status", nil, nil, http.StatusOK)
+}
+
+func createPosts(ctx context.Context, b *bench, rng *rand.Rand) error {
+	thread := rng.Intn(len(b.data.threads))
+	n := 1 + rng.Intn(5)
+	batch := make([]models.Post, 0, n)
+	for i := 0; i < n; i++ {
+		post := models.Post{
+			AuthorNick: b.data.users[rng.Intn(len(b.data.users))].Nick,
+			Message:    sentence(rng, 3, 40),
+		}
+		if rng.Float64() >= rootPostShare {
+			post.ParentId = b.data.randomThreadPost(rng, thread)
+		}
+		batch = append(batch, post)
+	}
+	created := make([]models.Post, 0, n)
+	err := b.client.call(ctx, "create_posts", http.MethodPost, "/thread/"+b.data.threadPath(rng, thread)+"/create",
+		batch, &created, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(created))
+	for _, post := range created {
+		ids = append(ids, post.Id)
+	}
+	b.data.addPosts(thread, ids)
+	return nil
+}
+
+func vote(ctx context.Context, b *bench, rng *rand.Rand) error {
+	thread := rng.Intn(len(b.data.threads))
+	body := models.Vote{Nick: b.data.users[rng.Intn(len(b.data.users))].Nick, Voice: 1}
+	if rng.Intn(3) == 0 {
+		body.Voice = -1
+	}
+	return b.client.call(ctx, "vote", http.MethodPost, "/thread/"+b.data.threadPath(rng, thread)+"/vote",
+		body, nil, http.StatusOK)
+}
+
+func updatePost(ctx context.Context, b *bench, rng *rand.Rand) error {
+	id := b.data.randomPost(rng)
+	if id == 0 {
+		return nil
+	}
+	body := map[string]string{"message": sentence(rng, 3, 40)}
+	return b.client.call(ctx, "update_post", http.MethodPost, fmt.Sprintf("/post/%d/details", id), body, nil, http.StatusOK)
+}
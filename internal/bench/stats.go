@@ -0,0 +1,108 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// recorder collects request latencies per operation. It is safe for concurrent use.
+type recorder struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+}
+
+func newRecorder() *recorder {
+	return &recorder{latencies: map[string][]time.Duration{}, errors: map[string]int{}}
+}
+
+func (r *recorder) record(op string, latency time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies[op] = append(r.latencies[op], latency)
+	if failed {
+		r.errors[op]++
+	}
+}
+
+type OpStats struct {
+	Name       string        `json:"name"`
+	Count      int           `json:"count"`
+	Errors     int           `json:"errors"`
+	Throughput float64       `json:"rps"`
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+}
+
+// Report sums up one phase of a run: the fill or the replay.
+type Report struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"duration"`
+	Ops      []OpStats     `json:"ops"`
+	Total    OpStats       `json:"total"`
+}
+
+func (r *recorder) report(phase string, elapsed time.Duration) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := &Report{Phase: phase, Duration: elapsed}
+	all := make([]time.Duration, 0)
+	totalErrors := 0
+	for op, latencies := range r.latencies {
+		report.Ops = append(report.Ops, opStats(op, latencies, r.errors[op], elapsed))
+		all = append(all, latencies...)
+		totalErrors += r.errors[op]
+	}
+	sort.Slice(report.Ops, func(i, j int) bool { return report.Ops[i].Name < report.Ops[j].Name })
+	report.Total = opStats("total", all, totalErrors, elapsed)
+	return report
+}
+
+func opStats(name string, latencies []time.Duration, errors int, elapsed time.Duration) OpStats {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats := OpStats{Name: name, Count: len(sorted), Errors: errors}
+	if len(sorted) == 0 {
+		return stats
+	}
+	if elapsed > 0 {
+		stats.Throughput = float64(len(sorted)) / elapsed.Seconds()
+	}
+	stats.P50 = percentile(sorted, 0.50)
+	stats.P90 = percentile(sorted, 0.90)
+	stats.P99 = percentile(sorted, 0.99)
+	stats.Max = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile uses the nearest-rank method on sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func (r *Report) Print(out io.Writer) {
+	fmt.Fprintf(out, "%s: %d requests in %s\n", r.Phase, r.Total.Count, r.Duration.Round(time.Millisecond))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "op\tcount\terrors\trps\tp50\tp90\tp99\tmax\t")
+	for _, op := range append(r.Ops, r.Total) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op.Name, op.Count, op.Errors, op.Throughput,
+			round(op.P50), round(op.P90), round(op.P99), round(op.Max))
+	}
+	w.Flush()
+	fmt.Fprintln(out)
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
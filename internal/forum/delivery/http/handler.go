@@ -6,8 +6,12 @@ import (
 
 	forumRepo "github.com/Natali-Skv/technopark_db_forum/internal/forum"
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
 	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
-	"github.com/jackc/pgx"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/go-openapi/strfmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -16,6 +20,8 @@ const (
 	DescSortQueryParam = "desc"
 	SinceQueryParam    = "since"
 	LimitQueryParam    = "limit"
+	SortQueryParam     = "sort"
+	PeriodQueryParam   = "period"
 )
 
 type Handler struct {
@@ -30,31 +36,33 @@ func (h *Handler) CreateForum(ctx echo.Context) error {
 	if err := ctx.Bind(forum); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
-	newForum, err := h.Repo.Create(forum)
+	if err := validate.Forum(forum); err != nil {
+		return validate.HTTPError(err)
+	}
+	newForum, err := h.Repo.Create(ctx.Request().Context(), forum)
 	if err != nil {
-		pgerr, _ := err.(pgx.PgError)
-		switch pgerr.Code {
+		switch dbconn.ErrorCode(err) {
 		case "23505":
-			conflictForum, err := h.Repo.GetBySlug(forum.Slug)
+			conflictForum, err := h.Repo.GetBySlug(ctx.Request().Context(), forum.Slug)
 			if err != nil || conflictForum == nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+				return deadline.HTTPError(err)
 			}
 			return ctx.JSON(http.StatusConflict, conflictForum)
 		case "AAAA1":
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+forum.UserNick)
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusCreated, newForum)
 }
 func (h *Handler) GetForum(ctx echo.Context) error {
 	slug := ctx.Param(SlugCtxKey)
-	userResp, err := h.Repo.GetBySlug(slug)
+	userResp, err := h.Repo.GetBySlug(ctx.Request().Context(), slug)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+slug)
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, userResp)
 }
@@ -68,12 +76,22 @@ func (h *Handler) GetForumThreads(ctx echo.Context) error {
 	}
 	limit, _ := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
 	slug := ctx.Param(SlugCtxKey)
-	threads, err := h.Repo.GetForumThreads(slug, desc, limit, since)
+	sort := ctx.QueryParam(SortQueryParam)
+	if since != "" {
+		if sort == "" || sort == "created" {
+			if _, err := strfmt.ParseDateTime(since); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_THREAD_SINCE)
+			}
+		} else if _, err := strconv.Atoi(since); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_THREAD_SINCE)
+		}
+	}
+	threads, err := h.Repo.GetForumThreads(ctx.Request().Context(), slug, desc, limit, since, sort, ctx.QueryParam(PeriodQueryParam))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	if len(threads) == 0 {
-		if exists, err := h.Repo.CheckBySlug(slug); !exists && err == nil {
+		if exists, err := h.Repo.CheckBySlug(ctx.Request().Context(), slug); !exists && err == nil {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+slug)
 		}
 	}
@@ -85,6 +103,7 @@ func (h *Handler) GetForumUsers(ctx echo.Context) error {
 
 	limit, _ := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
 	since := ctx.QueryParam(SinceQueryParam)
+	sort := ctx.QueryParam(SortQueryParam)
 
 	descStr := ctx.QueryParam(DescSortQueryParam)
 	desc := false
@@ -92,16 +111,16 @@ func (h *Handler) GetForumUsers(ctx echo.Context) error {
 		desc = true
 	}
 
-	users, err := h.Repo.GetForumUsers(slug, desc, limit, since)
+	users, err := h.Repo.GetForumUsers(ctx.Request().Context(), slug, desc, limit, since, sort)
 
 	if err != nil {
-		return ctx.JSON(http.StatusOK, users)
+		return deadline.HTTPError(err)
 	}
 	if len(users) == 0 {
-		if exists, err := h.Repo.CheckBySlug(slug); !exists && err == nil {
+		if exists, err := h.Repo.CheckBySlug(ctx.Request().Context(), slug); !exists && err == nil {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+slug)
 		}
-		return ctx.JSON(http.StatusOK, []models.User{})
+		return ctx.JSON(http.StatusOK, []models.ForumUser{})
 	}
 
 	return ctx.JSON(http.StatusOK, users)
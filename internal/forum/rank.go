@@ -0,0 +1,43 @@
+package forum
+
+import (
+	goErrors "errors"
+	"math"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+)
+
+// hotDecay is how many seconds newer a thread has to be to rank as hot as one with ten times its votes.
+const hotDecay = 45000
+
+// PeriodStart returns the time the threads of sort=top are created since: nothing for "all",
+// otherwise a day, week, month or year back from now.
+func PeriodStart(period string, now time.Time) (*time.Time, error) {
+	var start time.Time
+	switch period {
+	case "all", "":
+		return nil, nil
+	case "day":
+		start = now.AddDate(0, 0, -1)
+	case "week":
+		start = now.AddDate(0, 0, -7)
+	case "month":
+		start = now.AddDate(0, -1, 0)
+	case "year":
+		start = now.AddDate(-1, 0, 0)
+	default:
+		return nil, goErrors.New(errors.UNKNOWN_PERIOD)
+	}
+	return &start, nil
+}
+
+// Hotness is the rank of a thread for sort=hot, the same as the threads.hot column: the order of
+// magnitude of its votes plus its creation time, so that new threads overtake old ones as time goes.
+func Hotness(votes int, created time.Time) float64 {
+	score := math.Log10(math.Max(math.Abs(float64(votes)), 1))
+	if votes < 0 {
+		score = -score
+	}
+	return score + float64(created.UnixMicro())/1e6/hotDecay
+}
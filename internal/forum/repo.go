@@ -1,11 +1,20 @@
 package forum
 
-import "github.com/Natali-Skv/technopark_db_forum/internal/models"
+import (
+	"context"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
 
 type Repo interface {
-	Create(forum *models.Forum) (*models.Forum, error)
-	GetBySlug(slug string) (*models.Forum, error)
-	CheckBySlug(slug string) (bool, error)
-	GetForumThreads(slug string, desc bool, limit int, since string) ([]models.Thread, error)
-	GetForumUsers(slug string, desc bool, limit int, since string) ([]models.User, error)
+	Create(ctx context.Context, forum *models.Forum) (*models.Forum, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Forum, error)
+	CheckBySlug(ctx context.Context, slug string) (bool, error)
+	// GetForumThreads orders the threads by creation time, since being the earliest (or, desc, the latest)
+	// creation time of the page; or by hot, top or active, since being the id of the thread the page starts
+	// after. period is the window of top, see PeriodStart.
+	GetForumThreads(ctx context.Context, slug string, desc bool, limit int, since string, sort string, period string) ([]models.Thread, error)
+	// GetForumUsers orders the users by nickname, or by last activity when sort is "activity";
+	// since is the nickname of the user the page starts after in either order.
+	GetForumUsers(ctx context.Context, slug string, desc bool, limit int, since string, sort string) ([]models.ForumUser, error)
 }
@@ -1,56 +1,118 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
+	goErrors "errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Natali-Skv/technopark_db_forum/internal/forum"
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
 	"github.com/go-openapi/strfmt"
-	"github.com/jackc/pgx"
+)
+
+const (
+	forumUsersQuery   = "SELECT u.name, u.nick, u.email, u.about, fu.posts, fu.threads, fu.first_activity, fu.last_activity FROM forum_users fu JOIN users u ON u.nick=fu.nick WHERE "
+	forumUserActivity = "(SELECT last_activity, nick FROM forum_users WHERE forum_slug=$1 AND nick=$3)"
+	threadsQuery      = "SELECT id, slug, title, author_nick, forum_slug, message, votes, created, posts_count, CASE WHEN posts_count>0 THEN last_post_at END, COALESCE(last_post_author, ''), participants_count FROM threads WHERE forum_slug=$1 AND "
+	// the ranked sorts page by (rank, id) after the thread given by since
+	threadsHot    = threadsQuery + "($2=0 OR (hot, id) %s (SELECT hot, id FROM threads WHERE id=$3)) ORDER BY hot %[2]s, id %[2]s LIMIT NULLIF($4,0)"
+	threadsTop    = threadsQuery + "($2=0 OR (votes, id) %s (SELECT votes, id FROM threads WHERE id=$3)) AND ($5::timestamptz IS NULL OR created>=$5) ORDER BY votes %[2]s, id %[2]s LIMIT NULLIF($4,0)"
+	threadsActive = threadsQuery + "($2=0 OR (last_post_at, id) %s (SELECT last_post_at, id FROM threads WHERE id=$3)) ORDER BY last_post_at %[2]s, id %[2]s LIMIT NULLIF($4,0)"
 )
 
 type Repo struct {
-	Conn *pgx.ConnPool
+	Conn *dbconn.Pool
 }
 
-func NewRepo(conn *pgx.ConnPool) *Repo {
-	conn.Prepare("create_forum", "INSERT into forums(title, slug, author_nick) VALUES ($1,$2,$3) RETURNING author_nick")
-	conn.Prepare("get_by_slug_forum", "SELECT slug, title, posts, threads, author_nick FROM forums WHERE slug =$1")
-	conn.Prepare("check_by_slug", "SELECT exists(SELECT 1 FROM forums WHERE slug =$1)")
-	conn.Prepare("get_forum_users_desc", "SELECT name,nick,email,about FROM forum_users WHERE forum_slug=$1 AND ($2='' OR nick<$3) ORDER BY nick DESC LIMIT NULLIF($4,0)")
-	conn.Prepare("get_forum_users", "SELECT name,nick,email,about FROM forum_users WHERE forum_slug=$1 AND ($2='' OR nick>$3) ORDER BY nick LIMIT NULLIF($4,0)")
-	conn.Prepare("get_threads", "SELECT id, slug, title, author_nick, forum_slug, message, votes, created FROM threads WHERE forum_slug =$1 AND ($2::text IS NULL OR created>=$3) ORDER BY created LIMIT NULLIF($4,0)")
-	conn.Prepare("get_threads_desc", "SELECT id, slug, title, author_nick, forum_slug, message, votes, created FROM threads WHERE forum_slug =$1 AND ($2::text IS NULL OR created<=$3) ORDER BY created DESC LIMIT NULLIF($4,0)")
+func NewRepo(conn *dbconn.Pool) *Repo {
+	conn.Register("create_forum", "INSERT into forums(title, slug, author_nick) VALUES ($1,$2,$3) RETURNING author_nick")
+	conn.Register("get_by_slug_forum", "SELECT slug, title, posts, threads, author_nick FROM forums WHERE slug =$1")
+	conn.Register("check_by_slug", "SELECT exists(SELECT 1 FROM forums WHERE slug =$1)")
+	conn.Register("get_forum_users_desc", forumUsersQuery+"fu.forum_slug=$1 AND ($2='' OR fu.nick<$3) ORDER BY fu.nick DESC LIMIT NULLIF($4,0)")
+	conn.Register("get_forum_users", forumUsersQuery+"fu.forum_slug=$1 AND ($2='' OR fu.nick>$3) ORDER BY fu.nick LIMIT NULLIF($4,0)")
+	conn.Register("get_forum_users_activity_desc", forumUsersQuery+"fu.forum_slug=$1 AND ($2='' OR (fu.last_activity, fu.nick) < "+forumUserActivity+") ORDER BY fu.last_activity DESC, fu.nick DESC LIMIT NULLIF($4,0)")
+	conn.Register("get_forum_users_activity", forumUsersQuery+"fu.forum_slug=$1 AND ($2='' OR (fu.last_activity, fu.nick) > "+forumUserActivity+") ORDER BY fu.last_activity, fu.nick LIMIT NULLIF($4,0)")
+	conn.Register("get_threads", threadsQuery+"($2::timestamptz IS NULL OR created>=$2) ORDER BY created, id LIMIT NULLIF($3,0)")
+	conn.Register("get_threads_desc", threadsQuery+"($2::timestamptz IS NULL OR created<=$2) ORDER BY created DESC, id DESC LIMIT NULLIF($3,0)")
+	for name, query := range map[string]string{"get_threads_hot": threadsHot, "get_threads_top": threadsTop, "get_threads_active": threadsActive} {
+		conn.Register(name, fmt.Sprintf(query, ">", ""))
+		conn.Register(name+"_desc", fmt.Sprintf(query, "<", "DESC"))
+	}
 	return &Repo{Conn: conn}
 }
-func (r *Repo) Create(forum *models.Forum) (*models.Forum, error) {
-	err := r.Conn.QueryRow("EXECUTE create_forum($1,$2,$3)", forum.Title, forum.Slug, forum.UserNick).Scan(&forum.UserNick)
+func (r *Repo) Create(ctx context.Context, forum *models.Forum) (*models.Forum, error) {
+	ctx, span := tracing.Start(ctx, "forumRepo.Create")
+	defer span.End()
+	err := r.Conn.QueryRow(ctx, "create_forum", forum.Title, forum.Slug, forum.UserNick).Scan(&forum.UserNick)
 	if err != nil {
 		return nil, err
 	}
 
 	return forum, nil
 }
-func (r *Repo) GetBySlug(slug string) (*models.Forum, error) {
+func (r *Repo) GetBySlug(ctx context.Context, slug string) (*models.Forum, error) {
+	ctx, span := tracing.Start(ctx, "forumRepo.GetBySlug")
+	defer span.End()
 	forum := &models.Forum{}
-	err := r.Conn.QueryRow("EXECUTE get_by_slug_forum($1)", slug).Scan(&forum.Slug, &forum.Title, &forum.Posts, &forum.Threads, &forum.UserNick)
+	err := r.Conn.QueryRow(ctx, "get_by_slug_forum", slug).Scan(&forum.Slug, &forum.Title, &forum.Posts, &forum.Threads, &forum.UserNick)
 	if err != nil {
 		return nil, err
 	}
 	return forum, nil
 }
-func (r *Repo) CheckBySlug(slug string) (bool, error) {
+func (r *Repo) CheckBySlug(ctx context.Context, slug string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "forumRepo.CheckBySlug")
+	defer span.End()
 	var exists bool
-	err := r.Conn.QueryRow("EXECUTE check_by_slug($1)", slug).Scan(&exists)
+	err := r.Conn.QueryRow(ctx, "check_by_slug", slug).Scan(&exists)
 	return exists, err
 }
-func (r *Repo) GetForumThreads(slug string, desc bool, limit int, since string) ([]models.Thread, error) {
-	var threadRows *pgx.Rows
-	var err error
+func (r *Repo) GetForumThreads(ctx context.Context, slug string, desc bool, limit int, since string, sort string, period string) ([]models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "forumRepo.GetForumThreads")
+	defer span.End()
+	var query string
+	switch sort {
+	case "created", "":
+		query = "get_threads"
+	case "hot", "top", "active":
+		query = "get_threads_" + sort
+	default:
+		return nil, goErrors.New(errors.UNKNOWN_SORT_TYPE)
+	}
 	if desc {
-		threadRows, err = r.Conn.Query("EXECUTE get_threads_desc($1,NULLIF($2,''),NULLIF($3,'')::timestamptz,$4)", slug, since, since, limit)
-	} else {
-		threadRows, err = r.Conn.Query("EXECUTE get_threads($1,NULLIF($2,''),NULLIF($3,'')::timestamptz,$4)", slug, since, since, limit)
+		query += "_desc"
+	}
+	var threadRows *dbconn.Rows
+	var err error
+	switch sort {
+	case "created", "":
+		var sinceArg interface{}
+		if since != "" {
+			sinceArg = since
+		}
+		threadRows, err = r.Conn.Query(ctx, query, slug, sinceArg, limit)
+	default:
+		var sinceId int
+		if since != "" {
+			if sinceId, err = strconv.Atoi(since); err != nil {
+				return nil, goErrors.New(errors.BAD_THREAD_SINCE)
+			}
+		}
+		args := []interface{}{slug, sinceId, sinceId, limit}
+		if sort == "top" {
+			start, err := forum.PeriodStart(period, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, start)
+		}
+		threadRows, err = r.Conn.Query(ctx, query, args...)
 	}
 
 	defer threadRows.Close()
@@ -61,26 +123,39 @@ func (r *Repo) GetForumThreads(slug string, desc bool, limit int, since string)
 	for threadRows.Next() {
 		thread := models.Thread{}
 		var created time.Time
+		var lastPostAt *time.Time
 		var slug sql.NullString
-		err = threadRows.Scan(&thread.Id, &slug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created)
+		err = threadRows.Scan(&thread.Id, &slug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created,
+			&thread.PostsCount, &lastPostAt, &thread.LastPostAuthor, &thread.ParticipantsCount)
 		if err != nil {
 			return nil, err
 		}
 		thread.Created = strfmt.DateTime(created.UTC()).String()
 		thread.Slug = slug.String
+		if lastPostAt != nil {
+			thread.LastPostAt = strfmt.DateTime(lastPostAt.UTC()).String()
+		}
 		threadsResp = append(threadsResp, thread)
 	}
 	return threadsResp, nil
 }
 
-func (r *Repo) GetForumUsers(slug string, desc bool, limit int, since string) ([]models.User, error) {
-	var userRows *pgx.Rows
-	var err error
+func (r *Repo) GetForumUsers(ctx context.Context, slug string, desc bool, limit int, since string, sort string) ([]models.ForumUser, error) {
+	ctx, span := tracing.Start(ctx, "forumRepo.GetForumUsers")
+	defer span.End()
+	var query string
+	switch sort {
+	case "nickname", "":
+		query = "get_forum_users"
+	case "activity":
+		query = "get_forum_users_activity"
+	default:
+		return nil, goErrors.New(errors.UNKNOWN_SORT_TYPE)
+	}
 	if desc {
-		userRows, err = r.Conn.Query("EXECUTE get_forum_users_desc($1,$2,$3,$4)", slug, since, since, limit)
-	} else {
-		userRows, err = r.Conn.Query("EXECUTE get_forum_users($1,$2,$3,$4)", slug, since, since, limit)
+		query += "_desc"
 	}
+	userRows, err := r.Conn.Query(ctx, query, slug, since, since, limit)
 
 	defer userRows.Close()
 
@@ -88,11 +163,12 @@ func (r *Repo) GetForumUsers(slug string, desc bool, limit int, since string) ([
 		return nil, err
 	}
 
-	userResp := make([]models.User, 0)
+	userResp := make([]models.ForumUser, 0)
 
 	for userRows.Next() {
-		user := models.User{}
-		err = userRows.Scan(&user.Name, &user.Nick, &user.Email, &user.About)
+		user := models.ForumUser{}
+		var firstActivity, lastActivity time.Time
+		err = userRows.Scan(&user.Name, &user.Nick, &user.Email, &user.About, &user.Posts, &user.Threads, &firstActivity, &lastActivity)
 		if err != nil {
 			return nil, err
 		}
@@ -100,6 +176,8 @@ func (r *Repo) GetForumUsers(slug string, desc bool, limit int, since string) ([
 		if err != nil {
 			return nil, err
 		}
+		user.FirstActivity = strfmt.DateTime(firstActivity.UTC()).String()
+		user.LastActivity = strfmt.DateTime(lastActivity.UTC()).String()
 		userResp = append(userResp, user)
 	}
 	return userResp, nil
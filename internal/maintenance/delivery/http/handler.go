@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/maintenance"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	TaskCtxKey = "task"
+)
+
+type Handler struct {
+	Scheduler *maintenance.Scheduler
+}
+
+func NewHandler(scheduler *maintenance.Scheduler) *Handler {
+	return &Handler{Scheduler: scheduler}
+}
+
+func (h *Handler) Status(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, h.Scheduler.Status())
+}
+
+// RunTask only queues the task: it runs in the background and its outcome shows up in Status.
+func (h *Handler) RunTask(ctx echo.Context) error {
+	name := ctx.Param(TaskCtxKey)
+	status, err := h.Scheduler.Trigger(name)
+	switch {
+	case err == maintenance.ErrUnknownTask:
+		return echo.NewHTTPError(http.StatusNotFound, errors.NO_MAINTENANCE_TASK+name)
+	case err == maintenance.ErrTaskBusy:
+		return ctx.JSON(http.StatusConflict, status)
+	}
+	return ctx.JSON(http.StatusAccepted, status)
+}
@@ -0,0 +1,13 @@
+package maintenance
+
+import (
+	"context"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+type Repo interface {
+	TableStats(ctx context.Context) ([]models.TableStat, error)
+	// Run executes the statements of a task one by one, outside of a transaction.
+	Run(ctx context.Context, statements []string) error
+}
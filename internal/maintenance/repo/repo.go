@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
+	"github.com/jackc/pgx/v5"
+)
+
+type Repo struct {
+	Conn *dbconn.Pool
+}
+
+func NewRepo(conn *dbconn.Pool) *Repo {
+	conn.Register("maintenance_table_stats", "SELECT relname, n_live_tup, n_dead_tup FROM pg_stat_user_tables ORDER BY relname")
+	return &Repo{Conn: conn}
+}
+
+func (r *Repo) TableStats(ctx context.Context) ([]models.TableStat, error) {
+	ctx, span := tracing.Start(ctx, "maintenanceRepo.TableStats")
+	defer span.End()
+	tableRows, err := r.Conn.Query(ctx, "maintenance_table_stats")
+	defer tableRows.Close()
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]models.TableStat, 0)
+	for tableRows.Next() {
+		table := models.TableStat{}
+		if err = tableRows.Scan(&table.Name, &table.LiveRows, &table.DeadRows); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, tableRows.Err()
+}
+
+// Run bypasses the statement timeout of the pool: CLUSTER and VACUUM of a big table take minutes,
+// the scheduler bounds the whole run instead. The simple protocol keeps VACUUM out of an implicit transaction.
+func (r *Repo) Run(ctx context.Context, statements []string) error {
+	ctx, span := tracing.Start(ctx, "maintenanceRepo.Run")
+	defer span.End()
+	for _, statement := range statements {
+		stmtCtx, stmtSpan := tracing.Start(ctx, statement)
+		_, err := r.Conn.Pool.Exec(stmtCtx, statement, pgx.QueryExecModeSimpleProtocol)
+		tracing.End(stmtSpan, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/go-openapi/strfmt"
+	"github.com/rs/zerolog"
+)
+
+const (
+	StateIdle    = "idle"
+	StateQueued  = "queued"
+	StateRunning = "running"
+
+	TriggerInterval   = "interval"
+	TriggerRowGrowth  = "row_growth"
+	TriggerDeadTuples = "dead_tuples"
+	TriggerManual     = "manual"
+)
+
+var (
+	ErrUnknownTask = errors.New("unknown maintenance task")
+	ErrTaskBusy    = errors.New("maintenance task is already queued or running")
+)
+
+type task struct {
+	cfg     config.MaintenanceTaskConfig
+	state   string
+	trigger string
+	// baseline is the live row count of the watched tables after the last run, -1 until it is known
+	baseline int64
+	lastRun  time.Time
+	// retryAt holds the triggers back after a failed run
+	retryAt time.Time
+	status  models.MaintenanceTask
+}
+
+// Scheduler runs maintenance tasks in the background, one at a time, when their triggers fire
+// or when they are requested through Trigger. Nothing runs on the request path.
+type Scheduler struct {
+	Repo   Repo
+	cfg    config.MaintenanceConfigStruct
+	logger zerolog.Logger
+
+	mu     sync.Mutex
+	tasks  []*task
+	byName map[string]*task
+	queue  chan *task
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewScheduler(repo Repo, cfg config.MaintenanceConfigStruct, logger zerolog.Logger) *Scheduler {
+	s := &Scheduler{
+		Repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		byName: map[string]*task{},
+		queue:  make(chan *task, len(cfg.Tasks)),
+	}
+	now := time.Now()
+	for _, taskCfg := range cfg.Tasks {
+		t := &task{cfg: taskCfg, state: StateIdle, baseline: -1, lastRun: now}
+		s.tasks = append(s.tasks, t)
+		s.byName[taskCfg.Name] = t
+	}
+	return s
+}
+
+// Start runs the scheduler until Stop is called.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.loop(ctx)
+}
+
+// Stop cancels a running task and waits for the scheduler to exit.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Trigger queues a task to run as soon as the task running now, if any, is done.
+func (s *Scheduler) Trigger(name string) (models.MaintenanceTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.byName[name]
+	if t == nil {
+		return models.MaintenanceTask{}, ErrUnknownTask
+	}
+	if t.state != StateIdle {
+		return s.taskStatus(t), ErrTaskBusy
+	}
+	s.enqueue(t, TriggerManual)
+	return s.taskStatus(t), nil
+}
+
+func (s *Scheduler) Status() []models.MaintenanceTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]models.MaintenanceTask, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		statuses = append(statuses, s.taskStatus(t))
+	}
+	return statuses
+}
+
+func (s *Scheduler) taskStatus(t *task) models.MaintenanceTask {
+	status := t.status
+	status.Name = t.cfg.Name
+	status.State = t.state
+	if s.cfg.Automatic && t.cfg.Interval > 0 {
+		status.NextRun = formatTime(t.lastRun.Add(t.cfg.Interval))
+	}
+	return status
+}
+
+// enqueue must be called with mu held. The queue has room for every task and a task is queued once at most.
+func (s *Scheduler) enqueue(t *task, trigger string) {
+	t.state = StateQueued
+	t.trigger = trigger
+	s.queue <- t
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-s.queue:
+			s.run(ctx, t)
+		case <-ticker.C:
+			if s.cfg.Automatic {
+				s.check(ctx)
+			}
+		}
+	}
+}
+
+// check queues the idle tasks whose triggers fire.
+func (s *Scheduler) check(ctx context.Context) {
+	var tables []models.TableStat
+	if s.needsTableStats() {
+		checkCtx, cancel := context.WithTimeout(ctx, s.cfg.CheckInterval)
+		var err error
+		tables, err = s.Repo.TableStats(checkCtx)
+		cancel()
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("maintenance: read table stats")
+			tables = nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.tasks {
+		if t.state != StateIdle {
+			continue
+		}
+		if tables != nil && t.baseline < 0 {
+			t.baseline = liveRows(tables, t.cfg.Tables)
+		}
+		if trigger := t.due(now, tables); trigger != "" {
+			s.enqueue(t, trigger)
+		}
+	}
+}
+
+func (s *Scheduler) needsTableStats() bool {
+	for _, t := range s.tasks {
+		if t.cfg.RowGrowth > 0 || t.cfg.DeadTupleRatio > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// due returns the trigger that fires for the task, or "". tables is nil if the stats are unavailable.
+func (t *task) due(now time.Time, tables []models.TableStat) string {
+	if now.Before(t.retryAt) {
+		return ""
+	}
+	if t.cfg.Interval > 0 && now.Sub(t.lastRun) >= t.cfg.Interval {
+		return TriggerInterval
+	}
+	if tables == nil {
+		return ""
+	}
+	if t.cfg.RowGrowth > 0 && t.baseline >= 0 && liveRows(tables, t.cfg.Tables)-t.baseline >= t.cfg.RowGrowth {
+		return TriggerRowGrowth
+	}
+	if t.cfg.DeadTupleRatio > 0 {
+		for _, table := range tables {
+			if !watched(table.Name, t.cfg.Tables) {
+				continue
+			}
+			total := table.LiveRows + table.DeadRows
+			if total >= t.cfg.MinRows && total > 0 && float64(table.DeadRows)/float64(total) >= t.cfg.DeadTupleRatio {
+				return TriggerDeadTuples
+			}
+		}
+	}
+	return ""
+}
+
+func (s *Scheduler) run(ctx context.Context, t *task) {
+	s.mu.Lock()
+	t.state = StateRunning
+	trigger := t.trigger
+	s.mu.Unlock()
+
+	logger := s.logger.With().Str("task", t.cfg.Name).Str("trigger", trigger).Logger()
+	logger.Info().Msg("maintenance task started")
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	err := s.Repo.Run(runCtx, t.cfg.Statements)
+	cancel()
+	duration := time.Since(start)
+
+	// the baseline is taken after the run, so that the rows the task itself touched do not count
+	baseline := int64(-1)
+	if err == nil && t.cfg.RowGrowth > 0 {
+		statsCtx, cancel := context.WithTimeout(ctx, s.cfg.CheckInterval)
+		if tables, statsErr := s.Repo.TableStats(statsCtx); statsErr == nil {
+			baseline = liveRows(tables, t.cfg.Tables)
+		}
+		cancel()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.state = StateIdle
+	t.lastRun = time.Now()
+	t.status.Runs++
+	t.status.LastTrigger = trigger
+	t.status.LastStarted = formatTime(start)
+	t.status.LastDuration = duration.Round(time.Millisecond).String()
+	t.status.LastError = ""
+	if err != nil {
+		t.status.Failures++
+		t.status.LastError = err.Error()
+		t.retryAt = t.lastRun.Add(s.cfg.RetryDelay)
+		logger.Error().Err(err).Dur("duration", duration).Msg("maintenance task failed")
+		return
+	}
+	if baseline >= 0 {
+		t.baseline = baseline
+	}
+	logger.Info().Dur("duration", duration).Msg("maintenance task finished")
+}
+
+func liveRows(tables []models.TableStat, names []string) int64 {
+	var rows int64
+	for _, table := range tables {
+		if watched(table.Name, names) {
+			rows += table.LiveRows
+		}
+	}
+	return rows
+}
+
+func watched(table string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if name == table {
+			return true
+		}
+	}
+	return false
+}
+
+func formatTime(t time.Time) string {
+	return strfmt.DateTime(t.UTC()).String()
+}
@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	goErrors "errors"
+	"sort"
+	"strconv"
+	"time"
+
+	forumRepo "github.com/Natali-Skv/technopark_db_forum/internal/forum"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ forumRepo.Repo = (*ForumRepo)(nil)
+
+type ForumRepo struct {
+	Store *Store
+}
+
+func NewForumRepo(store *Store) *ForumRepo {
+	return &ForumRepo{Store: store}
+}
+
+func (r *ForumRepo) Create(ctx context.Context, newForum *models.Forum) (*models.Forum, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	author := s.users[fold(newForum.UserNick)]
+	if author == nil {
+		return nil, pgError(codeUserNotFound)
+	}
+	if s.forums[fold(newForum.Slug)] != nil {
+		return nil, pgError(codeUniqueViolated)
+	}
+	s.lastForumId++
+	f := &forum{id: s.lastForumId, slug: newForum.Slug, title: newForum.Title, authorNick: author.Nick}
+	s.forums[fold(f.slug)] = f
+	s.forumsById[f.id] = f
+	s.stats.Forums++
+	newForum.UserNick = author.Nick
+	return newForum, nil
+}
+
+func (r *ForumRepo) GetBySlug(ctx context.Context, slug string) (*models.Forum, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f := s.forums[fold(slug)]
+	if f == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return f.model(), nil
+}
+
+func (r *ForumRepo) CheckBySlug(ctx context.Context, slug string) (bool, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.forums[fold(slug)] != nil, nil
+}
+
+func (r *ForumRepo) GetForumThreads(ctx context.Context, slug string, desc bool, limit int, since string, sort string, period string) ([]models.Thread, error) {
+	switch sort {
+	case "created", "":
+		return r.getThreadsByCreated(slug, desc, limit, since)
+	case "hot", "top", "active":
+	default:
+		return nil, goErrors.New(errors.UNKNOWN_SORT_TYPE)
+	}
+	sinceId := 0
+	if since != "" {
+		var err error
+		if sinceId, err = strconv.Atoi(since); err != nil {
+			return nil, goErrors.New(errors.BAD_THREAD_SINCE)
+		}
+	}
+	var start *time.Time
+	if sort == "top" {
+		var err error
+		if start, err = forumRepo.PeriodStart(period, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	threadsResp := make([]models.Thread, 0)
+	f := s.forums[fold(slug)]
+	if f == nil {
+		return threadsResp, nil
+	}
+	before := threadRanks[sort]
+	var sinceThread *thread
+	if sinceId != 0 {
+		// postgres compares with the rank of a missing thread, which is null
+		if sinceThread = s.threads[sinceId]; sinceThread == nil {
+			return threadsResp, nil
+		}
+	}
+	threads := make([]*thread, 0, len(s.forumThreads[f.id]))
+	for _, t := range s.forumThreads[f.id] {
+		if start != nil && t.created.Before(*start) {
+			continue
+		}
+		if sinceThread == nil || !desc && before(sinceThread, t) || desc && before(t, sinceThread) {
+			threads = append(threads, t)
+		}
+	}
+	sortThreads(threads, before, desc)
+	if limit > 0 && limit < len(threads) {
+		threads = threads[:limit]
+	}
+	for _, t := range threads {
+		threadsResp = append(threadsResp, *s.threadModel(t))
+	}
+	return threadsResp, nil
+}
+
+// threadRanks order the threads by the ranked sorts, the id breaking ties.
+var threadRanks = map[string]func(a, b *thread) bool{
+	"hot": func(a, b *thread) bool {
+		aHot, bHot := forumRepo.Hotness(a.votes, a.created), forumRepo.Hotness(b.votes, b.created)
+		if aHot != bHot {
+			return aHot < bHot
+		}
+		return a.id < b.id
+	},
+	"top": func(a, b *thread) bool {
+		if a.votes != b.votes {
+			return a.votes < b.votes
+		}
+		return a.id < b.id
+	},
+	"active": func(a, b *thread) bool {
+		if !a.lastPostAt.Equal(b.lastPostAt) {
+			return a.lastPostAt.Before(b.lastPostAt)
+		}
+		return a.id < b.id
+	},
+}
+
+func sortThreads(threads []*thread, before func(a, b *thread) bool, desc bool) {
+	sort.Slice(threads, func(i, j int) bool { return before(threads[i], threads[j]) != desc })
+}
+
+func (r *ForumRepo) getThreadsByCreated(slug string, desc bool, limit int, since string) ([]models.Thread, error) {
+	var sinceTime time.Time
+	var err error
+	if since != "" {
+		if sinceTime, err = parseTime(since); err != nil {
+			return nil, err
+		}
+	}
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	threadsResp := make([]models.Thread, 0)
+	f := s.forums[fold(slug)]
+	if f == nil {
+		return threadsResp, nil
+	}
+	threads := make([]*thread, 0, len(s.forumThreads[f.id]))
+	for _, t := range s.forumThreads[f.id] {
+		if since == "" || !desc && !t.created.Before(sinceTime) || desc && !t.created.After(sinceTime) {
+			threads = append(threads, t)
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		if !threads[i].created.Equal(threads[j].created) {
+			return threads[i].created.Before(threads[j].created) != desc
+		}
+		return threads[i].id < threads[j].id != desc
+	})
+	if limit > 0 && limit < len(threads) {
+		threads = threads[:limit]
+	}
+	for _, t := range threads {
+		threadsResp = append(threadsResp, *s.threadModel(t))
+	}
+	return threadsResp, nil
+}
+
+func (r *ForumRepo) GetForumUsers(ctx context.Context, slug string, desc bool, limit int, since string, sort string) ([]models.ForumUser, error) {
+	var before func(a, b *forumUser) bool
+	switch sort {
+	case "nickname", "":
+		before = nickBefore
+	case "activity":
+		before = activityBefore
+	default:
+		return nil, goErrors.New(errors.UNKNOWN_SORT_TYPE)
+	}
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	usersResp := make([]models.ForumUser, 0)
+	f := s.forums[fold(slug)]
+	if f == nil {
+		return usersResp, nil
+	}
+	forumUsers := s.forumUsers[f.id]
+	var sinceUser *forumUser
+	if since != "" {
+		sinceUser = forumUsers[fold(since)]
+		if sinceUser == nil {
+			// postgres compares with the activity of a missing user, which is null
+			if sort == "activity" {
+				return usersResp, nil
+			}
+			sinceUser = &forumUser{user: &user{User: models.User{Nick: since}}}
+		}
+	}
+	users := make([]*forumUser, 0, len(forumUsers))
+	for _, u := range forumUsers {
+		if sinceUser == nil || !desc && before(sinceUser, u) || desc && before(u, sinceUser) {
+			users = append(users, u)
+		}
+	}
+	sortForumUsers(users, before, desc)
+	if limit > 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	for _, u := range users {
+		usersResp = append(usersResp, u.model())
+	}
+	return usersResp, nil
+}
+
+func nickBefore(a, b *forumUser) bool {
+	return fold(a.Nick) < fold(b.Nick)
+}
+
+func activityBefore(a, b *forumUser) bool {
+	if !a.lastActivity.Equal(b.lastActivity) {
+		return a.lastActivity.Before(b.lastActivity)
+	}
+	return nickBefore(a, b)
+}
+
+func sortForumUsers(users []*forumUser, before func(a, b *forumUser) bool, desc bool) {
+	sort.Slice(users, func(i, j int) bool { return before(users[i], users[j]) != desc })
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+
+	maintenanceRepo "github.com/Natali-Skv/technopark_db_forum/internal/maintenance"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+var _ maintenanceRepo.Repo = (*MaintenanceRepo)(nil)
+
+// MaintenanceRepo has nothing to maintain: the store never leaves dead rows behind and tasks are no-ops.
+type MaintenanceRepo struct {
+	Store *Store
+}
+
+func NewMaintenanceRepo(store *Store) *MaintenanceRepo {
+	return &MaintenanceRepo{Store: store}
+}
+
+func (r *MaintenanceRepo) TableStats(ctx context.Context) ([]models.TableStat, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tableStats(), nil
+}
+
+func (r *MaintenanceRepo) Run(ctx context.Context, statements []string) error {
+	return ctx.Err()
+}
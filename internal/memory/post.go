@@ -0,0 +1,505 @@
+package memory
+
+import (
+	"context"
+	goErrors "errors"
+	"sort"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	postRepo "github.com/Natali-Skv/technopark_db_forum/internal/post"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ postRepo.Repo = (*PostRepo)(nil)
+
+const (
+	userRelated   = "user"
+	threadRelated = "thread"
+	forumRelated  = "forum"
+	// reactionsRelated counts the reactions to the posts instead of joining another object.
+	reactionsRelated = "reactions"
+)
+
+type PostRepo struct {
+	Store *Store
+}
+
+func NewPostRepo(store *Store) *PostRepo {
+	return &PostRepo{Store: store}
+}
+
+// Create inserts the posts as one statement: they all get the same created time, a post may answer
+// an earlier post of the same batch, and if any post is rejected none of them is stored.
+func (r *PostRepo) Create(ctx context.Context, threadSlug string, threadId int, posts []models.Post) ([]models.Post, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threadBySlugOrId(threadSlug, threadId)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if len(posts) == 0 {
+		return []models.Post{}, nil
+	}
+	f := s.forumsById[t.forumId]
+	created := time.Now()
+
+	batch := make(map[int]*post, len(posts))
+	newPosts := make([]*post, 0, len(posts))
+	authors := make([]*user, 0, len(posts))
+	for i := range posts {
+		author := s.users[fold(posts[i].AuthorNick)]
+		if author == nil {
+			return nil, pgError(codeUserNotFound)
+		}
+		p := &post{
+			id:         s.lastPostId + i + 1,
+			parentId:   posts[i].ParentId,
+			authorNick: author.Nick,
+			message:    posts[i].Message,
+			forumId:    f.id,
+			threadId:   t.id,
+			created:    created,
+		}
+		if p.parentId != 0 {
+			parent := s.posts[p.parentId]
+			if parent == nil {
+				parent = batch[p.parentId]
+			}
+			if parent == nil || parent.threadId != t.id {
+				return nil, pgError(codeBadParent)
+			}
+			p.path = append(append(make([]int, 0, len(parent.path)+1), parent.path...), p.id)
+		} else {
+			p.path = []int{p.id}
+		}
+		batch[p.id] = p
+		newPosts = append(newPosts, p)
+		authors = append(authors, author)
+	}
+
+	s.lastPostId += len(newPosts)
+	for i, p := range newPosts {
+		s.posts[p.id] = p
+		s.threadPosts[t.id] = append(s.threadPosts[t.id], p)
+		for _, ancestorId := range p.path[:len(p.path)-1] {
+			s.posts[ancestorId].descendants++
+		}
+		if parent := s.posts[p.parentId]; parent != nil {
+			parent.children++
+			p.branchRank = parent.branchRank
+			if parent.children > p.branchRank {
+				p.branchRank = parent.children
+			}
+		}
+		t.participants[fold(p.authorNick)] = true
+		t.lastPostAuthor = p.authorNick
+		s.addForumUser(f, authors[i], 1, 0, created)
+		posts[i].Id = p.id
+		posts[i].AuthorNick = p.authorNick
+		posts[i].ForumSlug = f.slug
+		posts[i].ThreadId = t.id
+		posts[i].Created = formatTime(created)
+		posts[i].Depth = len(p.path) - 1
+	}
+	f.posts += len(newPosts)
+	if created.After(t.lastPostAt) {
+		t.lastPostAt = created
+	}
+	s.stats.Posts += len(newPosts)
+	return posts, nil
+}
+
+func (r *PostRepo) GetThreadPosts(ctx context.Context, threadSlug string, threadId int, desc bool, limit int, since int, sort string, maxDepth int, childLimit int, related []string) ([]models.Post, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*post
+	switch sort {
+	case "flat", "":
+		posts = s.threadPostsFlat(threadSlug, threadId, desc, limit, since, maxDepth, childLimit)
+	case "tree":
+		posts = s.threadPostsTree(threadSlug, threadId, desc, limit, since, maxDepth, childLimit)
+	case "parent_tree":
+		posts = s.threadPostsParentTree(threadSlug, threadId, desc, limit, since, maxDepth, childLimit)
+	default:
+		return nil, goErrors.New(errors.UNKNOWN_SORT_TYPE)
+	}
+	reactions := false
+	for _, relatedItem := range related {
+		reactions = reactions || relatedItem == reactionsRelated
+	}
+	postsResp := make([]models.Post, 0, len(posts))
+	for _, p := range posts {
+		post := s.postModel(p)
+		post.MoreReplies = postRepo.MoreReplies(&post, maxDepth, childLimit)
+		if reactions {
+			post.Reactions = s.reactionsOf(reactionTarget{postId: p.id})
+		}
+		postsResp = append(postsResp, post)
+	}
+	return postsResp, nil
+}
+
+// postsOfThread returns a copy of the thread posts, which are kept in id order, without those deeper
+// than maxDepth levels or past childLimit replies to any post on their path.
+func (s *Store) postsOfThread(threadSlug string, threadId int, maxDepth int, childLimit int) []*post {
+	t := s.threadBySlugOrId(threadSlug, threadId)
+	if t == nil {
+		return nil
+	}
+	posts := make([]*post, 0, len(s.threadPosts[t.id]))
+	for _, p := range s.threadPosts[t.id] {
+		if (maxDepth == 0 || len(p.path) <= maxDepth) && (childLimit == 0 || p.branchRank <= childLimit) {
+			posts = append(posts, p)
+		}
+	}
+	return posts
+}
+
+func (s *Store) threadPostsFlat(threadSlug string, threadId int, desc bool, limit int, since int, maxDepth int, childLimit int) []*post {
+	posts := s.postsOfThread(threadSlug, threadId, maxDepth, childLimit)
+	filtered := posts[:0]
+	for _, p := range posts {
+		if since == 0 || !desc && p.id > since || desc && p.id < since {
+			filtered = append(filtered, p)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if !filtered[i].created.Equal(filtered[j].created) {
+			return filtered[i].created.Before(filtered[j].created) != desc
+		}
+		return filtered[i].id < filtered[j].id != desc
+	})
+	return limitPosts(filtered, limit)
+}
+
+func (s *Store) threadPostsTree(threadSlug string, threadId int, desc bool, limit int, since int, maxDepth int, childLimit int) []*post {
+	posts := s.postsOfThread(threadSlug, threadId, maxDepth, childLimit)
+	if since != 0 {
+		sincePost := s.posts[since]
+		if sincePost == nil {
+			return nil
+		}
+		filtered := posts[:0]
+		for _, p := range posts {
+			if cmp := comparePaths(p.path, sincePost.path); !desc && cmp > 0 || desc && cmp < 0 {
+				filtered = append(filtered, p)
+			}
+		}
+		posts = filtered
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return comparePaths(posts[i].path, posts[j].path) < 0 != desc
+	})
+	return limitPosts(posts, limit)
+}
+
+// threadPostsParentTree pages by root posts: limit counts roots and since skips up to the root of the since post.
+// Roots follow desc, the posts under a root are always in path order.
+func (s *Store) threadPostsParentTree(threadSlug string, threadId int, desc bool, limit int, since int, maxDepth int, childLimit int) []*post {
+	posts := s.postsOfThread(threadSlug, threadId, maxDepth, childLimit)
+	if since != 0 {
+		sincePost := s.posts[since]
+		if sincePost == nil {
+			return nil
+		}
+		sinceRoot := sincePost.path[0]
+		filtered := posts[:0]
+		for _, p := range posts {
+			if !desc && p.path[0] > sinceRoot || desc && p.path[0] < sinceRoot {
+				filtered = append(filtered, p)
+			}
+		}
+		posts = filtered
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].path[0] != posts[j].path[0] {
+			return posts[i].path[0] < posts[j].path[0] != desc
+		}
+		return comparePaths(posts[i].path, posts[j].path) < 0
+	})
+	if limit <= 0 {
+		return posts
+	}
+	roots := 0
+	for i, p := range posts {
+		if len(p.path) == 1 {
+			if roots == limit {
+				return posts[:i]
+			}
+			roots++
+		}
+	}
+	return posts
+}
+
+func limitPosts(posts []*post, limit int) []*post {
+	if limit > 0 && limit < len(posts) {
+		return posts[:limit]
+	}
+	return posts
+}
+
+// comparePaths orders materialized paths like postgres compares bigint[].
+func comparePaths(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+func (r *PostRepo) CheckThreadBySlugOrId(ctx context.Context, slug string, id int) (bool, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.threads[id] != nil || s.threadsBySlug[fold(slug)] != nil, nil
+}
+
+func (r *PostRepo) GetPostByIdRelated(ctx context.Context, id int, related []string) (*models.Post, *models.User, *models.Forum, *models.Thread, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := s.posts[id]
+	if p == nil {
+		return nil, nil, nil, nil, pgx.ErrNoRows
+	}
+	post := s.postModel(p)
+	var user *models.User
+	var forum *models.Forum
+	var thread *models.Thread
+	for _, relatedItem := range related {
+		switch relatedItem {
+		case userRelated:
+			author := s.users[fold(p.authorNick)].User
+			user = &author
+		case threadRelated:
+			thread = s.threadModel(s.threads[p.threadId])
+		case forumRelated:
+			forum = s.forumsById[p.forumId].model()
+		case reactionsRelated:
+			post.Reactions = s.reactionsOf(reactionTarget{postId: p.id})
+		}
+	}
+	return &post, user, forum, thread, nil
+}
+
+// UpdatePost marks the post edited only if the message really changes, like update_posts_tg.
+func (r *PostRepo) UpdatePost(ctx context.Context, update *models.Post) (*models.Post, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.posts[update.Id]
+	if p == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if update.Message != "" && update.Message != p.message {
+		p.message = update.Message
+		if !p.isEdited {
+			p.isEdited = true
+			s.forumsById[p.forumId].editedPosts++
+			s.stats.EditedPosts++
+		}
+	}
+	*update = s.postModel(p)
+	return update, nil
+}
+
+func (r *PostRepo) Vote(ctx context.Context, vote *models.PostVote) (*models.Post, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	voter := s.users[fold(vote.Nick)]
+	if voter == nil {
+		return nil, pgError(codeUserNotFound)
+	}
+	p := s.posts[vote.PostId]
+	if p == nil {
+		return nil, pgError(codeForeignKey)
+	}
+	key := postVoteKey{nick: fold(voter.Nick), postId: p.id}
+	p.votes += vote.Voice - s.postVotes[key]
+	s.postVotes[key] = vote.Voice
+	post := s.postModel(p)
+	return &post, nil
+}
+
+func (r *PostRepo) Unvote(ctx context.Context, vote *models.PostVote) (*models.Post, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.posts[vote.PostId]
+	if p == nil {
+		return nil, pgx.ErrNoRows
+	}
+	key := postVoteKey{nick: fold(vote.Nick), postId: p.id}
+	if _, ok := s.postVotes[key]; ok {
+		s.retractPostVote(key)
+	}
+	post := s.postModel(p)
+	return &post, nil
+}
+
+func (r *PostRepo) GetVotes(ctx context.Context, id int, desc bool, limit int, since string) ([]models.PostVote, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.posts[id] == nil {
+		return nil, pgx.ErrNoRows
+	}
+	voters := make([]string, 0)
+	for key := range s.postVotes {
+		if key.postId == id {
+			voters = append(voters, key.nick)
+		}
+	}
+	votes := make([]models.PostVote, 0)
+	for _, voter := range s.pageVoters(voters, desc, limit, since) {
+		votes = append(votes, models.PostVote{Nick: s.users[voter].Nick, Voice: s.postVotes[postVoteKey{nick: voter, postId: id}], PostId: id})
+	}
+	return votes, nil
+}
+
+func (r *PostRepo) React(ctx context.Context, reaction *models.Reaction) (*models.Post, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reactor := s.users[fold(reaction.Nick)]
+	if reactor == nil {
+		return nil, pgError(codeUserNotFound)
+	}
+	p := s.posts[reaction.PostId]
+	if p == nil {
+		return nil, pgError(codeForeignKey)
+	}
+	target := reactionTarget{postId: p.id}
+	s.addReaction(reactionKey{reactionTarget: target, nick: fold(reactor.Nick), reaction: reaction.Reaction})
+	post := s.postModel(p)
+	post.Reactions = s.reactionsOf(target)
+	return &post, nil
+}
+
+func (r *PostRepo) Unreact(ctx context.Context, reaction *models.Reaction) (*models.Post, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.posts[reaction.PostId]
+	if p == nil {
+		return nil, pgx.ErrNoRows
+	}
+	target := reactionTarget{postId: p.id}
+	s.removeReaction(reactionKey{reactionTarget: target, nick: fold(reaction.Nick), reaction: reaction.Reaction})
+	post := s.postModel(p)
+	post.Reactions = s.reactionsOf(target)
+	return &post, nil
+}
+
+func (r *PostRepo) GetSubtree(ctx context.Context, id int, depth int, limit int, since int) ([]models.Post, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	root := s.posts[id]
+	if root == nil {
+		return nil, pgx.ErrNoRows
+	}
+	var sincePath []int
+	if since != 0 {
+		sincePost := s.posts[since]
+		if sincePost == nil {
+			return []models.Post{}, nil
+		}
+		sincePath = sincePost.path
+	}
+	subtree := make([]*post, 0)
+	for _, p := range s.threadPosts[root.threadId] {
+		if len(p.path) <= len(root.path) || comparePaths(p.path[:len(root.path)], root.path) != 0 {
+			continue
+		}
+		if depth != 0 && len(p.path) > len(root.path)+depth || sincePath != nil && comparePaths(p.path, sincePath) <= 0 {
+			continue
+		}
+		subtree = append(subtree, p)
+	}
+	sort.Slice(subtree, func(i, j int) bool {
+		return comparePaths(subtree[i].path, subtree[j].path) < 0
+	})
+	return s.postModels(limitPosts(subtree, limit)), nil
+}
+
+func (r *PostRepo) GetAncestors(ctx context.Context, id int) ([]models.Post, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := s.posts[id]
+	if p == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return s.ancestorsOf(p), nil
+}
+
+func (r *PostRepo) GetContext(ctx context.Context, id int, siblings int) (*models.PostContext, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := s.posts[id]
+	if p == nil {
+		return nil, pgx.ErrNoRows
+	}
+	before, after := make([]*post, 0), make([]*post, 0)
+	for _, sibling := range s.threadPosts[p.threadId] {
+		if sibling.parentId != p.parentId || sibling.id == p.id {
+			continue
+		}
+		if comparePaths(sibling.path, p.path) < 0 {
+			before = append(before, sibling)
+		} else {
+			after = append(after, sibling)
+		}
+	}
+	sort.Slice(before, func(i, j int) bool {
+		return comparePaths(before[i].path, before[j].path) > 0
+	})
+	sort.Slice(after, func(i, j int) bool {
+		return comparePaths(after[i].path, after[j].path) < 0
+	})
+	// before is sorted nearest first to keep the closest siblings, then put back in tree order
+	if len(before) > siblings {
+		before = before[:siblings]
+	}
+	if len(after) > siblings {
+		after = after[:siblings]
+	}
+	for i, j := 0, len(before)-1; i < j; i, j = i+1, j-1 {
+		before[i], before[j] = before[j], before[i]
+	}
+	return &models.PostContext{
+		Ancestors: s.ancestorsOf(p),
+		Before:    s.postModels(before),
+		Post:      s.postModel(p),
+		After:     s.postModels(after),
+	}, nil
+}
+
+// ancestorsOf returns the posts on the path of p above it, root first.
+func (s *Store) ancestorsOf(p *post) []models.Post {
+	ancestors := make([]models.Post, 0, len(p.path)-1)
+	for _, ancestorId := range p.path[:len(p.path)-1] {
+		ancestors = append(ancestors, s.postModel(s.posts[ancestorId]))
+	}
+	return ancestors
+}
+
+func (s *Store) postModels(posts []*post) []models.Post {
+	postsResp := make([]models.Post, 0, len(posts))
+	for _, p := range posts {
+		postsResp = append(postsResp, s.postModel(p))
+	}
+	return postsResp
+}
@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	serviceRepo "github.com/Natali-Skv/technopark_db_forum/internal/service"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ serviceRepo.Repo = (*ServiceRepo)(nil)
+
+type ServiceRepo struct {
+	Store *Store
+}
+
+func NewServiceRepo(store *Store) *ServiceRepo {
+	return &ServiceRepo{Store: store}
+}
+
+func (r *ServiceRepo) Status(ctx context.Context) (*models.Status, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status := s.stats
+	return &status, nil
+}
+
+func (r *ServiceRepo) ForumsStatus(ctx context.Context, limit int) ([]models.ForumStatus, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	forumsResp := make([]models.ForumStatus, 0, len(s.forumsById))
+	for _, f := range s.forumsById {
+		forumsResp = append(forumsResp, models.ForumStatus{
+			Slug:         f.slug,
+			Threads:      f.threads,
+			Posts:        f.posts,
+			Votes:        f.votes,
+			Users:        f.users,
+			EditedPosts:  f.editedPosts,
+			DeletedPosts: f.deletedPosts,
+		})
+	}
+	sort.Slice(forumsResp, func(i, j int) bool {
+		if forumsResp[i].Posts != forumsResp[j].Posts {
+			return forumsResp[i].Posts > forumsResp[j].Posts
+		}
+		return forumsResp[i].Slug < forumsResp[j].Slug
+	})
+	if limit >= 0 && limit < len(forumsResp) {
+		forumsResp = forumsResp[:limit]
+	}
+	return forumsResp, nil
+}
+
+// TruncateDB drops all data and counters but keeps the audit log and the id sequences, like TRUNCATE does.
+func (r *ServiceRepo) TruncateDB(ctx context.Context) error {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset()
+	return nil
+}
+
+func (r *ServiceRepo) ClearForum(ctx context.Context, slug string) error {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.forums[fold(slug)]
+	if f == nil {
+		return pgx.ErrNoRows
+	}
+	for _, t := range s.forumThreads[f.id] {
+		for key := range s.votes {
+			if key.threadId == t.id {
+				delete(s.votes, key)
+				s.stats.Votes--
+			}
+		}
+		for key := range s.postVotes {
+			if s.posts[key.postId].threadId == t.id {
+				delete(s.postVotes, key)
+			}
+		}
+		for key := range s.reactions {
+			if key.threadId == t.id || key.postId != 0 && s.posts[key.postId].threadId == t.id {
+				s.removeReaction(key)
+			}
+		}
+		for _, p := range s.threadPosts[t.id] {
+			delete(s.posts, p.id)
+			s.stats.Posts--
+			s.stats.DeletedPosts++
+			if p.isEdited {
+				s.stats.EditedPosts--
+			}
+		}
+		delete(s.threadPosts, t.id)
+		delete(s.threads, t.id)
+		if t.slug != "" {
+			delete(s.threadsBySlug, fold(t.slug))
+		}
+		s.stats.Threads--
+	}
+	s.stats.ForumUsers -= len(s.forumUsers[f.id])
+	s.stats.Forums--
+	delete(s.forumUsers, f.id)
+	delete(s.forumThreads, f.id)
+	delete(s.forumsById, f.id)
+	delete(s.forums, fold(f.slug))
+	return nil
+}
+
+func (r *ServiceRepo) ClearVotes(ctx context.Context) error {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.votes = map[voteKey]int{}
+	s.postVotes = map[postVoteKey]int{}
+	for _, t := range s.threads {
+		t.votes = 0
+	}
+	for _, p := range s.posts {
+		p.votes = 0
+	}
+	for _, f := range s.forumsById {
+		f.votes = 0
+	}
+	s.stats.Votes = 0
+	return nil
+}
+
+func (r *ServiceRepo) Audit(ctx context.Context, event *models.AuditEvent) error {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = append(s.audit, *event)
+	return nil
+}
+
+func (r *ServiceRepo) Ping(ctx context.Context) error {
+	return nil
+}
+
+// SchemaVersion always matches: there is no schema to migrate.
+func (r *ServiceRepo) SchemaVersion(ctx context.Context) (int, error) {
+	return config.DbConfig.SchemaVersion, nil
+}
+
+func (r *ServiceRepo) CheckStatements(ctx context.Context) error {
+	return nil
+}
+
+func (r *ServiceRepo) Diagnostics(ctx context.Context) (*models.Diagnostics, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &models.Diagnostics{
+		SchemaVersion:   config.DbConfig.SchemaVersion,
+		Statements:      []string{},
+		StatementErrors: map[string]string{},
+		Tables:          s.tableStats(),
+	}, nil
+}
+
+// tableStats counts rows the way pg_stat_user_tables would for the same data. Must be called with mu held.
+func (s *Store) tableStats() []models.TableStat {
+	forumUsers := 0
+	for _, users := range s.forumUsers {
+		forumUsers += len(users)
+	}
+	postReactions := 0
+	for key := range s.reactions {
+		if key.postId != 0 {
+			postReactions++
+		}
+	}
+	return []models.TableStat{
+		{Name: "forum_users", LiveRows: int64(forumUsers)},
+		{Name: "forums", LiveRows: int64(len(s.forumsById))},
+		{Name: "post_reactions", LiveRows: int64(postReactions)},
+		{Name: "post_votes", LiveRows: int64(len(s.postVotes))},
+		{Name: "posts", LiveRows: int64(len(s.posts))},
+		{Name: "thread_reactions", LiveRows: int64(len(s.reactions) - postReactions)},
+		{Name: "threads", LiveRows: int64(len(s.threads))},
+		{Name: "users", LiveRows: int64(len(s.users))},
+		{Name: "votes", LiveRows: int64(len(s.votes))},
+	}
+}
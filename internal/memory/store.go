@@ -0,0 +1,345 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	userRepo "github.com/Natali-Skv/technopark_db_forum/internal/user"
+	"github.com/go-openapi/strfmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes the handlers tell apart, raised the same way db/db.sql raises them.
+const (
+	codeBadParent      = "AAAA0"
+	codeUserNotFound   = "AAAA1"
+	codeForumNotFound  = "AAAA3"
+	codeUniqueViolated = "23505"
+	codeNotNull        = "23502"
+	codeForeignKey     = "23503"
+	codeBadDatetime    = "22007"
+)
+
+type user struct {
+	id int
+	models.User
+}
+
+type forum struct {
+	id           int
+	slug         string
+	title        string
+	authorNick   string
+	threads      int
+	posts        int
+	votes        int
+	users        int
+	editedPosts  int
+	deletedPosts int
+}
+
+type thread struct {
+	id         int
+	slug       string
+	title      string
+	authorNick string
+	forumId    int
+	message    string
+	votes      int
+	created    time.Time
+	lastPostAt time.Time
+	// lastPostAuthor and participants, the folded nicknames of the post authors, are kept by PostRepo.Create
+	lastPostAuthor string
+	participants   map[string]bool
+}
+
+type post struct {
+	id         int
+	parentId   int
+	authorNick string
+	message    string
+	isEdited   bool
+	forumId    int
+	threadId   int
+	created    time.Time
+	path       []int
+	votes      int
+	// children and descendants are counted up the path of each new post
+	children    int
+	descendants int
+	// branchRank is the highest position among its siblings of a reply on the path down to the post
+	branchRank int
+}
+
+// forumUser is a row of forum_users; the profile is the user itself, so it follows every update.
+type forumUser struct {
+	*user
+	posts         int
+	threads       int
+	firstActivity time.Time
+	lastActivity  time.Time
+}
+
+func (fu *forumUser) model() models.ForumUser {
+	return models.ForumUser{
+		User:          fu.User,
+		Posts:         fu.posts,
+		Threads:       fu.threads,
+		FirstActivity: formatTime(fu.firstActivity),
+		LastActivity:  formatTime(fu.lastActivity),
+	}
+}
+
+type voteKey struct {
+	nick     string
+	threadId int
+}
+
+type postVoteKey struct {
+	nick   string
+	postId int
+}
+
+// reactionTarget is the post or the thread a reaction is given to; the other id is 0.
+type reactionTarget struct {
+	postId   int
+	threadId int
+}
+
+type reactionKey struct {
+	reactionTarget
+	nick     string
+	reaction string
+}
+
+// Store keeps all tables in memory and does by hand what the triggers of db/db.sql do:
+// it fills forum_users, keeps the forum and global counters, sums up votes and builds post paths.
+// Nicks, emails and slugs are citext in postgres, so every index is keyed by the lowercased value.
+type Store struct {
+	mu sync.RWMutex
+
+	users         map[string]*user
+	usersByEmail  map[string]*user
+	forums        map[string]*forum
+	forumsById    map[int]*forum
+	threads       map[int]*thread
+	threadsBySlug map[string]*thread
+	forumThreads  map[int][]*thread
+	posts         map[int]*post
+	threadPosts   map[int][]*post
+	votes         map[voteKey]int
+	postVotes     map[postVoteKey]int
+	reactions     map[reactionKey]bool
+	// reactionCounts is what the SQL repos aggregate from post_reactions and thread_reactions on read.
+	reactionCounts map[reactionTarget]map[string]int
+	forumUsers     map[int]map[string]*forumUser
+	renames        map[string]*user
+	stats          models.Status
+	audit          []models.AuditEvent
+
+	lastUserId   int
+	lastForumId  int
+	lastThreadId int
+	lastPostId   int
+}
+
+func NewStore() *Store {
+	s := &Store{}
+	s.reset()
+	return s
+}
+
+func (s *Store) reset() {
+	s.users = map[string]*user{}
+	s.usersByEmail = map[string]*user{}
+	s.forums = map[string]*forum{}
+	s.forumsById = map[int]*forum{}
+	s.threads = map[int]*thread{}
+	s.threadsBySlug = map[string]*thread{}
+	s.forumThreads = map[int][]*thread{}
+	s.posts = map[int]*post{}
+	s.threadPosts = map[int][]*post{}
+	s.votes = map[voteKey]int{}
+	s.postVotes = map[postVoteKey]int{}
+	s.reactions = map[reactionKey]bool{}
+	s.reactionCounts = map[reactionTarget]map[string]int{}
+	s.forumUsers = map[int]map[string]*forumUser{}
+	s.renames = map[string]*user{}
+	s.stats = models.Status{}
+}
+
+// addForumUser is the insert ... ON CONFLICT DO UPDATE into forum_users of one post or thread
+// created at the given time, together with its counters.
+func (s *Store) addForumUser(f *forum, u *user, posts int, threads int, created time.Time) {
+	forumUsers := s.forumUsers[f.id]
+	if forumUsers == nil {
+		forumUsers = map[string]*forumUser{}
+		s.forumUsers[f.id] = forumUsers
+	}
+	key := fold(u.Nick)
+	fu := forumUsers[key]
+	if fu == nil {
+		fu = &forumUser{user: u, firstActivity: created, lastActivity: created}
+		forumUsers[key] = fu
+		f.users++
+		s.stats.ForumUsers++
+	}
+	fu.posts += posts
+	fu.threads += threads
+	if created.Before(fu.firstActivity) {
+		fu.firstActivity = created
+	}
+	if created.After(fu.lastActivity) {
+		fu.lastActivity = created
+	}
+}
+
+// retractVote is the delete of a row of votes together with its triggers.
+func (s *Store) retractVote(key voteKey) {
+	t := s.threads[key.threadId]
+	t.votes -= s.votes[key]
+	s.forumsById[t.forumId].votes--
+	s.stats.Votes--
+	delete(s.votes, key)
+}
+
+func (s *Store) retractPostVote(key postVoteKey) {
+	s.posts[key.postId].votes -= s.postVotes[key]
+	delete(s.postVotes, key)
+}
+
+// addReaction is the insert ... ON CONFLICT DO NOTHING of a reaction.
+func (s *Store) addReaction(key reactionKey) {
+	if s.reactions[key] {
+		return
+	}
+	s.reactions[key] = true
+	counts := s.reactionCounts[key.reactionTarget]
+	if counts == nil {
+		counts = map[string]int{}
+		s.reactionCounts[key.reactionTarget] = counts
+	}
+	counts[key.reaction]++
+}
+
+func (s *Store) removeReaction(key reactionKey) {
+	if !s.reactions[key] {
+		return
+	}
+	delete(s.reactions, key)
+	counts := s.reactionCounts[key.reactionTarget]
+	if counts[key.reaction]--; counts[key.reaction] == 0 {
+		delete(counts, key.reaction)
+	}
+	if len(counts) == 0 {
+		delete(s.reactionCounts, key.reactionTarget)
+	}
+}
+
+// reactionsOf copies the reaction counts of a post or a thread, so that responses don't share the store maps.
+func (s *Store) reactionsOf(target reactionTarget) map[string]int {
+	reactions := make(map[string]int, len(s.reactionCounts[target]))
+	for reaction, count := range s.reactionCounts[target] {
+		reactions[reaction] = count
+	}
+	return reactions
+}
+
+// pageVoters sorts the lowercased nicknames of voters and pages them the way the vote lists of the SQL repos do.
+func (s *Store) pageVoters(voters []string, desc bool, limit int, since string) []string {
+	paged := make([]string, 0, len(voters))
+	for _, voter := range voters {
+		if since == "" || !desc && voter > fold(since) || desc && voter < fold(since) {
+			paged = append(paged, voter)
+		}
+	}
+	sort.Slice(paged, func(i, j int) bool { return paged[i] < paged[j] != desc })
+	if limit > 0 && limit < len(paged) {
+		paged = paged[:limit]
+	}
+	return paged
+}
+
+// ghost returns the account of deleted users, creating it the first time like the SQL repo does.
+func (s *Store) ghost() *user {
+	if ghost := s.users[fold(userRepo.Ghost.Nick)]; ghost != nil {
+		return ghost
+	}
+	s.lastUserId++
+	ghost := &user{id: s.lastUserId, User: userRepo.Ghost}
+	s.users[fold(ghost.Nick)] = ghost
+	s.usersByEmail[fold(ghost.Email)] = ghost
+	s.stats.Users++
+	return ghost
+}
+
+func (s *Store) threadBySlugOrId(slug string, id int) *thread {
+	if id != 0 {
+		return s.threads[id]
+	}
+	return s.threadsBySlug[fold(slug)]
+}
+
+func (f *forum) model() *models.Forum {
+	return &models.Forum{Slug: f.slug, Title: f.title, UserNick: f.authorNick, Posts: f.posts, Threads: f.threads}
+}
+
+func (s *Store) threadModel(t *thread) *models.Thread {
+	thread := &models.Thread{
+		Id:                t.id,
+		Slug:              t.slug,
+		Title:             t.title,
+		AuthorNick:        t.authorNick,
+		ForumSlug:         s.forumsById[t.forumId].slug,
+		Message:           t.message,
+		Votes:             t.votes,
+		Created:           formatTime(t.created),
+		PostsCount:        len(s.threadPosts[t.id]),
+		LastPostAuthor:    t.lastPostAuthor,
+		ParticipantsCount: len(t.participants),
+	}
+	if thread.PostsCount > 0 {
+		thread.LastPostAt = formatTime(t.lastPostAt)
+	}
+	return thread
+}
+
+func (s *Store) postModel(p *post) models.Post {
+	return models.Post{
+		Id:          p.id,
+		AuthorNick:  p.authorNick,
+		ParentId:    p.parentId,
+		Message:     p.message,
+		IsEdited:    p.isEdited,
+		ForumSlug:   s.forumsById[p.forumId].slug,
+		ThreadId:    p.threadId,
+		Created:     formatTime(p.created),
+		Votes:       p.votes,
+		Children:    p.children,
+		Descendants: p.descendants,
+		Depth:       len(p.path) - 1,
+	}
+}
+
+func fold(s string) string {
+	return strings.ToLower(s)
+}
+
+func formatTime(t time.Time) string {
+	return strfmt.DateTime(t.UTC()).String()
+}
+
+func parseTime(s string) (time.Time, error) {
+	created, err := strfmt.ParseDateTime(s)
+	if err != nil {
+		return time.Time{}, pgError(codeBadDatetime)
+	}
+	return time.Time(created), nil
+}
+
+func pgError(code string) error {
+	return &pgconn.PgError{Severity: "ERROR", Code: code}
+}
@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	threadRepo "github.com/Natali-Skv/technopark_db_forum/internal/thread"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ threadRepo.Repo = (*ThreadRepo)(nil)
+
+type ThreadRepo struct {
+	Store *Store
+}
+
+func NewThreadRepo(store *Store) *ThreadRepo {
+	return &ThreadRepo{Store: store}
+}
+
+func (r *ThreadRepo) Create(ctx context.Context, newThread *models.Thread) (*models.Thread, error) {
+	created := time.Now()
+	if newThread.Created != "" {
+		var err error
+		if created, err = parseTime(newThread.Created); err != nil {
+			return nil, err
+		}
+	}
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	author := s.users[fold(newThread.AuthorNick)]
+	if author == nil {
+		return nil, pgError(codeUserNotFound)
+	}
+	f := s.forums[fold(newThread.ForumSlug)]
+	if f == nil {
+		return nil, pgError(codeForumNotFound)
+	}
+	if newThread.Slug != "" && s.threadsBySlug[fold(newThread.Slug)] != nil {
+		return nil, pgError(codeUniqueViolated)
+	}
+
+	s.lastThreadId++
+	t := &thread{
+		id:           s.lastThreadId,
+		slug:         newThread.Slug,
+		title:        newThread.Title,
+		authorNick:   author.Nick,
+		forumId:      f.id,
+		message:      newThread.Message,
+		created:      created,
+		lastPostAt:   created,
+		participants: map[string]bool{},
+	}
+	s.threads[t.id] = t
+	if t.slug != "" {
+		s.threadsBySlug[fold(t.slug)] = t
+	}
+	s.forumThreads[f.id] = append(s.forumThreads[f.id], t)
+	f.threads++
+	s.stats.Threads++
+	s.addForumUser(f, author, 0, 1, created)
+
+	newThread.AuthorNick = t.authorNick
+	newThread.Id = t.id
+	newThread.ForumSlug = f.slug
+	return newThread, nil
+}
+
+func (r *ThreadRepo) GetBySlugOrId(ctx context.Context, slug string, id int) (*models.Thread, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t := s.threadBySlugOrId(slug, id)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return s.threadModel(t), nil
+}
+
+func (r *ThreadRepo) UpdateThread(ctx context.Context, update *models.Thread) (*models.Thread, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threads[update.Id]
+	if t == nil && update.Slug != "" {
+		t = s.threadsBySlug[fold(update.Slug)]
+	}
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if update.Title != "" {
+		t.title = update.Title
+	}
+	if update.Message != "" {
+		t.message = update.Message
+	}
+	return s.threadModel(t), nil
+}
+
+// Vote counts a new vote in the thread, its forum and the global stats. Re-casting only moves the thread rating.
+func (r *ThreadRepo) Vote(ctx context.Context, vote *models.Vote) (*models.Thread, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	voter := s.users[fold(vote.Nick)]
+	if voter == nil {
+		return nil, pgError(codeUserNotFound)
+	}
+	var t *thread
+	if vote.ThreadId != 0 {
+		if t = s.threads[vote.ThreadId]; t == nil {
+			return nil, pgError(codeForeignKey)
+		}
+	} else {
+		if t = s.threadsBySlug[fold(vote.ThreadSlug)]; t == nil {
+			return nil, pgError(codeNotNull)
+		}
+		vote.ThreadId = t.id
+	}
+
+	key := voteKey{nick: fold(voter.Nick), threadId: t.id}
+	if old, ok := s.votes[key]; ok {
+		t.votes += vote.Voice - old
+	} else {
+		t.votes += vote.Voice
+		s.forumsById[t.forumId].votes++
+		s.stats.Votes++
+	}
+	s.votes[key] = vote.Voice
+	return s.threadModel(t), nil
+}
+
+func (r *ThreadRepo) Unvote(ctx context.Context, vote *models.Vote) (*models.Thread, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threadBySlugOrId(vote.ThreadSlug, vote.ThreadId)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	key := voteKey{nick: fold(vote.Nick), threadId: t.id}
+	if _, ok := s.votes[key]; ok {
+		s.retractVote(key)
+	}
+	return s.threadModel(t), nil
+}
+
+func (r *ThreadRepo) GetVotes(ctx context.Context, slug string, id int, desc bool, limit int, since string) ([]models.Vote, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t := s.threadBySlugOrId(slug, id)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	voters := make([]string, 0)
+	for key := range s.votes {
+		if key.threadId == t.id {
+			voters = append(voters, key.nick)
+		}
+	}
+	votes := make([]models.Vote, 0)
+	for _, voter := range s.pageVoters(voters, desc, limit, since) {
+		votes = append(votes, models.Vote{Nick: s.users[voter].Nick, Voice: s.votes[voteKey{nick: voter, threadId: t.id}], ThreadId: t.id})
+	}
+	return votes, nil
+}
+
+func (r *ThreadRepo) React(ctx context.Context, reaction *models.Reaction) (*models.Thread, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threadBySlugOrId(reaction.ThreadSlug, reaction.ThreadId)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	reactor := s.users[fold(reaction.Nick)]
+	if reactor == nil {
+		return nil, pgError(codeUserNotFound)
+	}
+	target := reactionTarget{threadId: t.id}
+	s.addReaction(reactionKey{reactionTarget: target, nick: fold(reactor.Nick), reaction: reaction.Reaction})
+	thread := s.threadModel(t)
+	thread.Reactions = s.reactionsOf(target)
+	return thread, nil
+}
+
+func (r *ThreadRepo) Unreact(ctx context.Context, reaction *models.Reaction) (*models.Thread, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.threadBySlugOrId(reaction.ThreadSlug, reaction.ThreadId)
+	if t == nil {
+		return nil, pgx.ErrNoRows
+	}
+	target := reactionTarget{threadId: t.id}
+	s.removeReaction(reactionKey{reactionTarget: target, nick: fold(reaction.Nick), reaction: reaction.Reaction})
+	thread := s.threadModel(t)
+	thread.Reactions = s.reactionsOf(target)
+	return thread, nil
+}
+
+func (r *ThreadRepo) GetReactions(ctx context.Context, id int) (map[string]int, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reactionsOf(reactionTarget{threadId: id}), nil
+}
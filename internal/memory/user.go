@@ -0,0 +1,402 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	userRepo "github.com/Natali-Skv/technopark_db_forum/internal/user"
+	"github.com/jackc/pgx/v5"
+)
+
+var _ userRepo.Repo = (*UserRepo)(nil)
+
+type UserRepo struct {
+	Store *Store
+}
+
+func NewUserRepo(store *Store) *UserRepo {
+	return &UserRepo{Store: store}
+}
+
+func (r *UserRepo) Create(ctx context.Context, newUser *models.User) (*models.User, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.createUser(newUser) {
+		return nil, pgError(codeUniqueViolated)
+	}
+	return newUser, nil
+}
+
+func (r *UserRepo) CreateBulk(ctx context.Context, users []models.User) ([][]models.User, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conflicts := make([][]models.User, len(users))
+	for i := range users {
+		if !s.createUser(&users[i]) {
+			conflicts[i] = s.usersByEmailOrNick(&users[i])
+		}
+	}
+	return conflicts, nil
+}
+
+// createUser returns false if the nickname or the email is taken. s.mu must be held for writing.
+func (s *Store) createUser(newUser *models.User) bool {
+	if s.users[fold(newUser.Nick)] != nil || s.usersByEmail[fold(newUser.Email)] != nil {
+		return false
+	}
+	s.lastUserId++
+	u := &user{id: s.lastUserId, User: *newUser}
+	s.users[fold(u.Nick)] = u
+	s.usersByEmail[fold(u.Email)] = u
+	s.stats.Users++
+	return true
+}
+
+func (r *UserRepo) GetByEmailOrNick(ctx context.Context, search *models.User) ([]models.User, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usersByEmailOrNick(search), nil
+}
+
+func (s *Store) usersByEmailOrNick(search *models.User) []models.User {
+	found := make([]*user, 0, 2)
+	if u := s.users[fold(search.Nick)]; u != nil {
+		found = append(found, u)
+	}
+	if u := s.usersByEmail[fold(search.Email)]; u != nil && (len(found) == 0 || found[0] != u) {
+		found = append(found, u)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].id < found[j].id })
+	usersResp := make([]models.User, 0, len(found))
+	for _, u := range found {
+		usersResp = append(usersResp, u.User)
+	}
+	return usersResp
+}
+
+func (r *UserRepo) GetByNick(ctx context.Context, nick string) (*models.User, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.users[fold(nick)]
+	if u == nil {
+		return nil, pgx.ErrNoRows
+	}
+	found := u.User
+	return &found, nil
+}
+
+func (r *UserRepo) GetByNicks(ctx context.Context, nicks []string) ([]models.User, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	usersResp := make([]models.User, 0, len(nicks))
+	seen := make(map[*user]bool, len(nicks))
+	for _, nick := range nicks {
+		if u := s.users[fold(nick)]; u != nil && !seen[u] {
+			seen[u] = true
+			usersResp = append(usersResp, u.User)
+		}
+	}
+	return usersResp, nil
+}
+
+func (r *UserRepo) Karma(ctx context.Context, nick string) (*models.Karma, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.users[fold(nick)]
+	if u == nil {
+		return nil, pgx.ErrNoRows
+	}
+	key := fold(u.Nick)
+	karma := &models.Karma{Nick: u.Nick}
+	for _, t := range s.threads {
+		if fold(t.authorNick) == key {
+			karma.ThreadVotes += t.votes
+		}
+	}
+	for _, p := range s.posts {
+		if fold(p.authorNick) == key {
+			karma.PostVotes += p.votes
+		}
+	}
+	karma.Karma = karma.ThreadVotes + karma.PostVotes
+	return karma, nil
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (string, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.usersByEmail[fold(email)]
+	if u == nil {
+		return "", pgx.ErrNoRows
+	}
+	return u.Nick, nil
+}
+
+func (r *UserRepo) Update(ctx context.Context, update *models.User) (*models.User, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.users[fold(update.Nick)]
+	if u == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if update.Email != "" {
+		if other := s.usersByEmail[fold(update.Email)]; other != nil && other != u {
+			return nil, pgError(codeUniqueViolated)
+		}
+		delete(s.usersByEmail, fold(u.Email))
+		u.Email = update.Email
+		s.usersByEmail[fold(u.Email)] = u
+	}
+	if update.Name != "" {
+		u.Name = update.Name
+	}
+	if update.About != "" {
+		u.About = update.About
+	}
+	*update = u.User
+	return update, nil
+}
+
+// Rename does what the ON UPDATE CASCADE foreign keys do in postgres: every copy of the nickname follows.
+func (r *UserRepo) Rename(ctx context.Context, nick string, newNick string) (*models.User, error) {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.users[fold(nick)]
+	if u == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if other := s.users[fold(newNick)]; other != nil && other != u {
+		return nil, pgError(codeUniqueViolated)
+	}
+	oldKey, newKey := fold(u.Nick), fold(newNick)
+	delete(s.users, oldKey)
+	u.Nick = newNick
+	s.users[newKey] = u
+
+	for _, f := range s.forumsById {
+		if fold(f.authorNick) == oldKey {
+			f.authorNick = newNick
+		}
+	}
+	for _, t := range s.threads {
+		if fold(t.authorNick) == oldKey {
+			t.authorNick = newNick
+		}
+		if fold(t.lastPostAuthor) == oldKey {
+			t.lastPostAuthor = newNick
+		}
+		if t.participants[oldKey] {
+			delete(t.participants, oldKey)
+			t.participants[newKey] = true
+		}
+	}
+	for _, p := range s.posts {
+		if fold(p.authorNick) == oldKey {
+			p.authorNick = newNick
+		}
+	}
+	renamedVotes := map[voteKey]int{}
+	for key, voice := range s.votes {
+		if key.nick == oldKey {
+			delete(s.votes, key)
+			renamedVotes[voteKey{nick: newKey, threadId: key.threadId}] = voice
+		}
+	}
+	for key, voice := range renamedVotes {
+		s.votes[key] = voice
+	}
+	renamedPostVotes := map[postVoteKey]int{}
+	for key, voice := range s.postVotes {
+		if key.nick == oldKey {
+			delete(s.postVotes, key)
+			renamedPostVotes[postVoteKey{nick: newKey, postId: key.postId}] = voice
+		}
+	}
+	for key, voice := range renamedPostVotes {
+		s.postVotes[key] = voice
+	}
+	renamedReactions := make([]reactionKey, 0)
+	for key := range s.reactions {
+		if key.nick == oldKey {
+			delete(s.reactions, key)
+			key.nick = newKey
+			renamedReactions = append(renamedReactions, key)
+		}
+	}
+	for _, key := range renamedReactions {
+		s.reactions[key] = true
+	}
+	for _, forumUsers := range s.forumUsers {
+		if forumUser, ok := forumUsers[oldKey]; ok {
+			delete(forumUsers, oldKey)
+			forumUsers[newKey] = forumUser
+		}
+	}
+
+	delete(s.renames, newKey)
+	if oldKey != newKey {
+		s.renames[oldKey] = u
+	}
+	renamed := u.User
+	return &renamed, nil
+}
+
+func (r *UserRepo) GetRenamed(ctx context.Context, nick string) (string, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.renames[fold(nick)]
+	if u == nil {
+		return "", pgx.ErrNoRows
+	}
+	return u.Nick, nil
+}
+
+func (r *UserRepo) Export(ctx context.Context, nick string) (*models.UserExport, error) {
+	s := r.Store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := s.users[fold(nick)]
+	if u == nil {
+		return nil, pgx.ErrNoRows
+	}
+	key := fold(u.Nick)
+	export := &models.UserExport{
+		User:      u.User,
+		Threads:   make([]models.Thread, 0),
+		Posts:     make([]models.Post, 0),
+		Votes:     make([]models.Vote, 0),
+		PostVotes: make([]models.PostVote, 0),
+		Reactions: make([]models.Reaction, 0),
+		Forums:    make([]models.ForumMembership, 0),
+	}
+	for _, t := range s.threads {
+		if fold(t.authorNick) == key {
+			export.Threads = append(export.Threads, *s.threadModel(t))
+		}
+	}
+	sort.Slice(export.Threads, func(i, j int) bool { return export.Threads[i].Id < export.Threads[j].Id })
+	for _, p := range s.posts {
+		if fold(p.authorNick) == key {
+			export.Posts = append(export.Posts, s.postModel(p))
+		}
+	}
+	sort.Slice(export.Posts, func(i, j int) bool { return export.Posts[i].Id < export.Posts[j].Id })
+	for vote, voice := range s.votes {
+		if vote.nick == key {
+			export.Votes = append(export.Votes, models.Vote{Nick: u.Nick, Voice: voice, ThreadId: vote.threadId})
+		}
+	}
+	sort.Slice(export.Votes, func(i, j int) bool { return export.Votes[i].ThreadId < export.Votes[j].ThreadId })
+	for vote, voice := range s.postVotes {
+		if vote.nick == key {
+			export.PostVotes = append(export.PostVotes, models.PostVote{Nick: u.Nick, Voice: voice, PostId: vote.postId})
+		}
+	}
+	sort.Slice(export.PostVotes, func(i, j int) bool { return export.PostVotes[i].PostId < export.PostVotes[j].PostId })
+	for reaction := range s.reactions {
+		if reaction.nick == key {
+			export.Reactions = append(export.Reactions, models.Reaction{Nick: u.Nick, Reaction: reaction.reaction,
+				PostId: reaction.postId, ThreadId: reaction.threadId})
+		}
+	}
+	sort.Slice(export.Reactions, func(i, j int) bool {
+		a, b := export.Reactions[i], export.Reactions[j]
+		if a.ThreadId != b.ThreadId {
+			return a.ThreadId < b.ThreadId
+		}
+		if a.PostId != b.PostId {
+			return a.PostId < b.PostId
+		}
+		return a.Reaction < b.Reaction
+	})
+	for forumId, forumUsers := range s.forumUsers {
+		if fu := forumUsers[key]; fu != nil {
+			export.Forums = append(export.Forums, models.ForumMembership{
+				Forum:         s.forumsById[forumId].slug,
+				Posts:         fu.posts,
+				Threads:       fu.threads,
+				FirstActivity: formatTime(fu.firstActivity),
+				LastActivity:  formatTime(fu.lastActivity),
+			})
+		}
+	}
+	sort.Slice(export.Forums, func(i, j int) bool { return fold(export.Forums[i].Forum) < fold(export.Forums[j].Forum) })
+	return export, nil
+}
+
+func (r *UserRepo) Delete(ctx context.Context, nick string) error {
+	s := r.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.users[fold(nick)]
+	if u == nil {
+		return pgx.ErrNoRows
+	}
+	ghost := s.ghost()
+	key := fold(u.Nick)
+
+	for vote := range s.votes {
+		if vote.nick == key {
+			s.retractVote(vote)
+		}
+	}
+	for vote := range s.postVotes {
+		if vote.nick == key {
+			s.retractPostVote(vote)
+		}
+	}
+	for reaction := range s.reactions {
+		if reaction.nick == key {
+			s.removeReaction(reaction)
+		}
+	}
+	for _, f := range s.forumsById {
+		if fold(f.authorNick) == key {
+			f.authorNick = ghost.Nick
+		}
+	}
+	for _, t := range s.threads {
+		if fold(t.authorNick) == key {
+			t.authorNick = ghost.Nick
+		}
+		if fold(t.lastPostAuthor) == key {
+			t.lastPostAuthor = ghost.Nick
+		}
+		if t.participants[key] {
+			delete(t.participants, key)
+			t.participants[fold(ghost.Nick)] = true
+		}
+	}
+	for _, p := range s.posts {
+		if fold(p.authorNick) == key {
+			p.authorNick = ghost.Nick
+		}
+	}
+	for forumId, forumUsers := range s.forumUsers {
+		if _, ok := forumUsers[key]; ok {
+			delete(forumUsers, key)
+			s.forumsById[forumId].users--
+			s.stats.ForumUsers--
+		}
+	}
+	for oldNick, renamed := range s.renames {
+		if renamed == u {
+			delete(s.renames, oldNick)
+		}
+	}
+	delete(s.users, key)
+	delete(s.usersByEmail, fold(u.Email))
+	s.stats.Users--
+	return nil
+}
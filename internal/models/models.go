@@ -2,15 +2,22 @@ package models
 
 //easyjson:json
 type Post struct {
-	Id         int    `json:"id"`
-	AuthorNick string `json:"author"`
-	ParentId   int    `json:"parent"`
-	Message    string `json:"message"`
-	IsEdited   bool   `json:"isEdited"`
-	ForumSlug  string `json:"forum"`
-	ThreadId   int    `json:"thread"`
-	ThreadSlug string `json:"-"`
-	Created    string `json:"created"`
+	Id          int            `json:"id"`
+	AuthorNick  string         `json:"author"`
+	ParentId    int            `json:"parent"`
+	Message     string         `json:"message"`
+	IsEdited    bool           `json:"isEdited"`
+	ForumSlug   string         `json:"forum"`
+	ThreadId    int            `json:"thread"`
+	ThreadSlug  string         `json:"-"`
+	Created     string         `json:"created"`
+	Votes       int            `json:"votes"`
+	Children    int            `json:"children"`
+	Descendants int            `json:"descendants"`
+	Depth       int            `json:"depth"`
+	MoreReplies int            `json:"moreReplies,omitempty"`
+	Replies     []Post         `json:"replies,omitempty"`
+	Reactions   map[string]int `json:"reactions,omitempty"`
 }
 
 //easyjson:json
@@ -24,14 +31,19 @@ type Forum struct {
 
 //easyjson:json
 type Thread struct {
-	Id         int    `json:"id"`
-	Slug       string `json:"slug" db:"slug"`
-	Title      string `json:"title" db:"title"`
-	AuthorNick string `json:"author" db:"author_nick"`
-	ForumSlug  string `json:"forum"`
-	Message    string `json:"message"`
-	Votes      int    `json:"votes"`
-	Created    string `json:"created"`
+	Id                int            `json:"id"`
+	Slug              string         `json:"slug" db:"slug"`
+	Title             string         `json:"title" db:"title"`
+	AuthorNick        string         `json:"author" db:"author_nick"`
+	ForumSlug         string         `json:"forum"`
+	Message           string         `json:"message"`
+	Votes             int            `json:"votes"`
+	Created           string         `json:"created"`
+	PostsCount        int            `json:"postsCount"`
+	LastPostAt        string         `json:"lastPostAt,omitempty"`
+	LastPostAuthor    string         `json:"lastPostAuthor,omitempty"`
+	ParticipantsCount int            `json:"participantsCount"`
+	Reactions         map[string]int `json:"reactions,omitempty"`
 }
 
 //easyjson:json
@@ -42,6 +54,52 @@ type User struct {
 	About string `json:"about"`
 }
 
+//easyjson:json
+type ForumUser struct {
+	User
+	Posts         int    `json:"posts"`
+	Threads       int    `json:"threads"`
+	FirstActivity string `json:"firstActivity"`
+	LastActivity  string `json:"lastActivity"`
+}
+
+//easyjson:json
+type ForumMembership struct {
+	Forum         string `json:"forum"`
+	Posts         int    `json:"posts"`
+	Threads       int    `json:"threads"`
+	FirstActivity string `json:"firstActivity"`
+	LastActivity  string `json:"lastActivity"`
+}
+
+//easyjson:json
+type UserExport struct {
+	User      User              `json:"user"`
+	Threads   []Thread          `json:"threads"`
+	Posts     []Post            `json:"posts"`
+	Votes     []Vote            `json:"votes"`
+	PostVotes []PostVote        `json:"postVotes"`
+	Reactions []Reaction        `json:"reactions"`
+	Forums    []ForumMembership `json:"forums"`
+}
+
+type ExportRecord struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+//easyjson:json
+type BulkUserResult struct {
+	Status   int    `json:"status"`
+	User     *User  `json:"user,omitempty"`
+	Existing []User `json:"existing,omitempty"`
+}
+
+//easyjson:json
+type UserRename struct {
+	Nick string `json:"nickname"`
+}
+
 //easyjson:json
 type Vote struct {
 	Nick       string `json:"nickname"`
@@ -50,10 +108,123 @@ type Vote struct {
 	ThreadSlug string `json:"-"`
 }
 
+//easyjson:json
+type PostVote struct {
+	Nick   string `json:"nickname"`
+	Voice  int    `json:"voice"`
+	PostId int    `json:"post"`
+}
+
+//easyjson:json
+type Reaction struct {
+	Nick       string `json:"nickname"`
+	Reaction   string `json:"reaction"`
+	PostId     int    `json:"post,omitempty"`
+	ThreadId   int    `json:"thread,omitempty"`
+	ThreadSlug string `json:"-"`
+}
+
+//easyjson:json
+type PostContext struct {
+	Ancestors []Post `json:"ancestors"`
+	Before    []Post `json:"before"`
+	Post      Post   `json:"post"`
+	After     []Post `json:"after"`
+}
+
+//easyjson:json
+type Karma struct {
+	Nick        string `json:"nickname"`
+	Karma       int    `json:"karma"`
+	ThreadVotes int    `json:"threadVotes"`
+	PostVotes   int    `json:"postVotes"`
+}
+
 //easyjson:json
 type Status struct {
-	Users   int `json:"user"`
-	Forums  int `json:"forum"`
-	Threads int `json:"thread"`
-	Posts   int `json:"post"`
+	Users        int `json:"user"`
+	Forums       int `json:"forum"`
+	Threads      int `json:"thread"`
+	Posts        int `json:"post"`
+	Votes        int `json:"vote"`
+	ForumUsers   int `json:"forumUser"`
+	EditedPosts  int `json:"editedPost"`
+	DeletedPosts int `json:"deletedPost"`
+}
+
+//easyjson:json
+type ForumStatus struct {
+	Slug         string `json:"slug"`
+	Threads      int    `json:"thread"`
+	Posts        int    `json:"post"`
+	Votes        int    `json:"vote"`
+	Users        int    `json:"user"`
+	EditedPosts  int    `json:"editedPost"`
+	DeletedPosts int    `json:"deletedPost"`
+}
+
+type AuditEvent struct {
+	Action     string
+	Target     string
+	Outcome    string
+	RequestId  string
+	RemoteAddr string
+}
+
+//easyjson:json
+type Health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+//easyjson:json
+type Diagnostics struct {
+	Uptime          string            `json:"uptime"`
+	GoVersion       string            `json:"goVersion"`
+	Goroutines      int               `json:"goroutines"`
+	SchemaVersion   int               `json:"schemaVersion"`
+	Pool            PoolStat          `json:"pool"`
+	Statements      []string          `json:"statements"`
+	StatementErrors map[string]string `json:"statementErrors"`
+	DatabaseSize    int64             `json:"databaseSize"`
+	Tables          []TableStat       `json:"tables"`
+}
+
+type PoolStat struct {
+	MaxConnections       int `json:"max"`
+	CurrentConnections   int `json:"current"`
+	AvailableConnections int `json:"available"`
+}
+
+type TableStat struct {
+	Name     string `json:"name"`
+	LiveRows int64  `json:"liveRows"`
+	DeadRows int64  `json:"deadRows"`
+	Size     int64  `json:"size"`
+}
+
+//easyjson:json
+type MaintenanceTask struct {
+	Name string `json:"name"`
+	// State is idle, queued or running.
+	State        string `json:"state"`
+	Runs         int    `json:"runs"`
+	Failures     int    `json:"failures"`
+	LastTrigger  string `json:"lastTrigger,omitempty"`
+	LastStarted  string `json:"lastStarted,omitempty"`
+	LastDuration string `json:"lastDuration,omitempty"`
+	LastError    string `json:"lastError,omitempty"`
+	NextRun      string `json:"nextRun,omitempty"`
+}
+
+//easyjson:json
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+//easyjson:json
+type ValidationError struct {
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields"`
 }
@@ -97,7 +97,478 @@ func (v *Vote) UnmarshalJSON(data []byte) error {
 func (v *Vote) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(in *jlexer.Lexer, out *User) {
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(in *jlexer.Lexer, out *ValidationError) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "message":
+			out.Message = string(in.String())
+		case "fields":
+			if in.IsNull() {
+				in.Skip()
+				out.Fields = nil
+			} else {
+				in.Delim('[')
+				if out.Fields == nil {
+					if !in.IsDelim(']') {
+						out.Fields = make([]FieldError, 0, 2)
+					} else {
+						out.Fields = []FieldError{}
+					}
+				} else {
+					out.Fields = (out.Fields)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 FieldError
+					(v1).UnmarshalEasyJSON(in)
+					out.Fields = append(out.Fields, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(out *jwriter.Writer, in ValidationError) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"message\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Message))
+	}
+	{
+		const prefix string = ",\"fields\":"
+		out.RawString(prefix)
+		if in.Fields == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Fields {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ValidationError) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ValidationError) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ValidationError) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ValidationError) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(in *jlexer.Lexer, out *UserRename) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "nickname":
+			out.Nick = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(out *jwriter.Writer, in UserRename) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"nickname\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Nick))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v UserRename) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v UserRename) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *UserRename) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *UserRename) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(in *jlexer.Lexer, out *UserExport) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user":
+			(out.User).UnmarshalEasyJSON(in)
+		case "threads":
+			if in.IsNull() {
+				in.Skip()
+				out.Threads = nil
+			} else {
+				in.Delim('[')
+				if out.Threads == nil {
+					if !in.IsDelim(']') {
+						out.Threads = make([]Thread, 0, 0)
+					} else {
+						out.Threads = []Thread{}
+					}
+				} else {
+					out.Threads = (out.Threads)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v4 Thread
+					(v4).UnmarshalEasyJSON(in)
+					out.Threads = append(out.Threads, v4)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "posts":
+			if in.IsNull() {
+				in.Skip()
+				out.Posts = nil
+			} else {
+				in.Delim('[')
+				if out.Posts == nil {
+					if !in.IsDelim(']') {
+						out.Posts = make([]Post, 0, 0)
+					} else {
+						out.Posts = []Post{}
+					}
+				} else {
+					out.Posts = (out.Posts)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v5 Post
+					(v5).UnmarshalEasyJSON(in)
+					out.Posts = append(out.Posts, v5)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "votes":
+			if in.IsNull() {
+				in.Skip()
+				out.Votes = nil
+			} else {
+				in.Delim('[')
+				if out.Votes == nil {
+					if !in.IsDelim(']') {
+						out.Votes = make([]Vote, 0, 1)
+					} else {
+						out.Votes = []Vote{}
+					}
+				} else {
+					out.Votes = (out.Votes)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v6 Vote
+					(v6).UnmarshalEasyJSON(in)
+					out.Votes = append(out.Votes, v6)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "postVotes":
+			if in.IsNull() {
+				in.Skip()
+				out.PostVotes = nil
+			} else {
+				in.Delim('[')
+				if out.PostVotes == nil {
+					if !in.IsDelim(']') {
+						out.PostVotes = make([]PostVote, 0, 2)
+					} else {
+						out.PostVotes = []PostVote{}
+					}
+				} else {
+					out.PostVotes = (out.PostVotes)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v7 PostVote
+					(v7).UnmarshalEasyJSON(in)
+					out.PostVotes = append(out.PostVotes, v7)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "reactions":
+			if in.IsNull() {
+				in.Skip()
+				out.Reactions = nil
+			} else {
+				in.Delim('[')
+				if out.Reactions == nil {
+					if !in.IsDelim(']') {
+						out.Reactions = make([]Reaction, 0, 1)
+					} else {
+						out.Reactions = []Reaction{}
+					}
+				} else {
+					out.Reactions = (out.Reactions)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v8 Reaction
+					(v8).UnmarshalEasyJSON(in)
+					out.Reactions = append(out.Reactions, v8)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "forums":
+			if in.IsNull() {
+				in.Skip()
+				out.Forums = nil
+			} else {
+				in.Delim('[')
+				if out.Forums == nil {
+					if !in.IsDelim(']') {
+						out.Forums = make([]ForumMembership, 0, 1)
+					} else {
+						out.Forums = []ForumMembership{}
+					}
+				} else {
+					out.Forums = (out.Forums)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v9 ForumMembership
+					(v9).UnmarshalEasyJSON(in)
+					out.Forums = append(out.Forums, v9)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels3(out *jwriter.Writer, in UserExport) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user\":"
+		out.RawString(prefix[1:])
+		(in.User).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"threads\":"
+		out.RawString(prefix)
+		if in.Threads == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v10, v11 := range in.Threads {
+				if v10 > 0 {
+					out.RawByte(',')
+				}
+				(v11).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"posts\":"
+		out.RawString(prefix)
+		if in.Posts == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v12, v13 := range in.Posts {
+				if v12 > 0 {
+					out.RawByte(',')
+				}
+				(v13).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"votes\":"
+		out.RawString(prefix)
+		if in.Votes == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v14, v15 := range in.Votes {
+				if v14 > 0 {
+					out.RawByte(',')
+				}
+				(v15).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"postVotes\":"
+		out.RawString(prefix)
+		if in.PostVotes == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v16, v17 := range in.PostVotes {
+				if v16 > 0 {
+					out.RawByte(',')
+				}
+				(v17).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"reactions\":"
+		out.RawString(prefix)
+		if in.Reactions == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v18, v19 := range in.Reactions {
+				if v18 > 0 {
+					out.RawByte(',')
+				}
+				(v19).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"forums\":"
+		out.RawString(prefix)
+		if in.Forums == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v20, v21 := range in.Forums {
+				if v20 > 0 {
+					out.RawByte(',')
+				}
+				(v21).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v UserExport) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels3(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v UserExport) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels3(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *UserExport) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *UserExport) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(in *jlexer.Lexer, out *User) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -134,7 +605,7 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(in
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(out *jwriter.Writer, in User) {
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels4(out *jwriter.Writer, in User) {
 	out.RawByte('{')
 	first := true
 	_ = first
@@ -156,35 +627,1539 @@ func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(ou
 	{
 		const prefix string = ",\"about\":"
 		out.RawString(prefix)
-		out.String(string(in.About))
+		out.String(string(in.About))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v User) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels4(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v User) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels4(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *User) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *User) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(in *jlexer.Lexer, out *Thread) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = int(in.Int())
+		case "slug":
+			out.Slug = string(in.String())
+		case "title":
+			out.Title = string(in.String())
+		case "author":
+			out.AuthorNick = string(in.String())
+		case "forum":
+			out.ForumSlug = string(in.String())
+		case "message":
+			out.Message = string(in.String())
+		case "votes":
+			out.Votes = int(in.Int())
+		case "created":
+			out.Created = string(in.String())
+		case "postsCount":
+			out.PostsCount = int(in.Int())
+		case "lastPostAt":
+			out.LastPostAt = string(in.String())
+		case "lastPostAuthor":
+			out.LastPostAuthor = string(in.String())
+		case "participantsCount":
+			out.ParticipantsCount = int(in.Int())
+		case "reactions":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Reactions = make(map[string]int)
+				} else {
+					out.Reactions = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v22 int
+					v22 = int(in.Int())
+					(out.Reactions)[key] = v22
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels5(out *jwriter.Writer, in Thread) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Id))
+	}
+	{
+		const prefix string = ",\"slug\":"
+		out.RawString(prefix)
+		out.String(string(in.Slug))
+	}
+	{
+		const prefix string = ",\"title\":"
+		out.RawString(prefix)
+		out.String(string(in.Title))
+	}
+	{
+		const prefix string = ",\"author\":"
+		out.RawString(prefix)
+		out.String(string(in.AuthorNick))
+	}
+	{
+		const prefix string = ",\"forum\":"
+		out.RawString(prefix)
+		out.String(string(in.ForumSlug))
+	}
+	{
+		const prefix string = ",\"message\":"
+		out.RawString(prefix)
+		out.String(string(in.Message))
+	}
+	{
+		const prefix string = ",\"votes\":"
+		out.RawString(prefix)
+		out.Int(int(in.Votes))
+	}
+	{
+		const prefix string = ",\"created\":"
+		out.RawString(prefix)
+		out.String(string(in.Created))
+	}
+	{
+		const prefix string = ",\"postsCount\":"
+		out.RawString(prefix)
+		out.Int(int(in.PostsCount))
+	}
+	if in.LastPostAt != "" {
+		const prefix string = ",\"lastPostAt\":"
+		out.RawString(prefix)
+		out.String(string(in.LastPostAt))
+	}
+	if in.LastPostAuthor != "" {
+		const prefix string = ",\"lastPostAuthor\":"
+		out.RawString(prefix)
+		out.String(string(in.LastPostAuthor))
+	}
+	{
+		const prefix string = ",\"participantsCount\":"
+		out.RawString(prefix)
+		out.Int(int(in.ParticipantsCount))
+	}
+	if len(in.Reactions) != 0 {
+		const prefix string = ",\"reactions\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v23First := true
+			for v23Name, v23Value := range in.Reactions {
+				if v23First {
+					v23First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v23Name))
+				out.RawByte(':')
+				out.Int(int(v23Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Thread) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels5(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Thread) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels5(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Thread) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Thread) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels6(in *jlexer.Lexer, out *Status) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "user":
+			out.Users = int(in.Int())
+		case "forum":
+			out.Forums = int(in.Int())
+		case "thread":
+			out.Threads = int(in.Int())
+		case "post":
+			out.Posts = int(in.Int())
+		case "vote":
+			out.Votes = int(in.Int())
+		case "forumUser":
+			out.ForumUsers = int(in.Int())
+		case "editedPost":
+			out.EditedPosts = int(in.Int())
+		case "deletedPost":
+			out.DeletedPosts = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels6(out *jwriter.Writer, in Status) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"user\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Users))
+	}
+	{
+		const prefix string = ",\"forum\":"
+		out.RawString(prefix)
+		out.Int(int(in.Forums))
+	}
+	{
+		const prefix string = ",\"thread\":"
+		out.RawString(prefix)
+		out.Int(int(in.Threads))
+	}
+	{
+		const prefix string = ",\"post\":"
+		out.RawString(prefix)
+		out.Int(int(in.Posts))
+	}
+	{
+		const prefix string = ",\"vote\":"
+		out.RawString(prefix)
+		out.Int(int(in.Votes))
+	}
+	{
+		const prefix string = ",\"forumUser\":"
+		out.RawString(prefix)
+		out.Int(int(in.ForumUsers))
+	}
+	{
+		const prefix string = ",\"editedPost\":"
+		out.RawString(prefix)
+		out.Int(int(in.EditedPosts))
+	}
+	{
+		const prefix string = ",\"deletedPost\":"
+		out.RawString(prefix)
+		out.Int(int(in.DeletedPosts))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Status) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels6(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Status) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels6(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Status) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels6(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Status) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels6(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels7(in *jlexer.Lexer, out *Reaction) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "nickname":
+			out.Nick = string(in.String())
+		case "reaction":
+			out.Reaction = string(in.String())
+		case "post":
+			out.PostId = int(in.Int())
+		case "thread":
+			out.ThreadId = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels7(out *jwriter.Writer, in Reaction) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"nickname\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Nick))
+	}
+	{
+		const prefix string = ",\"reaction\":"
+		out.RawString(prefix)
+		out.String(string(in.Reaction))
+	}
+	if in.PostId != 0 {
+		const prefix string = ",\"post\":"
+		out.RawString(prefix)
+		out.Int(int(in.PostId))
+	}
+	if in.ThreadId != 0 {
+		const prefix string = ",\"thread\":"
+		out.RawString(prefix)
+		out.Int(int(in.ThreadId))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Reaction) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels7(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Reaction) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels7(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Reaction) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels7(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Reaction) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels7(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels8(in *jlexer.Lexer, out *PostVote) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "nickname":
+			out.Nick = string(in.String())
+		case "voice":
+			out.Voice = int(in.Int())
+		case "post":
+			out.PostId = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels8(out *jwriter.Writer, in PostVote) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"nickname\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Nick))
+	}
+	{
+		const prefix string = ",\"voice\":"
+		out.RawString(prefix)
+		out.Int(int(in.Voice))
+	}
+	{
+		const prefix string = ",\"post\":"
+		out.RawString(prefix)
+		out.Int(int(in.PostId))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v PostVote) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels8(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v PostVote) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels8(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *PostVote) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels8(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *PostVote) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels8(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels9(in *jlexer.Lexer, out *PostContext) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "ancestors":
+			if in.IsNull() {
+				in.Skip()
+				out.Ancestors = nil
+			} else {
+				in.Delim('[')
+				if out.Ancestors == nil {
+					if !in.IsDelim(']') {
+						out.Ancestors = make([]Post, 0, 0)
+					} else {
+						out.Ancestors = []Post{}
+					}
+				} else {
+					out.Ancestors = (out.Ancestors)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v24 Post
+					(v24).UnmarshalEasyJSON(in)
+					out.Ancestors = append(out.Ancestors, v24)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "before":
+			if in.IsNull() {
+				in.Skip()
+				out.Before = nil
+			} else {
+				in.Delim('[')
+				if out.Before == nil {
+					if !in.IsDelim(']') {
+						out.Before = make([]Post, 0, 0)
+					} else {
+						out.Before = []Post{}
+					}
+				} else {
+					out.Before = (out.Before)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v25 Post
+					(v25).UnmarshalEasyJSON(in)
+					out.Before = append(out.Before, v25)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "post":
+			(out.Post).UnmarshalEasyJSON(in)
+		case "after":
+			if in.IsNull() {
+				in.Skip()
+				out.After = nil
+			} else {
+				in.Delim('[')
+				if out.After == nil {
+					if !in.IsDelim(']') {
+						out.After = make([]Post, 0, 0)
+					} else {
+						out.After = []Post{}
+					}
+				} else {
+					out.After = (out.After)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v26 Post
+					(v26).UnmarshalEasyJSON(in)
+					out.After = append(out.After, v26)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels9(out *jwriter.Writer, in PostContext) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"ancestors\":"
+		out.RawString(prefix[1:])
+		if in.Ancestors == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v27, v28 := range in.Ancestors {
+				if v27 > 0 {
+					out.RawByte(',')
+				}
+				(v28).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"before\":"
+		out.RawString(prefix)
+		if in.Before == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v29, v30 := range in.Before {
+				if v29 > 0 {
+					out.RawByte(',')
+				}
+				(v30).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"post\":"
+		out.RawString(prefix)
+		(in.Post).MarshalEasyJSON(out)
+	}
+	{
+		const prefix string = ",\"after\":"
+		out.RawString(prefix)
+		if in.After == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v31, v32 := range in.After {
+				if v31 > 0 {
+					out.RawByte(',')
+				}
+				(v32).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v PostContext) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels9(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v PostContext) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels9(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *PostContext) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels9(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *PostContext) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels9(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels10(in *jlexer.Lexer, out *Post) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = int(in.Int())
+		case "author":
+			out.AuthorNick = string(in.String())
+		case "parent":
+			out.ParentId = int(in.Int())
+		case "message":
+			out.Message = string(in.String())
+		case "isEdited":
+			out.IsEdited = bool(in.Bool())
+		case "forum":
+			out.ForumSlug = string(in.String())
+		case "thread":
+			out.ThreadId = int(in.Int())
+		case "created":
+			out.Created = string(in.String())
+		case "votes":
+			out.Votes = int(in.Int())
+		case "children":
+			out.Children = int(in.Int())
+		case "descendants":
+			out.Descendants = int(in.Int())
+		case "depth":
+			out.Depth = int(in.Int())
+		case "moreReplies":
+			out.MoreReplies = int(in.Int())
+		case "replies":
+			if in.IsNull() {
+				in.Skip()
+				out.Replies = nil
+			} else {
+				in.Delim('[')
+				if out.Replies == nil {
+					if !in.IsDelim(']') {
+						out.Replies = make([]Post, 0, 0)
+					} else {
+						out.Replies = []Post{}
+					}
+				} else {
+					out.Replies = (out.Replies)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v33 Post
+					(v33).UnmarshalEasyJSON(in)
+					out.Replies = append(out.Replies, v33)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "reactions":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Reactions = make(map[string]int)
+				} else {
+					out.Reactions = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v34 int
+					v34 = int(in.Int())
+					(out.Reactions)[key] = v34
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels10(out *jwriter.Writer, in Post) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Id))
+	}
+	{
+		const prefix string = ",\"author\":"
+		out.RawString(prefix)
+		out.String(string(in.AuthorNick))
+	}
+	{
+		const prefix string = ",\"parent\":"
+		out.RawString(prefix)
+		out.Int(int(in.ParentId))
+	}
+	{
+		const prefix string = ",\"message\":"
+		out.RawString(prefix)
+		out.String(string(in.Message))
+	}
+	{
+		const prefix string = ",\"isEdited\":"
+		out.RawString(prefix)
+		out.Bool(bool(in.IsEdited))
+	}
+	{
+		const prefix string = ",\"forum\":"
+		out.RawString(prefix)
+		out.String(string(in.ForumSlug))
+	}
+	{
+		const prefix string = ",\"thread\":"
+		out.RawString(prefix)
+		out.Int(int(in.ThreadId))
+	}
+	{
+		const prefix string = ",\"created\":"
+		out.RawString(prefix)
+		out.String(string(in.Created))
+	}
+	{
+		const prefix string = ",\"votes\":"
+		out.RawString(prefix)
+		out.Int(int(in.Votes))
+	}
+	{
+		const prefix string = ",\"children\":"
+		out.RawString(prefix)
+		out.Int(int(in.Children))
+	}
+	{
+		const prefix string = ",\"descendants\":"
+		out.RawString(prefix)
+		out.Int(int(in.Descendants))
+	}
+	{
+		const prefix string = ",\"depth\":"
+		out.RawString(prefix)
+		out.Int(int(in.Depth))
+	}
+	if in.MoreReplies != 0 {
+		const prefix string = ",\"moreReplies\":"
+		out.RawString(prefix)
+		out.Int(int(in.MoreReplies))
+	}
+	if len(in.Replies) != 0 {
+		const prefix string = ",\"replies\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v35, v36 := range in.Replies {
+				if v35 > 0 {
+					out.RawByte(',')
+				}
+				(v36).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.Reactions) != 0 {
+		const prefix string = ",\"reactions\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v37First := true
+			for v37Name, v37Value := range in.Reactions {
+				if v37First {
+					v37First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v37Name))
+				out.RawByte(':')
+				out.Int(int(v37Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Post) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels10(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Post) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels10(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Post) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels10(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Post) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels10(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels11(in *jlexer.Lexer, out *MaintenanceTask) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "state":
+			out.State = string(in.String())
+		case "runs":
+			out.Runs = int(in.Int())
+		case "failures":
+			out.Failures = int(in.Int())
+		case "lastTrigger":
+			out.LastTrigger = string(in.String())
+		case "lastStarted":
+			out.LastStarted = string(in.String())
+		case "lastDuration":
+			out.LastDuration = string(in.String())
+		case "lastError":
+			out.LastError = string(in.String())
+		case "nextRun":
+			out.NextRun = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels11(out *jwriter.Writer, in MaintenanceTask) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"state\":"
+		out.RawString(prefix)
+		out.String(string(in.State))
+	}
+	{
+		const prefix string = ",\"runs\":"
+		out.RawString(prefix)
+		out.Int(int(in.Runs))
+	}
+	{
+		const prefix string = ",\"failures\":"
+		out.RawString(prefix)
+		out.Int(int(in.Failures))
+	}
+	if in.LastTrigger != "" {
+		const prefix string = ",\"lastTrigger\":"
+		out.RawString(prefix)
+		out.String(string(in.LastTrigger))
+	}
+	if in.LastStarted != "" {
+		const prefix string = ",\"lastStarted\":"
+		out.RawString(prefix)
+		out.String(string(in.LastStarted))
+	}
+	if in.LastDuration != "" {
+		const prefix string = ",\"lastDuration\":"
+		out.RawString(prefix)
+		out.String(string(in.LastDuration))
+	}
+	if in.LastError != "" {
+		const prefix string = ",\"lastError\":"
+		out.RawString(prefix)
+		out.String(string(in.LastError))
+	}
+	if in.NextRun != "" {
+		const prefix string = ",\"nextRun\":"
+		out.RawString(prefix)
+		out.String(string(in.NextRun))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v MaintenanceTask) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels11(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v MaintenanceTask) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels11(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *MaintenanceTask) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels11(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *MaintenanceTask) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels11(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels12(in *jlexer.Lexer, out *Karma) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "nickname":
+			out.Nick = string(in.String())
+		case "karma":
+			out.Karma = int(in.Int())
+		case "threadVotes":
+			out.ThreadVotes = int(in.Int())
+		case "postVotes":
+			out.PostVotes = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels12(out *jwriter.Writer, in Karma) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"nickname\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Nick))
+	}
+	{
+		const prefix string = ",\"karma\":"
+		out.RawString(prefix)
+		out.Int(int(in.Karma))
+	}
+	{
+		const prefix string = ",\"threadVotes\":"
+		out.RawString(prefix)
+		out.Int(int(in.ThreadVotes))
+	}
+	{
+		const prefix string = ",\"postVotes\":"
+		out.RawString(prefix)
+		out.Int(int(in.PostVotes))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Karma) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels12(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Karma) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels12(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Karma) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels12(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Karma) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels12(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels13(in *jlexer.Lexer, out *Health) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "status":
+			out.Status = string(in.String())
+		case "checks":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.Checks = make(map[string]string)
+				} else {
+					out.Checks = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v38 string
+					v38 = string(in.String())
+					(out.Checks)[key] = v38
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels13(out *jwriter.Writer, in Health) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Status))
+	}
+	if len(in.Checks) != 0 {
+		const prefix string = ",\"checks\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v39First := true
+			for v39Name, v39Value := range in.Checks {
+				if v39First {
+					v39First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v39Name))
+				out.RawByte(':')
+				out.String(string(v39Value))
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Health) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels13(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Health) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels13(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Health) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels13(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Health) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels13(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels14(in *jlexer.Lexer, out *ForumUser) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "posts":
+			out.Posts = int(in.Int())
+		case "threads":
+			out.Threads = int(in.Int())
+		case "firstActivity":
+			out.FirstActivity = string(in.String())
+		case "lastActivity":
+			out.LastActivity = string(in.String())
+		case "fullname":
+			out.Name = string(in.String())
+		case "nickname":
+			out.Nick = string(in.String())
+		case "email":
+			out.Email = string(in.String())
+		case "about":
+			out.About = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels14(out *jwriter.Writer, in ForumUser) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"posts\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Posts))
+	}
+	{
+		const prefix string = ",\"threads\":"
+		out.RawString(prefix)
+		out.Int(int(in.Threads))
+	}
+	{
+		const prefix string = ",\"firstActivity\":"
+		out.RawString(prefix)
+		out.String(string(in.FirstActivity))
+	}
+	{
+		const prefix string = ",\"lastActivity\":"
+		out.RawString(prefix)
+		out.String(string(in.LastActivity))
+	}
+	{
+		const prefix string = ",\"fullname\":"
+		out.RawString(prefix)
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"nickname\":"
+		out.RawString(prefix)
+		out.String(string(in.Nick))
+	}
+	{
+		const prefix string = ",\"email\":"
+		out.RawString(prefix)
+		out.String(string(in.Email))
+	}
+	{
+		const prefix string = ",\"about\":"
+		out.RawString(prefix)
+		out.String(string(in.About))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ForumUser) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels14(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ForumUser) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels14(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ForumUser) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels14(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ForumUser) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels14(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels15(in *jlexer.Lexer, out *ForumStatus) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "slug":
+			out.Slug = string(in.String())
+		case "thread":
+			out.Threads = int(in.Int())
+		case "post":
+			out.Posts = int(in.Int())
+		case "vote":
+			out.Votes = int(in.Int())
+		case "user":
+			out.Users = int(in.Int())
+		case "editedPost":
+			out.EditedPosts = int(in.Int())
+		case "deletedPost":
+			out.DeletedPosts = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels15(out *jwriter.Writer, in ForumStatus) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"slug\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Slug))
+	}
+	{
+		const prefix string = ",\"thread\":"
+		out.RawString(prefix)
+		out.Int(int(in.Threads))
+	}
+	{
+		const prefix string = ",\"post\":"
+		out.RawString(prefix)
+		out.Int(int(in.Posts))
+	}
+	{
+		const prefix string = ",\"vote\":"
+		out.RawString(prefix)
+		out.Int(int(in.Votes))
+	}
+	{
+		const prefix string = ",\"user\":"
+		out.RawString(prefix)
+		out.Int(int(in.Users))
+	}
+	{
+		const prefix string = ",\"editedPost\":"
+		out.RawString(prefix)
+		out.Int(int(in.EditedPosts))
+	}
+	{
+		const prefix string = ",\"deletedPost\":"
+		out.RawString(prefix)
+		out.Int(int(in.DeletedPosts))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ForumStatus) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels15(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ForumStatus) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels15(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ForumStatus) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels15(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ForumStatus) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels15(l, v)
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels16(in *jlexer.Lexer, out *ForumMembership) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "forum":
+			out.Forum = string(in.String())
+		case "posts":
+			out.Posts = int(in.Int())
+		case "threads":
+			out.Threads = int(in.Int())
+		case "firstActivity":
+			out.FirstActivity = string(in.String())
+		case "lastActivity":
+			out.LastActivity = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels16(out *jwriter.Writer, in ForumMembership) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"forum\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Forum))
+	}
+	{
+		const prefix string = ",\"posts\":"
+		out.RawString(prefix)
+		out.Int(int(in.Posts))
+	}
+	{
+		const prefix string = ",\"threads\":"
+		out.RawString(prefix)
+		out.Int(int(in.Threads))
+	}
+	{
+		const prefix string = ",\"firstActivity\":"
+		out.RawString(prefix)
+		out.String(string(in.FirstActivity))
+	}
+	{
+		const prefix string = ",\"lastActivity\":"
+		out.RawString(prefix)
+		out.String(string(in.LastActivity))
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v User) MarshalJSON() ([]byte, error) {
+func (v ForumMembership) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(&w, v)
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels16(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v User) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels1(w, v)
+func (v ForumMembership) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels16(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *User) UnmarshalJSON(data []byte) error {
+func (v *ForumMembership) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(&r, v)
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels16(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *User) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels1(l, v)
+func (v *ForumMembership) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels16(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(in *jlexer.Lexer, out *Thread) {
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels17(in *jlexer.Lexer, out *Forum) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -203,22 +2178,16 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(in
 			continue
 		}
 		switch key {
-		case "id":
-			out.Id = int(in.Int())
 		case "slug":
 			out.Slug = string(in.String())
 		case "title":
 			out.Title = string(in.String())
-		case "author":
-			out.AuthorNick = string(in.String())
-		case "forum":
-			out.ForumSlug = string(in.String())
-		case "message":
-			out.Message = string(in.String())
-		case "votes":
-			out.Votes = int(in.Int())
-		case "created":
-			out.Created = string(in.String())
+		case "user":
+			out.UserNick = string(in.String())
+		case "posts":
+			out.Posts = int(in.Int())
+		case "threads":
+			out.Threads = int(in.Int())
 		default:
 			in.SkipRecursive()
 		}
@@ -229,18 +2198,13 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(in
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(out *jwriter.Writer, in Thread) {
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels17(out *jwriter.Writer, in Forum) {
 	out.RawByte('{')
 	first := true
 	_ = first
-	{
-		const prefix string = ",\"id\":"
-		out.RawString(prefix[1:])
-		out.Int(int(in.Id))
-	}
 	{
 		const prefix string = ",\"slug\":"
-		out.RawString(prefix)
+		out.RawString(prefix[1:])
 		out.String(string(in.Slug))
 	}
 	{
@@ -249,57 +2213,47 @@ func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(ou
 		out.String(string(in.Title))
 	}
 	{
-		const prefix string = ",\"author\":"
-		out.RawString(prefix)
-		out.String(string(in.AuthorNick))
-	}
-	{
-		const prefix string = ",\"forum\":"
-		out.RawString(prefix)
-		out.String(string(in.ForumSlug))
-	}
-	{
-		const prefix string = ",\"message\":"
+		const prefix string = ",\"user\":"
 		out.RawString(prefix)
-		out.String(string(in.Message))
+		out.String(string(in.UserNick))
 	}
 	{
-		const prefix string = ",\"votes\":"
+		const prefix string = ",\"posts\":"
 		out.RawString(prefix)
-		out.Int(int(in.Votes))
+		out.Int(int(in.Posts))
 	}
 	{
-		const prefix string = ",\"created\":"
+		const prefix string = ",\"threads\":"
 		out.RawString(prefix)
-		out.String(string(in.Created))
+		out.Int(int(in.Threads))
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v Thread) MarshalJSON() ([]byte, error) {
+func (v Forum) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(&w, v)
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels17(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v Thread) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels2(w, v)
+func (v Forum) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels17(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *Thread) UnmarshalJSON(data []byte) error {
+func (v *Forum) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(&r, v)
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels17(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *Thread) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels2(l, v)
+func (v *Forum) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels17(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(in *jlexer.Lexer, out *Status) {
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels18(in *jlexer.Lexer, out *FieldError) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -318,14 +2272,10 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(in
 			continue
 		}
 		switch key {
-		case "user":
-			out.Users = int(in.Int())
-		case "forum":
-			out.Forums = int(in.Int())
-		case "thread":
-			out.Threads = int(in.Int())
-		case "post":
-			out.Posts = int(in.Int())
+		case "field":
+			out.Field = string(in.String())
+		case "reason":
+			out.Reason = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -336,57 +2286,47 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(in
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels3(out *jwriter.Writer, in Status) {
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels18(out *jwriter.Writer, in FieldError) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"user\":"
+		const prefix string = ",\"field\":"
 		out.RawString(prefix[1:])
-		out.Int(int(in.Users))
-	}
-	{
-		const prefix string = ",\"forum\":"
-		out.RawString(prefix)
-		out.Int(int(in.Forums))
-	}
-	{
-		const prefix string = ",\"thread\":"
-		out.RawString(prefix)
-		out.Int(int(in.Threads))
+		out.String(string(in.Field))
 	}
 	{
-		const prefix string = ",\"post\":"
+		const prefix string = ",\"reason\":"
 		out.RawString(prefix)
-		out.Int(int(in.Posts))
+		out.String(string(in.Reason))
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v Status) MarshalJSON() ([]byte, error) {
+func (v FieldError) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels3(&w, v)
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels18(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v Status) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels3(w, v)
+func (v FieldError) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels18(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *Status) UnmarshalJSON(data []byte) error {
+func (v *FieldError) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(&r, v)
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels18(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *Status) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels3(l, v)
+func (v *FieldError) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels18(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(in *jlexer.Lexer, out *Post) {
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels19(in *jlexer.Lexer, out *Diagnostics) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -405,22 +2345,80 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(in
 			continue
 		}
 		switch key {
-		case "id":
-			out.Id = int(in.Int())
-		case "author":
-			out.AuthorNick = string(in.String())
-		case "parent":
-			out.ParentId = int(in.Int())
-		case "message":
-			out.Message = string(in.String())
-		case "isEdited":
-			out.IsEdited = bool(in.Bool())
-		case "forum":
-			out.ForumSlug = string(in.String())
-		case "thread":
-			out.ThreadId = int(in.Int())
-		case "created":
-			out.Created = string(in.String())
+		case "uptime":
+			out.Uptime = string(in.String())
+		case "goVersion":
+			out.GoVersion = string(in.String())
+		case "goroutines":
+			out.Goroutines = int(in.Int())
+		case "schemaVersion":
+			out.SchemaVersion = int(in.Int())
+		case "pool":
+			easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels20(in, &out.Pool)
+		case "statements":
+			if in.IsNull() {
+				in.Skip()
+				out.Statements = nil
+			} else {
+				in.Delim('[')
+				if out.Statements == nil {
+					if !in.IsDelim(']') {
+						out.Statements = make([]string, 0, 4)
+					} else {
+						out.Statements = []string{}
+					}
+				} else {
+					out.Statements = (out.Statements)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v40 string
+					v40 = string(in.String())
+					out.Statements = append(out.Statements, v40)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "statementErrors":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				out.StatementErrors = make(map[string]string)
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v41 string
+					v41 = string(in.String())
+					(out.StatementErrors)[key] = v41
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "databaseSize":
+			out.DatabaseSize = int64(in.Int64())
+		case "tables":
+			if in.IsNull() {
+				in.Skip()
+				out.Tables = nil
+			} else {
+				in.Delim('[')
+				if out.Tables == nil {
+					if !in.IsDelim(']') {
+						out.Tables = make([]TableStat, 0, 1)
+					} else {
+						out.Tables = []TableStat{}
+					}
+				} else {
+					out.Tables = (out.Tables)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v42 TableStat
+					easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels21(in, &v42)
+					out.Tables = append(out.Tables, v42)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -431,77 +2429,120 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(in
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels4(out *jwriter.Writer, in Post) {
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels19(out *jwriter.Writer, in Diagnostics) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"id\":"
+		const prefix string = ",\"uptime\":"
 		out.RawString(prefix[1:])
-		out.Int(int(in.Id))
+		out.String(string(in.Uptime))
 	}
 	{
-		const prefix string = ",\"author\":"
+		const prefix string = ",\"goVersion\":"
 		out.RawString(prefix)
-		out.String(string(in.AuthorNick))
+		out.String(string(in.GoVersion))
 	}
 	{
-		const prefix string = ",\"parent\":"
+		const prefix string = ",\"goroutines\":"
 		out.RawString(prefix)
-		out.Int(int(in.ParentId))
+		out.Int(int(in.Goroutines))
 	}
 	{
-		const prefix string = ",\"message\":"
+		const prefix string = ",\"schemaVersion\":"
 		out.RawString(prefix)
-		out.String(string(in.Message))
+		out.Int(int(in.SchemaVersion))
 	}
 	{
-		const prefix string = ",\"isEdited\":"
+		const prefix string = ",\"pool\":"
 		out.RawString(prefix)
-		out.Bool(bool(in.IsEdited))
+		easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels20(out, in.Pool)
 	}
 	{
-		const prefix string = ",\"forum\":"
+		const prefix string = ",\"statements\":"
 		out.RawString(prefix)
-		out.String(string(in.ForumSlug))
+		if in.Statements == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v43, v44 := range in.Statements {
+				if v43 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v44))
+			}
+			out.RawByte(']')
+		}
 	}
 	{
-		const prefix string = ",\"thread\":"
+		const prefix string = ",\"statementErrors\":"
 		out.RawString(prefix)
-		out.Int(int(in.ThreadId))
+		if in.StatementErrors == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v45First := true
+			for v45Name, v45Value := range in.StatementErrors {
+				if v45First {
+					v45First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v45Name))
+				out.RawByte(':')
+				out.String(string(v45Value))
+			}
+			out.RawByte('}')
+		}
 	}
 	{
-		const prefix string = ",\"created\":"
+		const prefix string = ",\"databaseSize\":"
 		out.RawString(prefix)
-		out.String(string(in.Created))
+		out.Int64(int64(in.DatabaseSize))
+	}
+	{
+		const prefix string = ",\"tables\":"
+		out.RawString(prefix)
+		if in.Tables == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v46, v47 := range in.Tables {
+				if v46 > 0 {
+					out.RawByte(',')
+				}
+				easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels21(out, v47)
+			}
+			out.RawByte(']')
+		}
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v Post) MarshalJSON() ([]byte, error) {
+func (v Diagnostics) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels4(&w, v)
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels19(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v Post) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels4(w, v)
+func (v Diagnostics) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels19(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *Post) UnmarshalJSON(data []byte) error {
+func (v *Diagnostics) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(&r, v)
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels19(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *Post) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels4(l, v)
+func (v *Diagnostics) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels19(l, v)
 }
-func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(in *jlexer.Lexer, out *Forum) {
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels21(in *jlexer.Lexer, out *TableStat) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -520,16 +2561,14 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(in
 			continue
 		}
 		switch key {
-		case "slug":
-			out.Slug = string(in.String())
-		case "title":
-			out.Title = string(in.String())
-		case "user":
-			out.UserNick = string(in.String())
-		case "posts":
-			out.Posts = int(in.Int())
-		case "threads":
-			out.Threads = int(in.Int())
+		case "name":
+			out.Name = string(in.String())
+		case "liveRows":
+			out.LiveRows = int64(in.Int64())
+		case "deadRows":
+			out.DeadRows = int64(in.Int64())
+		case "size":
+			out.Size = int64(in.Int64())
 		default:
 			in.SkipRecursive()
 		}
@@ -540,58 +2579,203 @@ func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(in
 		in.Consumed()
 	}
 }
-func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels5(out *jwriter.Writer, in Forum) {
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels21(out *jwriter.Writer, in TableStat) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
-		const prefix string = ",\"slug\":"
+		const prefix string = ",\"name\":"
 		out.RawString(prefix[1:])
-		out.String(string(in.Slug))
+		out.String(string(in.Name))
 	}
 	{
-		const prefix string = ",\"title\":"
+		const prefix string = ",\"liveRows\":"
 		out.RawString(prefix)
-		out.String(string(in.Title))
+		out.Int64(int64(in.LiveRows))
 	}
 	{
-		const prefix string = ",\"user\":"
+		const prefix string = ",\"deadRows\":"
 		out.RawString(prefix)
-		out.String(string(in.UserNick))
+		out.Int64(int64(in.DeadRows))
 	}
 	{
-		const prefix string = ",\"posts\":"
+		const prefix string = ",\"size\":"
 		out.RawString(prefix)
-		out.Int(int(in.Posts))
+		out.Int64(int64(in.Size))
+	}
+	out.RawByte('}')
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels20(in *jlexer.Lexer, out *PoolStat) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "max":
+			out.MaxConnections = int(in.Int())
+		case "current":
+			out.CurrentConnections = int(in.Int())
+		case "available":
+			out.AvailableConnections = int(in.Int())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
 	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels20(out *jwriter.Writer, in PoolStat) {
+	out.RawByte('{')
+	first := true
+	_ = first
 	{
-		const prefix string = ",\"threads\":"
+		const prefix string = ",\"max\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.MaxConnections))
+	}
+	{
+		const prefix string = ",\"current\":"
 		out.RawString(prefix)
-		out.Int(int(in.Threads))
+		out.Int(int(in.CurrentConnections))
+	}
+	{
+		const prefix string = ",\"available\":"
+		out.RawString(prefix)
+		out.Int(int(in.AvailableConnections))
+	}
+	out.RawByte('}')
+}
+func easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels22(in *jlexer.Lexer, out *BulkUserResult) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "status":
+			out.Status = int(in.Int())
+		case "user":
+			if in.IsNull() {
+				in.Skip()
+				out.User = nil
+			} else {
+				if out.User == nil {
+					out.User = new(User)
+				}
+				(*out.User).UnmarshalEasyJSON(in)
+			}
+		case "existing":
+			if in.IsNull() {
+				in.Skip()
+				out.Existing = nil
+			} else {
+				in.Delim('[')
+				if out.Existing == nil {
+					if !in.IsDelim(']') {
+						out.Existing = make([]User, 0, 1)
+					} else {
+						out.Existing = []User{}
+					}
+				} else {
+					out.Existing = (out.Existing)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v48 User
+					(v48).UnmarshalEasyJSON(in)
+					out.Existing = append(out.Existing, v48)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels22(out *jwriter.Writer, in BulkUserResult) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"status\":"
+		out.RawString(prefix[1:])
+		out.Int(int(in.Status))
+	}
+	if in.User != nil {
+		const prefix string = ",\"user\":"
+		out.RawString(prefix)
+		(*in.User).MarshalEasyJSON(out)
+	}
+	if len(in.Existing) != 0 {
+		const prefix string = ",\"existing\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v49, v50 := range in.Existing {
+				if v49 > 0 {
+					out.RawByte(',')
+				}
+				(v50).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
 	}
 	out.RawByte('}')
 }
 
 // MarshalJSON supports json.Marshaler interface
-func (v Forum) MarshalJSON() ([]byte, error) {
+func (v BulkUserResult) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels5(&w, v)
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels22(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
-func (v Forum) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels5(w, v)
+func (v BulkUserResult) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD2b7633eEncodeGithubComNataliSkvTechnoparkDbForumInternalModels22(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
-func (v *Forum) UnmarshalJSON(data []byte) error {
+func (v *BulkUserResult) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(&r, v)
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels22(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *Forum) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels5(l, v)
+func (v *BulkUserResult) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD2b7633eDecodeGithubComNataliSkvTechnoparkDbForumInternalModels22(l, v)
 }
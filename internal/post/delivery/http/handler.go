@@ -7,19 +7,31 @@ import (
 
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
 	postRepo "github.com/Natali-Skv/technopark_db_forum/internal/post"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
 	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
-	"github.com/jackc/pgx"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
 const (
-	SlugOrIdCtxKey     = "slug"
-	IdCtxKey           = "id"
-	DescSortQueryParam = "desc"
-	SinceQueryParam    = "since"
-	LimitQueryParam    = "limit"
-	SortQueryParam     = "sort"
-	RelatedQueryParam  = "related"
+	SlugOrIdCtxKey       = "slug"
+	IdCtxKey             = "id"
+	VoterCtxKey          = "nickname"
+	ReactionCtxKey       = "reaction"
+	DescSortQueryParam   = "desc"
+	SinceQueryParam      = "since"
+	LimitQueryParam      = "limit"
+	SortQueryParam       = "sort"
+	RelatedQueryParam    = "related"
+	DepthQueryParam      = "depth"
+	SiblingsQueryParam   = "siblings"
+	MaxDepthQueryParam   = "max_depth"
+	ChildLimitQueryParam = "child_limit"
+	NestedQueryParam     = "nested"
+	// defaultSiblings is how many siblings on each side GetContext returns without the siblings parameter
+	defaultSiblings = 5
 )
 
 type Handler struct {
@@ -34,25 +46,26 @@ func (h *Handler) CreatePost(ctx echo.Context) error {
 	if err := ctx.Bind(&posts); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
+	if err := validate.Posts(posts); err != nil {
+		return validate.HTTPError(err)
+	}
 	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
 	threadId, _ := strconv.Atoi(threadSlugOrId)
 
-	newPost, err := h.Repo.Create(threadSlugOrId, int(threadId), posts)
+	newPost, err := h.Repo.Create(ctx.Request().Context(), threadSlugOrId, int(threadId), posts)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+threadSlugOrId+strconv.Itoa(int(threadId)))
 		}
-		if pgerr, converted := err.(pgx.PgError); converted {
-			if pgerr.Code == "AAAA0" {
+		if code := dbconn.ErrorCode(err); code != "" {
+			if code == "AAAA0" {
 				return echo.NewHTTPError(http.StatusConflict, errors.NO_PARENT_POST)
 			}
-			if pgerr.Code == "AAAA1" {
+			if code == "AAAA1" {
 				return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_POST_AUTHOR_BY_NICK+posts[0].AuthorNick)
 			}
-		} else {
-
-			return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
 		}
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusCreated, newPost)
 }
@@ -67,15 +80,21 @@ func (h *Handler) GetThreadPosts(ctx echo.Context) error {
 		desc = true
 	}
 	limit, _ := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
-	posts, err := h.Repo.GetThreadPosts(threadSlugOrId, int(threadId), desc, limit, int(since), sort)
+	maxDepth, _ := strconv.Atoi(ctx.QueryParam(MaxDepthQueryParam))
+	childLimit, _ := strconv.Atoi(ctx.QueryParam(ChildLimitQueryParam))
+	related := strings.Split(ctx.QueryParam(RelatedQueryParam), ",")
+	posts, err := h.Repo.GetThreadPosts(ctx.Request().Context(), threadSlugOrId, int(threadId), desc, limit, int(since), sort, maxDepth, childLimit, related)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	if len(posts) == 0 {
-		if exists, err := h.Repo.CheckThreadBySlugOrId(threadSlugOrId, int(threadId)); !exists && err == nil {
+		if exists, err := h.Repo.CheckThreadBySlugOrId(ctx.Request().Context(), threadSlugOrId, int(threadId)); !exists && err == nil {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+threadSlugOrId+strconv.Itoa(int(threadId)))
 		}
 	}
+	if ctx.QueryParam(NestedQueryParam) == "true" {
+		posts = postRepo.Nest(posts)
+	}
 	return ctx.JSON(http.StatusOK, posts)
 }
 
@@ -84,12 +103,12 @@ func (h *Handler) GetPost(ctx echo.Context) error {
 	related := ctx.QueryParam(RelatedQueryParam)
 	relatedArray := strings.Split(related, ",")
 
-	post, user, forum, thread, err := h.Repo.GetPostByIdRelated(int(id), relatedArray)
+	post, user, forum, thread, err := h.Repo.GetPostByIdRelated(ctx.Request().Context(), int(id), relatedArray)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(id))
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	if post == nil {
 		return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(id))
@@ -108,13 +127,162 @@ func (h *Handler) UpdatePost(ctx echo.Context) error {
 	if err := ctx.Bind(post); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
+	if err := validate.PostUpdate(post); err != nil {
+		return validate.HTTPError(err)
+	}
 
-	postResp, err := h.Repo.UpdatePost(post)
+	postResp, err := h.Repo.UpdatePost(ctx.Request().Context(), post)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(post.Id))
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, postResp)
 }
+
+// Vote sets the voice of the user for the post, replacing the one they gave before.
+func (h *Handler) Vote(ctx echo.Context) error {
+	vote := &models.PostVote{}
+	if err := ctx.Bind(vote); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
+	}
+	if err := validate.PostVote(vote); err != nil {
+		return validate.HTTPError(err)
+	}
+	vote.PostId, _ = strconv.Atoi(ctx.Param(IdCtxKey))
+	post, err := h.Repo.Vote(ctx.Request().Context(), vote)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(vote.PostId))
+		}
+		switch dbconn.ErrorCode(err) {
+		case "23502", "23503":
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(vote.PostId))
+		case "AAAA1":
+			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+vote.Nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, post)
+}
+
+// Unvote retracts the voice of the user for the post; the post is returned unchanged if they did not vote.
+func (h *Handler) Unvote(ctx echo.Context) error {
+	vote := &models.PostVote{Nick: ctx.Param(VoterCtxKey)}
+	vote.PostId, _ = strconv.Atoi(ctx.Param(IdCtxKey))
+	post, err := h.Repo.Unvote(ctx.Request().Context(), vote)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(vote.PostId))
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, post)
+}
+
+// GetVotes lists who voted for the post and how, ordered by nickname.
+func (h *Handler) GetVotes(ctx echo.Context) error {
+	id, _ := strconv.Atoi(ctx.Param(IdCtxKey))
+	limit, _ := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
+	since := ctx.QueryParam(SinceQueryParam)
+	desc := ctx.QueryParam(DescSortQueryParam) == "true"
+
+	votes, err := h.Repo.GetVotes(ctx.Request().Context(), id, desc, limit, since)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(id))
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, votes)
+}
+
+// React adds a reaction of the user to the post and returns the post with the reaction counts.
+func (h *Handler) React(ctx echo.Context) error {
+	reaction := &models.Reaction{}
+	if err := ctx.Bind(reaction); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
+	}
+	if err := validate.Reaction(reaction); err != nil {
+		return validate.HTTPError(err)
+	}
+	reaction.PostId, _ = strconv.Atoi(ctx.Param(IdCtxKey))
+	post, err := h.Repo.React(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(reaction.PostId))
+		}
+		switch dbconn.ErrorCode(err) {
+		case "23502", "23503":
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(reaction.PostId))
+		case "AAAA1":
+			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+reaction.Nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, post)
+}
+
+// Unreact removes a reaction of the user from the post; the post is returned unchanged if they did not react so.
+func (h *Handler) Unreact(ctx echo.Context) error {
+	reaction := &models.Reaction{Nick: ctx.Param(VoterCtxKey), Reaction: ctx.Param(ReactionCtxKey)}
+	reaction.PostId, _ = strconv.Atoi(ctx.Param(IdCtxKey))
+	post, err := h.Repo.Unreact(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(reaction.PostId))
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, post)
+}
+
+// GetSubtree lists the replies under the post in tree order, optionally cut at depth levels below it.
+func (h *Handler) GetSubtree(ctx echo.Context) error {
+	id, _ := strconv.Atoi(ctx.Param(IdCtxKey))
+	depth, _ := strconv.Atoi(ctx.QueryParam(DepthQueryParam))
+	limit, _ := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
+	since, _ := strconv.Atoi(ctx.QueryParam(SinceQueryParam))
+
+	posts, err := h.Repo.GetSubtree(ctx.Request().Context(), id, depth, limit, since)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(id))
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, posts)
+}
+
+// GetAncestors lists the posts from the root of the tree down to the parent of the post.
+func (h *Handler) GetAncestors(ctx echo.Context) error {
+	id, _ := strconv.Atoi(ctx.Param(IdCtxKey))
+
+	posts, err := h.Repo.GetAncestors(ctx.Request().Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(id))
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, posts)
+}
+
+// GetContext returns the post with its ancestors and the neighbouring replies to the same parent.
+func (h *Handler) GetContext(ctx echo.Context) error {
+	id, _ := strconv.Atoi(ctx.Param(IdCtxKey))
+	siblings, err := validate.Siblings(ctx.QueryParam(SiblingsQueryParam), defaultSiblings)
+	if err != nil {
+		return validate.HTTPError(err)
+	}
+
+	postContext, err := h.Repo.GetContext(ctx.Request().Context(), id, siblings)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_POST+strconv.Itoa(id))
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, postContext)
+}
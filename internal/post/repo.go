@@ -1,13 +1,36 @@
 package forum
 
 import (
+	"context"
+
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
 )
 
 type Repo interface {
-	Create(threadSlug string, threadId int, posts []models.Post) ([]models.Post, error)
-	GetThreadPosts(threadSlug string, threadId int, desc bool, limit int, since int, sort string) ([]models.Post, error)
-	CheckThreadBySlugOrId(slug string, id int) (bool, error)
-	GetPostByIdRelated(id int, related []string) (*models.Post, *models.User, *models.Forum, *models.Thread, error)
-	UpdatePost(post *models.Post) (*models.Post, error)
+	Create(ctx context.Context, threadSlug string, threadId int, posts []models.Post) ([]models.Post, error)
+	// GetThreadPosts counts the reactions to the posts of the page if related has "reactions".
+	// Unless 0, maxDepth keeps the posts less than that many levels deep and childLimit the first that many
+	// replies to each post; the replies left out under a post are counted in its MoreReplies. The first replies are
+	// the first stored, which for concurrent replies may not follow the id and created order.
+	GetThreadPosts(ctx context.Context, threadSlug string, threadId int, desc bool, limit int, since int, sort string, maxDepth int, childLimit int, related []string) ([]models.Post, error)
+	CheckThreadBySlugOrId(ctx context.Context, slug string, id int) (bool, error)
+	GetPostByIdRelated(ctx context.Context, id int, related []string) (*models.Post, *models.User, *models.Forum, *models.Thread, error)
+	UpdatePost(ctx context.Context, post *models.Post) (*models.Post, error)
+	// Vote sets the voice of vote.Nick on the post, replacing the one they gave before.
+	Vote(ctx context.Context, vote *models.PostVote) (*models.Post, error)
+	// Unvote retracts the vote of vote.Nick on the post; it is a no-op if they did not vote.
+	Unvote(ctx context.Context, vote *models.PostVote) (*models.Post, error)
+	// GetVotes lists the votes of the post by nickname, since is the nickname of the last vote of the previous page.
+	GetVotes(ctx context.Context, id int, desc bool, limit int, since string) ([]models.PostVote, error)
+	// React adds the reaction of reaction.Nick to the post; adding it again is a no-op.
+	React(ctx context.Context, reaction *models.Reaction) (*models.Post, error)
+	// Unreact removes the reaction of reaction.Nick from the post; it is a no-op if they did not react so.
+	Unreact(ctx context.Context, reaction *models.Reaction) (*models.Post, error)
+	// GetSubtree lists the replies under the post in tree order, at most depth levels below it unless depth is 0;
+	// since is the id of the last post of the previous page.
+	GetSubtree(ctx context.Context, id int, depth int, limit int, since int) ([]models.Post, error)
+	// GetAncestors lists the chain of posts the post answers, from the root of its tree down to its parent.
+	GetAncestors(ctx context.Context, id int) ([]models.Post, error)
+	// GetContext returns the post with its ancestors and up to siblings replies to the same parent on each side of it.
+	GetContext(ctx context.Context, id int, siblings int) (*models.PostContext, error)
 }
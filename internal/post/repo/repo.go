@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	goErrors "errors"
 	"fmt"
@@ -8,57 +9,74 @@ import (
 	"time"
 
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	postRepo "github.com/Natali-Skv/technopark_db_forum/internal/post"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
 	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
 	"github.com/go-openapi/strfmt"
-	"github.com/jackc/pgx"
 )
 
 type Repo struct {
-	Conn *pgx.ConnPool
+	Conn *dbconn.Pool
 }
 
 const (
 	userRelated   = "user"
 	threadRelated = "thread"
 	forumRelated  = "forum"
-	maxPostCount  = 1500000
-	maxPostCount2 = 1502556
+	// reactionsRelated counts the reactions to the posts instead of joining another object.
+	reactionsRelated = "reactions"
 )
 
-var postCount = 0
-
-func NewRepo(conn *pgx.ConnPool) *Repo {
-	conn.Prepare("get_forum_and_thread_by_slug", "SELECT forum_slug, forum_id, id FROM threads WHERE slug=$1")
-	conn.Prepare("get_forum_and_thread_by_id", "SELECT forum_slug, forum_id, id FROM threads WHERE id=$1")
-	conn.Prepare("get_thread_posts_flat", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE ($1!=0 AND thread_id = $2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR id>$6) ORDER BY created,id  LIMIT NULLIF($7,0)")
-	conn.Prepare("get_thread_posts_flat_desc", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE ($1!=0 AND thread_id = $2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR id<$6) ORDER BY created DESC,id DESC LIMIT NULLIF($7,0)")
-	conn.Prepare("get_thread_posts_tree", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path > (SELECT path FROM posts WHERE id=$6)) ORDER BY path ASC LIMIT NULLIF($7,0)")
-	conn.Prepare("get_thread_posts_tree_desc", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path < (SELECT path FROM posts WHERE id=$6)) ORDER BY path DESC LIMIT NULLIF($7,0)")
-	conn.Prepare("get_thread_posts_parent_tree", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path > (SELECT path FROM posts WHERE id=$6)) ORDER BY path ASC LIMIT NULLIF($7,0)")
-	conn.Prepare("get_thread_posts_parent_tree_desc", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path < (SELECT path FROM posts WHERE id=$6)) ORDER BY path DESC LIMIT NULLIF($7,0)")
-	conn.Prepare("get_thread_posts_parent_tree_desc_limit", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM (SELECT id, parent_id, path, author_nick, forum_slug, thread_id, message, created, is_edited, dense_rank() OVER(ORDER BY path[1] DESC) FROM posts WHERE ($1 != 0 AND thread_id = $2 OR $3 != '' AND thread_id = (SELECT id FROM threads WHERE slug=$4)) AND ($5=0 OR path[1] < (SELECT path[1] FROM posts WHERE id=$6))) t WHERE dense_rank<=$7 ORDER BY path[1] desc, path")
-	conn.Prepare("get_thread_posts_parent_tree_limit", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM (SELECT id, parent_id, path, author_nick, forum_slug, thread_id, message, created, is_edited, dense_rank() OVER(ORDER BY path[1]) FROM posts WHERE ($1 != 0 AND thread_id = $2 OR $3 != '' AND thread_id = (SELECT id FROM threads WHERE slug=$4)) AND ($5=0 OR path[1] > (SELECT path[1] FROM posts WHERE id=$6))) t WHERE dense_rank<=$7 ORDER BY path")
-	conn.Prepare("check_exists_thread", "SELECT exists(SELECT 1 FROM threads WHERE slug =$1 OR id=$2)")
-	conn.Prepare("update_post", "UPDATE posts SET message=COALESCE(NULLIF($1, ''), message), is_edited=true WHERE id=$2 RETURNING id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited;")
-	conn.Prepare("get_post", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited FROM posts WHERE id=$1")
-	conn.Prepare("get_post_user", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, u.name, u.nick, u.email, u.about FROM posts p JOIN users u ON p.author_id = u.id WHERE p.id=$1")
-	conn.Prepare("get_post_thread", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created FROM posts p JOIN threads t ON p.thread_id = t.id WHERE p.id=$1")
-	conn.Prepare("get_post_user_thread", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, u.name, u.nick, u.email, u.about, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created FROM posts p JOIN threads t ON p.thread_id = t.id JOIN users u ON p.author_id = u.id WHERE p.id=$1")
-	conn.Prepare("get_post_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN forums f ON p.forum_id = f.id WHERE p.id=$1")
-	conn.Prepare("get_post_user_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, u.name, u.nick, u.email, u.about, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN forums f ON p.forum_id = f.id JOIN users u ON p.author_id = u.id WHERE p.id=$1")
-	conn.Prepare("get_post_thread_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN threads t ON p.thread_id = t.id JOIN forums f ON p.forum_id = f.id WHERE p.id=$1")
-	conn.Prepare("get_post_user_thread_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, u.name, u.nick, u.email, u.about, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN users u ON p.author_id = u.id JOIN threads t ON p.thread_id = t.id JOIN forums f ON p.forum_id = f.id WHERE p.id=$1")
+func NewRepo(conn *dbconn.Pool) *Repo {
+	conn.Register("get_forum_and_thread_by_slug", "SELECT forum_slug, forum_id, id, slug FROM threads WHERE slug=$1")
+	conn.Register("get_forum_and_thread_by_id", "SELECT forum_slug, forum_id, id, slug FROM threads WHERE id=$1")
+	// post_descendants counts a subtree, so the listings pick their page first and count only for its posts
+	conn.Register("get_thread_posts_flat", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, path FROM posts WHERE ($1!=0 AND thread_id = $2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR id>$6) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11) ORDER BY created,id  LIMIT NULLIF($7,0)) p ORDER BY created,id")
+	conn.Register("get_thread_posts_flat_desc", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, path FROM posts WHERE ($1!=0 AND thread_id = $2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR id<$6) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11) ORDER BY created DESC,id DESC LIMIT NULLIF($7,0)) p ORDER BY created DESC,id DESC")
+	conn.Register("get_thread_posts_tree", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, path FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path > (SELECT path FROM posts WHERE id=$6)) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11) ORDER BY path ASC LIMIT NULLIF($7,0)) p ORDER BY path ASC")
+	conn.Register("get_thread_posts_tree_desc", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, path FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path < (SELECT path FROM posts WHERE id=$6)) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11) ORDER BY path DESC LIMIT NULLIF($7,0)) p ORDER BY path DESC")
+	conn.Register("get_thread_posts_parent_tree", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, path FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path > (SELECT path FROM posts WHERE id=$6)) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11) ORDER BY path ASC LIMIT NULLIF($7,0)) p ORDER BY path ASC")
+	conn.Register("get_thread_posts_parent_tree_desc", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, path FROM posts WHERE ($1!=0 AND thread_id=$2 OR ($3 != '') AND thread_slug=$4) AND ($5=0 OR path < (SELECT path FROM posts WHERE id=$6)) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11) ORDER BY path DESC LIMIT NULLIF($7,0)) p ORDER BY path DESC")
+	conn.Register("get_thread_posts_parent_tree_desc_limit", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, path, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, dense_rank() OVER(ORDER BY path[1] DESC) FROM posts WHERE ($1 != 0 AND thread_id = $2 OR $3 != '' AND thread_id = (SELECT id FROM threads WHERE slug=$4)) AND ($5=0 OR path[1] < (SELECT path[1] FROM posts WHERE id=$6)) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11)) t WHERE ($7=0 OR dense_rank<=$7) ORDER BY path[1] desc, path")
+	conn.Register("get_thread_posts_parent_tree_limit", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT id, parent_id, path, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, dense_rank() OVER(ORDER BY path[1]) FROM posts WHERE ($1 != 0 AND thread_id = $2 OR $3 != '' AND thread_id = (SELECT id FROM threads WHERE slug=$4)) AND ($5=0 OR path[1] > (SELECT path[1] FROM posts WHERE id=$6)) AND ($8=0 OR array_length(path, 1) <= $9) AND ($10=0 OR branch_rank <= $11)) t WHERE ($7=0 OR dense_rank<=$7) ORDER BY path")
+	conn.Register("check_exists_thread", "SELECT exists(SELECT 1 FROM threads WHERE slug =$1 OR id=$2)")
+	conn.Register("update_post", "UPDATE posts SET message=COALESCE(NULLIF($1, ''), message), is_edited=true WHERE id=$2 RETURNING id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1;")
+	conn.Register("get_post", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM posts WHERE id=$1")
+	conn.Register("get_post_user", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, u.name, u.nick, u.email, u.about FROM posts p JOIN users u ON p.author_id = u.id WHERE p.id=$1")
+	conn.Register("get_post_thread", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created, t.posts_count, CASE WHEN t.posts_count>0 THEN t.last_post_at END, COALESCE(t.last_post_author, ''), t.participants_count FROM posts p JOIN threads t ON p.thread_id = t.id WHERE p.id=$1")
+	conn.Register("get_post_user_thread", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, u.name, u.nick, u.email, u.about, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created, t.posts_count, CASE WHEN t.posts_count>0 THEN t.last_post_at END, COALESCE(t.last_post_author, ''), t.participants_count FROM posts p JOIN threads t ON p.thread_id = t.id JOIN users u ON p.author_id = u.id WHERE p.id=$1")
+	conn.Register("get_post_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN forums f ON p.forum_id = f.id WHERE p.id=$1")
+	conn.Register("get_post_user_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, u.name, u.nick, u.email, u.about, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN forums f ON p.forum_id = f.id JOIN users u ON p.author_id = u.id WHERE p.id=$1")
+	conn.Register("get_post_thread_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created, t.posts_count, CASE WHEN t.posts_count>0 THEN t.last_post_at END, COALESCE(t.last_post_author, ''), t.participants_count, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN threads t ON p.thread_id = t.id JOIN forums f ON p.forum_id = f.id WHERE p.id=$1")
+	conn.Register("get_post_user_thread_forum", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1, u.name, u.nick, u.email, u.about, t.id, t.slug, t.title, t.author_nick, t.forum_slug, t.message, t.votes, t.created, t.posts_count, CASE WHEN t.posts_count>0 THEN t.last_post_at END, COALESCE(t.last_post_author, ''), t.participants_count, f.slug, f.title, f.posts, f.threads, f.author_nick FROM posts p JOIN users u ON p.author_id = u.id JOIN threads t ON p.thread_id = t.id JOIN forums f ON p.forum_id = f.id WHERE p.id=$1")
+	conn.Register("vote_post", "INSERT INTO post_votes(user_nick, post_id, vote) VALUES ($1,$2,$3) ON CONFLICT(user_nick, post_id) DO UPDATE SET vote=$4")
+	conn.Register("unvote_post", "DELETE FROM post_votes WHERE user_nick=$1 AND post_id=$2")
+	conn.Register("get_post_votes", "SELECT user_nick, vote FROM post_votes WHERE post_id=$1 AND ($2='' OR user_nick>$3) ORDER BY user_nick LIMIT NULLIF($4,0)")
+	conn.Register("get_post_votes_desc", "SELECT user_nick, vote FROM post_votes WHERE post_id=$1 AND ($2='' OR user_nick<$3) ORDER BY user_nick DESC LIMIT NULLIF($4,0)")
+	conn.Register("react_post", "INSERT INTO post_reactions(user_nick, post_id, reaction) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING")
+	conn.Register("unreact_post", "DELETE FROM post_reactions WHERE user_nick=$1 AND post_id=$2 AND reaction=$3")
+	conn.Register("get_posts_reactions", "SELECT post_id, reaction, count(*) FROM post_reactions WHERE post_id = ANY($1::bigint[]) GROUP BY post_id, reaction")
+	// the posts under s share its path as a prefix, so they sort between s.path and s.path || max bigint
+	conn.Register("get_post_subtree", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, p.path FROM posts s JOIN posts p ON p.thread_id = s.thread_id AND p.path > s.path AND p.path < s.path || 9223372036854775807::bigint WHERE s.id=$1 AND ($2=0 OR array_length(p.path, 1) <= array_length(s.path, 1) + $3) AND ($4=0 OR p.path > (SELECT path FROM posts WHERE id=$5)) ORDER BY p.path LIMIT NULLIF($6,0)) p ORDER BY path")
+	conn.Register("get_post_ancestors", "SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, post_descendants(p.thread_id, p.path), array_length(p.path, 1) - 1 FROM posts s JOIN posts p ON p.id = ANY(s.path) AND p.id != s.id WHERE s.id=$1 ORDER BY p.path")
+	conn.Register("get_post_siblings_before", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, p.path FROM posts s JOIN posts p ON p.thread_id = s.thread_id AND p.path < s.path AND p.path > s.path[1:array_length(s.path, 1)-1] AND array_length(p.path, 1) = array_length(s.path, 1) WHERE s.id=$1 ORDER BY p.path DESC LIMIT $2) p ORDER BY path DESC")
+	conn.Register("get_post_siblings_after", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM (SELECT p.id, p.parent_id, p.author_nick, p.forum_slug, p.thread_id, p.message, p.created, p.is_edited, p.votes, p.children, p.path FROM posts s JOIN posts p ON p.thread_id = s.thread_id AND p.path > s.path AND p.path < s.path[1:array_length(s.path, 1)-1] || 9223372036854775807::bigint AND array_length(p.path, 1) = array_length(s.path, 1) WHERE s.id=$1 ORDER BY p.path LIMIT $2) p ORDER BY path")
 
 	return &Repo{Conn: conn}
 }
-func (r *Repo) Create(threadSlug string, threadId int, posts []models.Post) ([]models.Post, error) {
+func (r *Repo) Create(ctx context.Context, threadSlug string, threadId int, posts []models.Post) ([]models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.Create")
+	defer span.End()
 	var forumSlug string
 	var forumId int64
+	// the real thread slug, so that posts created by thread id are found by slug too
+	var storedSlug sql.NullString
 	var err error
 	if threadId != 0 {
-		err = r.Conn.QueryRow("EXECUTE get_forum_and_thread_by_id($1)", threadId).Scan(&forumSlug, &forumId, &threadId)
+		err = r.Conn.QueryRow(ctx, "get_forum_and_thread_by_id", threadId).Scan(&forumSlug, &forumId, &threadId, &storedSlug)
 	} else {
-		err = r.Conn.QueryRow("EXECUTE get_forum_and_thread_by_slug($1)", threadSlug).Scan(&forumSlug, &forumId, &threadId)
+		err = r.Conn.QueryRow(ctx, "get_forum_and_thread_by_slug", threadSlug).Scan(&forumSlug, &forumId, &threadId, &storedSlug)
 	}
 	if err != nil {
 		return nil, err
@@ -75,13 +93,13 @@ func (r *Repo) Create(threadSlug string, threadId int, posts []models.Post) ([]m
 	var post models.Post
 	for i, post = range posts[:len(posts)-1] {
 		fmt.Fprintf(&query, "($%d,$%d,$%d,$%d,$%d,$%d,$%d),", i*fieldCount+1, i*fieldCount+2, i*fieldCount+3, i*fieldCount+4, i*fieldCount+5, i*fieldCount+6, i*fieldCount+7)
-		args = append(args, post.AuthorNick, post.ParentId, post.Message, forumSlug, forumId, threadId, threadSlug)
+		args = append(args, post.AuthorNick, post.ParentId, post.Message, forumSlug, forumId, threadId, storedSlug)
 		i += 1
 	}
 	post = posts[len(posts)-1]
-	fmt.Fprintf(&query, "($%d,$%d,$%d,$%d,$%d,$%d,$%d) RETURNING id, author_nick, created;", i*fieldCount+1, i*fieldCount+2, i*fieldCount+3, i*fieldCount+4, i*fieldCount+5, i*fieldCount+6, i*fieldCount+7)
-	args = append(args, post.AuthorNick, post.ParentId, post.Message, forumSlug, forumId, threadId, threadSlug)
-	postRows, err := r.Conn.Query(query.String(), args...)
+	fmt.Fprintf(&query, "($%d,$%d,$%d,$%d,$%d,$%d,$%d) RETURNING id, author_nick, created, array_length(path, 1) - 1;", i*fieldCount+1, i*fieldCount+2, i*fieldCount+3, i*fieldCount+4, i*fieldCount+5, i*fieldCount+6, i*fieldCount+7)
+	args = append(args, post.AuthorNick, post.ParentId, post.Message, forumSlug, forumId, threadId, storedSlug)
+	postRows, err := r.Conn.Query(ctx, query.String(), args...)
 	defer postRows.Close()
 	if err != nil {
 		return nil, goErrors.New(errors.INTERNAL_SERVER_ERROR)
@@ -93,7 +111,7 @@ func (r *Repo) Create(threadSlug string, threadId int, posts []models.Post) ([]m
 		if err != nil {
 			return nil, err
 		}
-		scanErr := postRows.Scan(&posts[i].Id, &posts[i].AuthorNick, &created)
+		scanErr := postRows.Scan(&posts[i].Id, &posts[i].AuthorNick, &created, &posts[i].Depth)
 		posts[i].ForumSlug = forumSlug
 		posts[i].ThreadId = threadId
 		posts[i].Created = strfmt.DateTime(created.UTC()).String()
@@ -101,41 +119,80 @@ func (r *Repo) Create(threadSlug string, threadId int, posts []models.Post) ([]m
 			return nil, scanErr
 		}
 	}
-	postCount += len(posts)
-	if postCount == maxPostCount || postCount == maxPostCount2 {
-		r.Conn.Exec("CLUSTER users USING user_nick_idx")
-		r.Conn.Exec("CLUSTER forums USING forum_slug_idx;")
-		r.Conn.Exec("CLUSTER forum_users USING forum_users_idx;")
-		r.Conn.Exec("CLUSTER threads USING thread_forum_created_idx")
-		r.Conn.Exec("CLUSTER votes USING vote_full")
-		r.Conn.Exec("CLUSTER posts USING post_thread_idx")
-		r.Conn.Exec("SELECT pg_prewarm('forums')")
-		r.Conn.Exec("SELECT pg_prewarm('users')")
-		r.Conn.Exec("VACUUM ANALYZE")
-	}
 	return posts, nil
 }
 
-func (r *Repo) GetThreadPosts(threadSlug string, threadId int, desc bool, limit int, since int, sort string) ([]models.Post, error) {
+func (r *Repo) GetThreadPosts(ctx context.Context, threadSlug string, threadId int, desc bool, limit int, since int, sort string, maxDepth int, childLimit int, related []string) ([]models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.GetThreadPosts")
+	defer span.End()
+	var posts []models.Post
+	var err error
 	switch sort {
 	case "flat", "":
-		return r.getThreadPostsFlat(threadSlug, threadId, desc, limit, since)
+		posts, err = r.getThreadPostsFlat(ctx, threadSlug, threadId, desc, limit, since, maxDepth, childLimit)
 	case "tree":
-		return r.getThreadPostsTree(threadSlug, threadId, desc, limit, since)
+		posts, err = r.getThreadPostsTree(ctx, threadSlug, threadId, desc, limit, since, maxDepth, childLimit)
 	case "parent_tree":
-		return r.getThreadPostsParentTree(threadSlug, threadId, desc, limit, since)
+		posts, err = r.getThreadPostsParentTree(ctx, threadSlug, threadId, desc, limit, since, maxDepth, childLimit)
 	default:
 		return nil, goErrors.New(errors.UNKNOWN_SORT_TYPE)
 	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].MoreReplies = postRepo.MoreReplies(&posts[i], maxDepth, childLimit)
+	}
+	if !hasRelated(related, reactionsRelated) {
+		return posts, nil
+	}
+	return posts, r.countReactions(ctx, posts)
+}
+
+func hasRelated(related []string, name string) bool {
+	for _, relatedItem := range related {
+		if relatedItem == name {
+			return true
+		}
+	}
+	return false
+}
+
+// countReactions fills in the reaction counts of a page of posts with one query.
+func (r *Repo) countReactions(ctx context.Context, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(posts))
+	byId := make(map[int]*models.Post, len(posts))
+	for i := range posts {
+		posts[i].Reactions = map[string]int{}
+		ids = append(ids, posts[i].Id)
+		byId[posts[i].Id] = &posts[i]
+	}
+	rows, err := r.Conn.Query(ctx, "get_posts_reactions", ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, count int
+		var reaction string
+		if err = rows.Scan(&id, &reaction, &count); err != nil {
+			return err
+		}
+		byId[id].Reactions[reaction] = count
+	}
+	return rows.Err()
 }
 
-func (r *Repo) getThreadPostsFlat(threadSlug string, threadId int, desc bool, limit int, since int) ([]models.Post, error) {
-	var threadRows *pgx.Rows
+func (r *Repo) getThreadPostsFlat(ctx context.Context, threadSlug string, threadId int, desc bool, limit int, since int, maxDepth int, childLimit int) ([]models.Post, error) {
+	var threadRows *dbconn.Rows
 	var err error
 	if desc {
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_flat_desc($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
+		threadRows, err = r.Conn.Query(ctx, "get_thread_posts_flat_desc", threadId, threadId, threadSlug, threadSlug, since, since, limit, maxDepth, maxDepth, childLimit, childLimit)
 	} else {
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_flat($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
+		threadRows, err = r.Conn.Query(ctx, "get_thread_posts_flat", threadId, threadId, threadSlug, threadSlug, since, since, limit, maxDepth, maxDepth, childLimit, childLimit)
 	}
 
 	defer threadRows.Close()
@@ -147,7 +204,7 @@ func (r *Repo) getThreadPostsFlat(threadSlug string, threadId int, desc bool, li
 		post := models.Post{}
 		parentId := sql.NullInt64{}
 		var created time.Time
-		err = threadRows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited)
+		err = threadRows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth)
 		post.ParentId = int(parentId.Int64)
 		if err != nil {
 			return nil, err
@@ -158,14 +215,14 @@ func (r *Repo) getThreadPostsFlat(threadSlug string, threadId int, desc bool, li
 	return postsResp, nil
 }
 
-func (r *Repo) getThreadPostsTree(threadSlug string, threadId int, desc bool, limit int, since int) ([]models.Post, error) {
-	var threadRows *pgx.Rows
+func (r *Repo) getThreadPostsTree(ctx context.Context, threadSlug string, threadId int, desc bool, limit int, since int, maxDepth int, childLimit int) ([]models.Post, error) {
+	var threadRows *dbconn.Rows
 	var err error
 
 	if desc {
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_tree_desc($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
+		threadRows, err = r.Conn.Query(ctx, "get_thread_posts_tree_desc", threadId, threadId, threadSlug, threadSlug, since, since, limit, maxDepth, maxDepth, childLimit, childLimit)
 	} else {
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_tree($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
+		threadRows, err = r.Conn.Query(ctx, "get_thread_posts_tree", threadId, threadId, threadSlug, threadSlug, since, since, limit, maxDepth, maxDepth, childLimit, childLimit)
 	}
 	defer threadRows.Close()
 	if err != nil {
@@ -176,7 +233,7 @@ func (r *Repo) getThreadPostsTree(threadSlug string, threadId int, desc bool, li
 		post := models.Post{}
 		parentId := sql.NullInt64{}
 		var created time.Time
-		err = threadRows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited)
+		err = threadRows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth)
 		post.ParentId = int(parentId.Int64)
 		if err != nil {
 			return nil, err
@@ -187,19 +244,14 @@ func (r *Repo) getThreadPostsTree(threadSlug string, threadId int, desc bool, li
 	return postsResp, nil
 }
 
-func (r *Repo) getThreadPostsParentTree(threadSlug string, threadId int, desc bool, limit int, since int) ([]models.Post, error) {
-	var threadRows *pgx.Rows
+func (r *Repo) getThreadPostsParentTree(ctx context.Context, threadSlug string, threadId int, desc bool, limit int, since int, maxDepth int, childLimit int) ([]models.Post, error) {
+	var threadRows *dbconn.Rows
 	var err error
 
-	switch {
-	case limit == 0 && desc:
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_tree($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
-	case limit == 0 && !desc:
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_tree_desc($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
-	case limit != 0 && desc:
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_parent_tree_desc_limit($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
-	case limit != 0 && !desc:
-		threadRows, err = r.Conn.Query("EXECUTE get_thread_posts_parent_tree_limit($1,$2,$3,$4,$5,$6,$7)", threadId, threadId, threadSlug, threadSlug, since, since, limit)
+	if desc {
+		threadRows, err = r.Conn.Query(ctx, "get_thread_posts_parent_tree_desc_limit", threadId, threadId, threadSlug, threadSlug, since, since, limit, maxDepth, maxDepth, childLimit, childLimit)
+	} else {
+		threadRows, err = r.Conn.Query(ctx, "get_thread_posts_parent_tree_limit", threadId, threadId, threadSlug, threadSlug, since, since, limit, maxDepth, maxDepth, childLimit, childLimit)
 	}
 
 	defer threadRows.Close()
@@ -211,7 +263,7 @@ func (r *Repo) getThreadPostsParentTree(threadSlug string, threadId int, desc bo
 		post := models.Post{}
 		parentId := sql.NullInt64{}
 		var created time.Time
-		err = threadRows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited)
+		err = threadRows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth)
 		post.ParentId = int(parentId.Int64)
 		if err != nil {
 			return nil, err
@@ -222,13 +274,17 @@ func (r *Repo) getThreadPostsParentTree(threadSlug string, threadId int, desc bo
 	return postsResp, nil
 }
 
-func (r *Repo) CheckThreadBySlugOrId(slug string, id int) (bool, error) {
+func (r *Repo) CheckThreadBySlugOrId(ctx context.Context, slug string, id int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.CheckThreadBySlugOrId")
+	defer span.End()
 	var exists bool
-	err := r.Conn.QueryRow(`EXECUTE check_exists_thread($1,$2)`, slug, id).Scan(&exists)
+	err := r.Conn.QueryRow(ctx, "check_exists_thread", slug, id).Scan(&exists)
 	return exists, err
 }
 
-func (r *Repo) GetPostByIdRelated(id int, related []string) (*models.Post, *models.User, *models.Forum, *models.Thread, error) {
+func (r *Repo) GetPostByIdRelated(ctx context.Context, id int, related []string) (*models.Post, *models.User, *models.Forum, *models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.GetPostByIdRelated")
+	defer span.End()
 	post := &models.Post{}
 	var user *models.User
 	var forum *models.Forum
@@ -236,10 +292,11 @@ func (r *Repo) GetPostByIdRelated(id int, related []string) (*models.Post, *mode
 
 	var created time.Time
 	var threadCreated time.Time
+	var threadLastPostAt *time.Time
 	var threadSlug sql.NullString
 	parentId := sql.NullInt64{}
 
-	scanArgs := []interface{}{&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited}
+	scanArgs := []interface{}{&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth}
 
 	relatedMap := map[string]bool{}
 
@@ -254,7 +311,7 @@ func (r *Repo) GetPostByIdRelated(id int, related []string) (*models.Post, *mode
 		}
 	}
 
-	query := "EXECUTE get_post"
+	query := "get_post"
 
 	if relatedMap[userRelated] {
 		query += "_user"
@@ -264,14 +321,14 @@ func (r *Repo) GetPostByIdRelated(id int, related []string) (*models.Post, *mode
 	if relatedMap[threadRelated] {
 		query += "_thread"
 		thread = &models.Thread{}
-		scanArgs = append(scanArgs, &thread.Id, &threadSlug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &threadCreated)
+		scanArgs = append(scanArgs, &thread.Id, &threadSlug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &threadCreated, &thread.PostsCount, &threadLastPostAt, &thread.LastPostAuthor, &thread.ParticipantsCount)
 	}
 	if relatedMap[forumRelated] {
 		query += "_forum"
 		forum = &models.Forum{}
 		scanArgs = append(scanArgs, &forum.Slug, &forum.Title, &forum.Posts, &forum.Threads, &forum.UserNick)
 	}
-	err := r.Conn.QueryRow(query+"($1)", id).Scan(scanArgs...)
+	err := r.Conn.QueryRow(ctx, query, id).Scan(scanArgs...)
 
 	if err != nil {
 		return nil, nil, nil, nil, err
@@ -282,16 +339,28 @@ func (r *Repo) GetPostByIdRelated(id int, related []string) (*models.Post, *mode
 	if thread != nil {
 		thread.Created = strfmt.DateTime(threadCreated.UTC()).String()
 		thread.Slug = threadSlug.String
+		if threadLastPostAt != nil {
+			thread.LastPostAt = strfmt.DateTime(threadLastPostAt.UTC()).String()
+		}
+	}
+	if hasRelated(related, reactionsRelated) {
+		posts := []models.Post{*post}
+		if err = r.countReactions(ctx, posts); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		post = &posts[0]
 	}
 
 	return post, user, forum, thread, nil
 }
 
-func (r *Repo) UpdatePost(post *models.Post) (*models.Post, error) {
+func (r *Repo) UpdatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.UpdatePost")
+	defer span.End()
 	var created time.Time
 	parentId := sql.NullInt64{}
 
-	err := r.Conn.QueryRow(`EXECUTE update_post($1, $2)`, post.Message, post.Id).Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited)
+	err := r.Conn.QueryRow(ctx, "update_post", post.Message, post.Id).Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth)
 	post.Created = strfmt.DateTime(created.UTC()).String()
 	post.ParentId = int(parentId.Int64)
 	if err != nil {
@@ -299,3 +368,134 @@ func (r *Repo) UpdatePost(post *models.Post) (*models.Post, error) {
 	}
 	return post, nil
 }
+
+// Vote relies on the triggers of post_votes to keep posts.votes; an unknown post fails the foreign key.
+func (r *Repo) Vote(ctx context.Context, vote *models.PostVote) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.Vote")
+	defer span.End()
+	if _, err := r.Conn.Exec(ctx, "vote_post", vote.Nick, vote.PostId, vote.Voice, vote.Voice); err != nil {
+		return nil, err
+	}
+	post, _, _, _, err := r.GetPostByIdRelated(ctx, vote.PostId, nil)
+	return post, err
+}
+
+func (r *Repo) Unvote(ctx context.Context, vote *models.PostVote) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.Unvote")
+	defer span.End()
+	if _, err := r.Conn.Exec(ctx, "unvote_post", vote.Nick, vote.PostId); err != nil {
+		return nil, err
+	}
+	post, _, _, _, err := r.GetPostByIdRelated(ctx, vote.PostId, nil)
+	return post, err
+}
+
+func (r *Repo) GetVotes(ctx context.Context, id int, desc bool, limit int, since string) ([]models.PostVote, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.GetVotes")
+	defer span.End()
+	if _, _, _, _, err := r.GetPostByIdRelated(ctx, id, nil); err != nil {
+		return nil, err
+	}
+	query := "get_post_votes"
+	if desc {
+		query += "_desc"
+	}
+	rows, err := r.Conn.Query(ctx, query, id, since, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	votes := make([]models.PostVote, 0)
+	for rows.Next() {
+		vote := models.PostVote{PostId: id}
+		if err = rows.Scan(&vote.Nick, &vote.Voice); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+// React relies on the foreign key of post_reactions to reject an unknown post.
+func (r *Repo) React(ctx context.Context, reaction *models.Reaction) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.React")
+	defer span.End()
+	if _, err := r.Conn.Exec(ctx, "react_post", reaction.Nick, reaction.PostId, reaction.Reaction); err != nil {
+		return nil, err
+	}
+	post, _, _, _, err := r.GetPostByIdRelated(ctx, reaction.PostId, []string{reactionsRelated})
+	return post, err
+}
+
+func (r *Repo) Unreact(ctx context.Context, reaction *models.Reaction) (*models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.Unreact")
+	defer span.End()
+	if _, err := r.Conn.Exec(ctx, "unreact_post", reaction.Nick, reaction.PostId, reaction.Reaction); err != nil {
+		return nil, err
+	}
+	post, _, _, _, err := r.GetPostByIdRelated(ctx, reaction.PostId, []string{reactionsRelated})
+	return post, err
+}
+
+func (r *Repo) GetSubtree(ctx context.Context, id int, depth int, limit int, since int) ([]models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.GetSubtree")
+	defer span.End()
+	if _, _, _, _, err := r.GetPostByIdRelated(ctx, id, nil); err != nil {
+		return nil, err
+	}
+	return r.queryPosts(ctx, "get_post_subtree", id, depth, depth, since, since, limit)
+}
+
+func (r *Repo) GetAncestors(ctx context.Context, id int) ([]models.Post, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.GetAncestors")
+	defer span.End()
+	if _, _, _, _, err := r.GetPostByIdRelated(ctx, id, nil); err != nil {
+		return nil, err
+	}
+	return r.queryPosts(ctx, "get_post_ancestors", id)
+}
+
+func (r *Repo) GetContext(ctx context.Context, id int, siblings int) (*models.PostContext, error) {
+	ctx, span := tracing.Start(ctx, "postRepo.GetContext")
+	defer span.End()
+	post, _, _, _, err := r.GetPostByIdRelated(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	postContext := &models.PostContext{Post: *post}
+	if postContext.Ancestors, err = r.queryPosts(ctx, "get_post_ancestors", id); err != nil {
+		return nil, err
+	}
+	if postContext.Before, err = r.queryPosts(ctx, "get_post_siblings_before", id, siblings); err != nil {
+		return nil, err
+	}
+	// the siblings before are fetched nearest first
+	for i, j := 0, len(postContext.Before)-1; i < j; i, j = i+1, j-1 {
+		postContext.Before[i], postContext.Before[j] = postContext.Before[j], postContext.Before[i]
+	}
+	if postContext.After, err = r.queryPosts(ctx, "get_post_siblings_after", id, siblings); err != nil {
+		return nil, err
+	}
+	return postContext, nil
+}
+
+func (r *Repo) queryPosts(ctx context.Context, query string, args ...any) ([]models.Post, error) {
+	rows, err := r.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		post := models.Post{}
+		parentId := sql.NullInt64{}
+		var created time.Time
+		if err = rows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth); err != nil {
+			return nil, err
+		}
+		post.ParentId = int(parentId.Int64)
+		post.Created = strfmt.DateTime(created.UTC()).String()
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
@@ -0,0 +1,46 @@
+package forum
+
+import "github.com/Natali-Skv/technopark_db_forum/internal/models"
+
+// MoreReplies counts the direct replies to the post left out of a tree cut at maxDepth levels
+// and childLimit replies per post, where 0 does not cut.
+func MoreReplies(post *models.Post, maxDepth int, childLimit int) int {
+	if maxDepth > 0 && post.Depth+1 >= maxDepth {
+		return post.Children
+	}
+	if childLimit > 0 && post.Children > childLimit {
+		return post.Children - childLimit
+	}
+	return 0
+}
+
+// Nest turns a page of posts into trees: a post goes into the replies of its parent if the parent
+// is on the page and is at the top level otherwise; siblings keep the order of the page.
+func Nest(posts []models.Post) []models.Post {
+	onPage := make(map[int]bool, len(posts))
+	for i := range posts {
+		onPage[posts[i].Id] = true
+	}
+	top := make([]int, 0)
+	replies := make(map[int][]int)
+	for i := range posts {
+		if parentId := posts[i].ParentId; onPage[parentId] {
+			replies[parentId] = append(replies[parentId], i)
+		} else {
+			top = append(top, i)
+		}
+	}
+	var build func(indexes []int) []models.Post
+	build = func(indexes []int) []models.Post {
+		nested := make([]models.Post, 0, len(indexes))
+		for _, i := range indexes {
+			post := posts[i]
+			if len(replies[post.Id]) > 0 {
+				post.Replies = build(replies[post.Id])
+			}
+			nested = append(nested, post)
+		}
+		return nested
+	}
+	return build(top)
+}
@@ -1,33 +1,199 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
 	"github.com/Natali-Skv/technopark_db_forum/internal/service"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
 	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	SlugCtxKey         = "slug"
+	LimitQueryParam    = "limit"
+	defaultStatusLimit = 100
+	ConfirmClearHeader = "X-Confirm-Clear"
+
+	clearAllAction   = "clear_all"
+	clearVotesAction = "clear_votes"
+	clearForumAction = "clear_forum"
+	confirmAll       = "all"
+	confirmVotes     = "votes"
+
+	outcomeOk       = "ok"
+	outcomeDenied   = "denied"
+	outcomeNotFound = "not_found"
+	outcomeError    = "error"
+
+	statusOk   = "ok"
+	statusFail = "fail"
+
+	checkState      = "state"
+	checkDatabase   = "database"
+	checkSchema     = "schema"
+	checkStatements = "statements"
 )
 
 type Handler struct {
-	Repo service.Repo
+	Repo    service.Repo
+	started time.Time
+	ready   atomic.Bool
 }
 
 func NewHandler(repo service.Repo) *Handler {
-	return &Handler{Repo: repo}
+	return &Handler{Repo: repo, started: time.Now()}
+}
+
+// SetReady switches /readyz on once the server is fully initialised and off again on shutdown.
+func (h *Handler) SetReady(ready bool) {
+	h.ready.Store(ready)
 }
 
 func (h *Handler) Status(ctx echo.Context) error {
-	status, err := h.Repo.Status()
+	status, err := h.Repo.Status(ctx.Request().Context())
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, status)
 }
 
-func (h *Handler) ClearDB(ctx echo.Context) error {
-	err := h.Repo.TruncateDB()
+func (h *Handler) ForumsStatus(ctx echo.Context) error {
+	limit, err := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
+	if err != nil || limit <= 0 {
+		limit = defaultStatusLimit
+	}
+	forums, err := h.Repo.ForumsStatus(ctx.Request().Context(), limit)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, forums)
+}
+
+func (h *Handler) ClearDB(ctx echo.Context) error {
+	return h.clear(ctx, clearAllAction, "", confirmAll, h.Repo.TruncateDB)
+}
+
+func (h *Handler) ClearVotes(ctx echo.Context) error {
+	return h.clear(ctx, clearVotesAction, "", confirmVotes, h.Repo.ClearVotes)
+}
+
+func (h *Handler) ClearForum(ctx echo.Context) error {
+	slug := ctx.Param(SlugCtxKey)
+	return h.clear(ctx, clearForumAction, slug, slug, func(reqCtx context.Context) error {
+		return h.Repo.ClearForum(reqCtx, slug)
+	})
+}
+
+// clear runs a destructive action. Outside the test profile it has to be enabled in config and
+// confirmed by the X-Confirm-Clear header; every attempt ends up in the audit log.
+func (h *Handler) clear(ctx echo.Context, action string, target string, confirmation string, run func(context.Context) error) error {
+	event := &models.AuditEvent{
+		Action:     action,
+		Target:     target,
+		RequestId:  ctx.Response().Header().Get(echo.HeaderXRequestID),
+		RemoteAddr: ctx.RealIP(),
+	}
+	httpErr := h.checkClearAllowed(ctx, confirmation)
+	if httpErr == nil {
+		err := run(ctx.Request().Context())
+		switch {
+		case err == nil:
+			event.Outcome = outcomeOk
+		case err == pgx.ErrNoRows:
+			event.Outcome = outcomeNotFound
+			httpErr = echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_FORUM+target)
+		default:
+			event.Outcome = outcomeError
+			httpErr = deadline.HTTPError(err)
+		}
+	} else {
+		event.Outcome = outcomeDenied
+	}
+
+	if err := h.Repo.Audit(ctx.Request().Context(), event); err != nil {
+		zerolog.Ctx(ctx.Request().Context()).Error().Err(err).Str("action", action).Str("target", target).Msg("write audit event")
+	}
+	if httpErr != nil {
+		return httpErr
 	}
 	return ctx.NoContent(http.StatusOK)
 }
+
+func (h *Handler) checkClearAllowed(ctx echo.Context, confirmation string) *echo.HTTPError {
+	if config.AdminConfig.Profile == config.ProfileTest {
+		return nil
+	}
+	if !config.AdminConfig.ClearEnabled {
+		return echo.NewHTTPError(http.StatusForbidden, errors.CLEAR_DISABLED)
+	}
+	if ctx.Request().Header.Get(ConfirmClearHeader) != confirmation {
+		return echo.NewHTTPError(http.StatusPreconditionRequired, errors.CLEAR_NOT_CONFIRMED+confirmation)
+	}
+	return nil
+}
+
+func (h *Handler) Healthz(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, models.Health{Status: statusOk})
+}
+
+func (h *Handler) Readyz(ctx echo.Context) error {
+	health := models.Health{Status: statusOk, Checks: map[string]string{}}
+	fail := func(check string, reason string) {
+		health.Status = statusFail
+		health.Checks[check] = reason
+	}
+
+	if !h.ready.Load() {
+		fail(checkState, errors.NOT_READY)
+		return ctx.JSON(http.StatusServiceUnavailable, health)
+	}
+	health.Checks[checkState] = statusOk
+
+	reqCtx, cancel := context.WithTimeout(ctx.Request().Context(), config.ServerConfig.ReadyTimeout)
+	defer cancel()
+	if err := h.Repo.Ping(reqCtx); err != nil {
+		fail(checkDatabase, err.Error())
+		return ctx.JSON(http.StatusServiceUnavailable, health)
+	}
+	health.Checks[checkDatabase] = statusOk
+
+	if version, err := h.Repo.SchemaVersion(reqCtx); err != nil {
+		fail(checkSchema, err.Error())
+	} else if version != config.DbConfig.SchemaVersion {
+		fail(checkSchema, errors.SCHEMA_VERSION_MISMATCH+strconv.Itoa(version))
+	} else {
+		health.Checks[checkSchema] = statusOk
+	}
+
+	if err := h.Repo.CheckStatements(reqCtx); err != nil {
+		fail(checkStatements, err.Error())
+	} else {
+		health.Checks[checkStatements] = statusOk
+	}
+
+	if health.Status != statusOk {
+		return ctx.JSON(http.StatusServiceUnavailable, health)
+	}
+	return ctx.JSON(http.StatusOK, health)
+}
+
+func (h *Handler) Diagnostics(ctx echo.Context) error {
+	diag, err := h.Repo.Diagnostics(ctx.Request().Context())
+	if err != nil {
+		return deadline.HTTPError(err)
+	}
+	diag.Uptime = time.Since(h.started).Round(time.Second).String()
+	diag.GoVersion = runtime.Version()
+	diag.Goroutines = runtime.NumGoroutine()
+	return ctx.JSON(http.StatusOK, diag)
+}
@@ -1,8 +1,20 @@
 package service
 
-import "github.com/Natali-Skv/technopark_db_forum/internal/models"
+import (
+	"context"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
 
 type Repo interface {
-	Status() (*models.Status, error)
-	TruncateDB() error
+	Status(ctx context.Context) (*models.Status, error)
+	ForumsStatus(ctx context.Context, limit int) ([]models.ForumStatus, error)
+	TruncateDB(ctx context.Context) error
+	ClearForum(ctx context.Context, slug string) error
+	ClearVotes(ctx context.Context) error
+	Audit(ctx context.Context, event *models.AuditEvent) error
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+	CheckStatements(ctx context.Context) error
+	Diagnostics(ctx context.Context) (*models.Diagnostics, error)
 }
@@ -1,31 +1,254 @@
 package userRepo
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
-	"github.com/jackc/pgx"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
 )
 
 type Repo struct {
-	Conn *pgx.ConnPool
+	Conn *dbconn.Pool
 }
 
-func NewRepo(conn *pgx.ConnPool) *Repo {
-	conn.Prepare("status", " SELECT COUNT(*), sum(posts), sum(threads) FROM forums")
-	conn.Prepare("status_users", " SELECT COUNT(*) FROM users")
+func NewRepo(conn *dbconn.Pool) *Repo {
+	// the counters are split over the slot rows of stats
+	conn.Register("status", "SELECT sum(users)::bigint, sum(forums)::bigint, sum(threads)::bigint, sum(posts)::bigint, sum(votes)::bigint, sum(forum_users)::bigint, sum(edited_posts)::bigint, sum(deleted_posts)::bigint FROM stats")
+	conn.Register("forums_status", "SELECT slug, threads, posts, votes, users, edited_posts, deleted_posts FROM forums ORDER BY posts DESC, slug LIMIT $1")
+	conn.Register("reset_stats", "UPDATE stats SET users=0, forums=0, threads=0, posts=0, votes=0, forum_users=0, edited_posts=0, deleted_posts=0")
+	conn.Register("reset_stats_votes", "UPDATE stats SET votes=0")
+	conn.Register("reset_forums_votes", "UPDATE forums SET votes=0 WHERE votes!=0")
+	conn.Register("audit", "INSERT INTO audit_log(action, target, outcome, request_id, remote_addr) VALUES ($1,$2,$3,$4,$5)")
+	conn.Register("lock_forum_by_slug", "SELECT id, slug FROM forums WHERE slug=$1 FOR UPDATE")
+	conn.Register("clear_forum_votes", "DELETE FROM votes WHERE thread_id IN (SELECT id FROM threads WHERE forum_id=$1)")
+	conn.Register("clear_forum_post_votes", "DELETE FROM post_votes WHERE post_id IN (SELECT id FROM posts WHERE forum_id=$1)")
+	conn.Register("clear_forum_post_reactions", "DELETE FROM post_reactions WHERE post_id IN (SELECT id FROM posts WHERE forum_id=$1)")
+	conn.Register("clear_forum_thread_reactions", "DELETE FROM thread_reactions WHERE thread_id IN (SELECT id FROM threads WHERE forum_id=$1)")
+	conn.Register("clear_forum_participants", "DELETE FROM thread_participants WHERE thread_id IN (SELECT id FROM threads WHERE forum_id=$1)")
+	conn.Register("clear_forum_posts", "DELETE FROM posts WHERE forum_id=$1")
+	conn.Register("clear_forum_users", "DELETE FROM forum_users WHERE forum_slug=$1")
+	conn.Register("clear_forum_threads", "DELETE FROM threads WHERE forum_id=$1")
+	conn.Register("clear_forum", "DELETE FROM forums WHERE id=$1")
+	conn.Register("reset_thread_votes", "UPDATE threads SET votes=0 WHERE votes!=0")
+	conn.Register("reset_post_votes", "UPDATE posts SET votes=0 WHERE votes!=0")
+	conn.Register("schema_version", "SELECT max(version) FROM schema_version")
+	conn.Register("database_size", "SELECT pg_database_size(current_database())")
+	conn.Register("prepared_statements", "SELECT name FROM pg_prepared_statements")
+	conn.Register("table_stats", "SELECT relname, n_live_tup, n_dead_tup, pg_total_relation_size(relid) FROM pg_stat_user_tables ORDER BY relname")
 	return &Repo{Conn: conn}
 }
 
-func (r *Repo) Status() (*models.Status, error) {
+func (r *Repo) Status(ctx context.Context) (*models.Status, error) {
+	ctx, span := tracing.Start(ctx, "serviceRepo.Status")
+	defer span.End()
 	status := &models.Status{}
-	err := r.Conn.QueryRow("EXECUTE status").Scan(&status.Forums, &status.Posts, &status.Threads)
-	err = r.Conn.QueryRow("EXECUTE status_users").Scan(&status.Users)
+	err := r.Conn.QueryRow(ctx, "status").Scan(&status.Users, &status.Forums, &status.Threads, &status.Posts,
+		&status.Votes, &status.ForumUsers, &status.EditedPosts, &status.DeletedPosts)
 	if err != nil {
 		return nil, err
 	}
 	return status, nil
 }
 
-func (r *Repo) TruncateDB() error {
-	_, err := r.Conn.Exec(`TRUNCATE forum_users, users, forums, threads, posts, votes`)
+func (r *Repo) ForumsStatus(ctx context.Context, limit int) ([]models.ForumStatus, error) {
+	ctx, span := tracing.Start(ctx, "serviceRepo.ForumsStatus")
+	defer span.End()
+	forumRows, err := r.Conn.Query(ctx, "forums_status", limit)
+	defer forumRows.Close()
+	if err != nil {
+		return nil, err
+	}
+	forumsResp := make([]models.ForumStatus, 0)
+	for forumRows.Next() {
+		forum := models.ForumStatus{}
+		err = forumRows.Scan(&forum.Slug, &forum.Threads, &forum.Posts, &forum.Votes, &forum.Users, &forum.EditedPosts, &forum.DeletedPosts)
+		if err != nil {
+			return nil, err
+		}
+		forumsResp = append(forumsResp, forum)
+	}
+	return forumsResp, forumRows.Err()
+}
+
+// TruncateDB resets the counters in the same transaction, so /service/status never sees the old figures.
+func (r *Repo) TruncateDB(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "serviceRepo.TruncateDB")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err = tx.Exec(ctx, `TRUNCATE forum_users, user_renames, users, forums, threads, posts, votes, post_votes, post_reactions, thread_reactions, thread_participants`); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, "reset_stats"); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ClearForum deletes the forum with all its threads, posts, votes, reactions and forum users. Users themselves are kept.
+func (r *Repo) ClearForum(ctx context.Context, slug string) error {
+	ctx, span := tracing.Start(ctx, "serviceRepo.ClearForum")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var forumId int64
+	err = tx.QueryRow(ctx, "lock_forum_by_slug", slug).Scan(&forumId, &slug)
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{"clear_forum_votes", "clear_forum_post_votes", "clear_forum_post_reactions", "clear_forum_thread_reactions", "clear_forum_participants", "clear_forum_posts"} {
+		if _, err = tx.Exec(ctx, query, forumId); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(ctx, "clear_forum_users", slug); err != nil {
+		return err
+	}
+	for _, query := range []string{"clear_forum_threads", "clear_forum"} {
+		if _, err = tx.Exec(ctx, query, forumId); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repo) ClearVotes(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "serviceRepo.ClearVotes")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err = tx.Exec(ctx, "TRUNCATE votes, post_votes"); err != nil {
+		return err
+	}
+	for _, query := range []string{"reset_thread_votes", "reset_post_votes", "reset_forums_votes", "reset_stats_votes"} {
+		if _, err = tx.Exec(ctx, query); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repo) Audit(ctx context.Context, event *models.AuditEvent) error {
+	ctx, span := tracing.Start(ctx, "serviceRepo.Audit")
+	defer span.End()
+	_, err := r.Conn.Exec(ctx, "audit", event.Action, event.Target, event.Outcome, event.RequestId, event.RemoteAddr)
+	return err
+}
+
+func (r *Repo) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "serviceRepo.Ping")
+	defer span.End()
+	_, err := r.Conn.Exec(ctx, "SELECT 1")
 	return err
 }
+
+func (r *Repo) SchemaVersion(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "serviceRepo.SchemaVersion")
+	defer span.End()
+	var version int
+	err := r.Conn.QueryRow(ctx, "schema_version").Scan(&version)
+	return version, err
+}
+
+// CheckStatements fails if some registered statement was rejected by the server
+// or is missing from the connection the check runs on.
+func (r *Repo) CheckStatements(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "serviceRepo.CheckStatements")
+	defer span.End()
+	registered, failed := r.Conn.Statements()
+	if len(failed) != 0 {
+		names := make([]string, 0, len(failed))
+		for name := range failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("statements failed to prepare: %s", strings.Join(names, ", "))
+	}
+	if len(registered) == 0 {
+		return fmt.Errorf("no statements prepared")
+	}
+
+	preparedRows, err := r.Conn.Query(ctx, "prepared_statements")
+	defer preparedRows.Close()
+	if err != nil {
+		return err
+	}
+	prepared := make(map[string]bool, len(registered))
+	for preparedRows.Next() {
+		var name string
+		if err = preparedRows.Scan(&name); err != nil {
+			return err
+		}
+		prepared[name] = true
+	}
+	if err = preparedRows.Err(); err != nil {
+		return err
+	}
+	missing := make([]string, 0)
+	for _, name := range registered {
+		if !prepared[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("statements not prepared on connection: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (r *Repo) Diagnostics(ctx context.Context) (*models.Diagnostics, error) {
+	ctx, span := tracing.Start(ctx, "serviceRepo.Diagnostics")
+	defer span.End()
+	diag := &models.Diagnostics{StatementErrors: map[string]string{}}
+
+	stat := r.Conn.Stat()
+	diag.Pool = models.PoolStat{
+		MaxConnections:       int(stat.MaxConns()),
+		CurrentConnections:   int(stat.TotalConns()),
+		AvailableConnections: int(stat.IdleConns()),
+	}
+	registered, failed := r.Conn.Statements()
+	diag.Statements = registered
+	for name, err := range failed {
+		diag.StatementErrors[name] = err.Error()
+	}
+
+	err := r.Conn.QueryRow(ctx, "schema_version").Scan(&diag.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	err = r.Conn.QueryRow(ctx, "database_size").Scan(&diag.DatabaseSize)
+	if err != nil {
+		return nil, err
+	}
+
+	tableRows, err := r.Conn.Query(ctx, "table_stats")
+	defer tableRows.Close()
+	if err != nil {
+		return nil, err
+	}
+	diag.Tables = make([]models.TableStat, 0)
+	for tableRows.Next() {
+		table := models.TableStat{}
+		err = tableRows.Scan(&table.Name, &table.LiveRows, &table.DeadRows, &table.Size)
+		if err != nil {
+			return nil, err
+		}
+		diag.Tables = append(diag.Tables, table)
+	}
+	return diag, tableRows.Err()
+}
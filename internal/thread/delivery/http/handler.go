@@ -6,14 +6,23 @@ import (
 
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
 	threadRepo "github.com/Natali-Skv/technopark_db_forum/internal/thread"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
 	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
-	"github.com/jackc/pgx"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
 const (
 	SlugCtxKey     = "slug"
 	SlugOrIdCtxKey = "slug"
+	VoterCtxKey    = "nickname"
+	ReactionCtxKey = "reaction"
+
+	DescSortQueryParam = "desc"
+	SinceQueryParam    = "since"
+	LimitQueryParam    = "limit"
 )
 
 type Handler struct {
@@ -30,14 +39,16 @@ func (h *Handler) CreateThread(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
 	thread.ForumSlug = ctx.Param(SlugCtxKey)
-	newThread, err := h.Repo.Create(thread)
+	if err := validate.Thread(thread); err != nil {
+		return validate.HTTPError(err)
+	}
+	newThread, err := h.Repo.Create(ctx.Request().Context(), thread)
 	if err != nil {
-		pgerr, _ := err.(pgx.PgError)
-		switch pgerr.Code {
+		switch dbconn.ErrorCode(err) {
 		case "23505":
-			conflictForum, err := h.Repo.GetBySlugOrId(thread.Slug, 0)
+			conflictForum, err := h.Repo.GetBySlugOrId(ctx.Request().Context(), thread.Slug, 0)
 			if err != nil || conflictForum == nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+				return deadline.HTTPError(err)
 			}
 			return ctx.JSON(http.StatusConflict, conflictForum)
 		case "AAAA1":
@@ -45,7 +56,7 @@ func (h *Handler) CreateThread(ctx echo.Context) error {
 		case "AAAA3":
 			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD_FORUM+thread.ForumSlug)
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusCreated, newThread)
 }
@@ -57,16 +68,19 @@ func (h *Handler) UpdateThread(ctx echo.Context) error {
 	if err := ctx.Bind(thread); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
+	if err := validate.ThreadUpdate(thread); err != nil {
+		return validate.HTTPError(err)
+	}
 
 	thread.Slug = threadSlugOrId
 	thread.Id = threadId
 
-	threadResp, err := h.Repo.UpdateThread(thread)
+	threadResp, err := h.Repo.UpdateThread(ctx.Request().Context(), thread)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+threadSlugOrId)
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, threadResp)
 }
@@ -74,12 +88,15 @@ func (h *Handler) UpdateThread(ctx echo.Context) error {
 func (h *Handler) GetThread(ctx echo.Context) error {
 	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
 	threadId, err := strconv.Atoi(threadSlugOrId)
-	threadResp, err := h.Repo.GetBySlugOrId(threadSlugOrId, int(threadId))
+	threadResp, err := h.Repo.GetBySlugOrId(ctx.Request().Context(), threadSlugOrId, int(threadId))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+threadSlugOrId)
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
+	}
+	if threadResp.Reactions, err = h.Repo.GetReactions(ctx.Request().Context(), threadResp.Id); err != nil {
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, threadResp)
 }
@@ -89,6 +106,9 @@ func (h *Handler) Vote(ctx echo.Context) error {
 	if err := ctx.Bind(&vote); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
+	if err := validate.Vote(vote); err != nil {
+		return validate.HTTPError(err)
+	}
 	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
 	ThreadId, err := strconv.Atoi(threadSlugOrId)
 	if err == nil {
@@ -96,18 +116,101 @@ func (h *Handler) Vote(ctx echo.Context) error {
 	} else {
 		vote.ThreadSlug = threadSlugOrId
 	}
-	thread, err := h.Repo.Vote(vote)
+	thread, err := h.Repo.Vote(ctx.Request().Context(), vote)
 	if err != nil {
-		if pgerr, converted := err.(pgx.PgError); converted {
-			if pgerr.Code == "23502" || pgerr.Code == "23503" {
+		if code := dbconn.ErrorCode(err); code != "" {
+			if code == "23502" || code == "23503" {
 				return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+vote.ThreadSlug+strconv.Itoa(int(vote.ThreadId)))
 			}
-			if pgerr.Code == "AAAA1" {
+			if code == "AAAA1" {
 				return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_POST_AUTHOR_BY_NICK+vote.Nick)
 			}
-		} else {
-			return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
 		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, thread)
+}
+
+// Unvote retracts the voice of the user in the thread; the thread is returned unchanged if they did not vote.
+func (h *Handler) Unvote(ctx echo.Context) error {
+	vote := &models.Vote{Nick: ctx.Param(VoterCtxKey)}
+	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
+	if threadId, err := strconv.Atoi(threadSlugOrId); err == nil {
+		vote.ThreadId = threadId
+	} else {
+		vote.ThreadSlug = threadSlugOrId
+	}
+	thread, err := h.Repo.Unvote(ctx.Request().Context(), vote)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+threadSlugOrId)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, thread)
+}
+
+// GetVotes lists who voted in the thread and how, ordered by nickname.
+func (h *Handler) GetVotes(ctx echo.Context) error {
+	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
+	threadId, _ := strconv.Atoi(threadSlugOrId)
+	limit, _ := strconv.Atoi(ctx.QueryParam(LimitQueryParam))
+	since := ctx.QueryParam(SinceQueryParam)
+	desc := ctx.QueryParam(DescSortQueryParam) == "true"
+
+	votes, err := h.Repo.GetVotes(ctx.Request().Context(), threadSlugOrId, threadId, desc, limit, since)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+threadSlugOrId)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, votes)
+}
+
+// React adds a reaction of the user to the thread and returns the thread with the reaction counts.
+func (h *Handler) React(ctx echo.Context) error {
+	reaction := &models.Reaction{}
+	if err := ctx.Bind(reaction); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
+	}
+	if err := validate.Reaction(reaction); err != nil {
+		return validate.HTTPError(err)
+	}
+	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
+	if threadId, err := strconv.Atoi(threadSlugOrId); err == nil {
+		reaction.ThreadId = threadId
+	} else {
+		reaction.ThreadSlug = threadSlugOrId
+	}
+	thread, err := h.Repo.React(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+threadSlugOrId)
+		}
+		if dbconn.ErrorCode(err) == "AAAA1" {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+reaction.Nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, thread)
+}
+
+// Unreact removes a reaction of the user from the thread; the thread is returned unchanged if they did not react so.
+func (h *Handler) Unreact(ctx echo.Context) error {
+	reaction := &models.Reaction{Nick: ctx.Param(VoterCtxKey), Reaction: ctx.Param(ReactionCtxKey)}
+	threadSlugOrId := ctx.Param(SlugOrIdCtxKey)
+	if threadId, err := strconv.Atoi(threadSlugOrId); err == nil {
+		reaction.ThreadId = threadId
+	} else {
+		reaction.ThreadSlug = threadSlugOrId
+	}
+	thread, err := h.Repo.Unreact(ctx.Request().Context(), reaction)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NO_THREAD+threadSlugOrId)
+		}
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, thread)
 }
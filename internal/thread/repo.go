@@ -1,10 +1,24 @@
 package thread
 
-import "github.com/Natali-Skv/technopark_db_forum/internal/models"
+import (
+	"context"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
 
 type Repo interface {
-	Create(forum *models.Thread) (*models.Thread, error)
-	GetBySlugOrId(slug string, id int) (*models.Thread, error)
-	Vote(vote *models.Vote) (*models.Thread, error)
-	UpdateThread(thread *models.Thread) (*models.Thread, error)
+	Create(ctx context.Context, forum *models.Thread) (*models.Thread, error)
+	GetBySlugOrId(ctx context.Context, slug string, id int) (*models.Thread, error)
+	Vote(ctx context.Context, vote *models.Vote) (*models.Thread, error)
+	// Unvote retracts the vote of vote.Nick in the thread; it is a no-op if they did not vote.
+	Unvote(ctx context.Context, vote *models.Vote) (*models.Thread, error)
+	// GetVotes lists the votes of the thread by nickname, since is the nickname of the last vote of the previous page.
+	GetVotes(ctx context.Context, slug string, id int, desc bool, limit int, since string) ([]models.Vote, error)
+	// React adds the reaction of reaction.Nick to the thread; adding it again is a no-op.
+	React(ctx context.Context, reaction *models.Reaction) (*models.Thread, error)
+	// Unreact removes the reaction of reaction.Nick from the thread; it is a no-op if they did not react so.
+	Unreact(ctx context.Context, reaction *models.Reaction) (*models.Thread, error)
+	// GetReactions counts the reactions to the thread by reaction.
+	GetReactions(ctx context.Context, id int) (map[string]int, error)
+	UpdateThread(ctx context.Context, thread *models.Thread) (*models.Thread, error)
 }
@@ -1,34 +1,47 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
 	"github.com/go-openapi/strfmt"
-	"github.com/jackc/pgx"
 )
 
+// threadColumns are read by scanThread; lastPostAt is null until the thread has posts.
+const threadColumns = "id, slug, title, author_nick, forum_slug, message, votes, created, posts_count, CASE WHEN posts_count>0 THEN last_post_at END, COALESCE(last_post_author, ''), participants_count"
+
 type Repo struct {
-	Conn *pgx.ConnPool
+	Conn *dbconn.Pool
 }
 
-func NewRepo(conn *pgx.ConnPool) *Repo {
-	conn.Prepare("create_thread_now", "INSERT into threads(slug, title, author_nick, forum_slug, message) VALUES (NULLIF($1, ''),$2,$3,$4,$5) RETURNING author_nick, id, forum_slug")
-	conn.Prepare("create_thread", "INSERT into threads(slug, title, author_nick, forum_slug, message, created) VALUES (NULLIF($1, ''),$2,$3,$4,$5,$6) RETURNING author_nick, id, forum_slug")
-	conn.Prepare("get_thread_by_slug", "SELECT id, slug, title, author_nick, forum_slug, message, votes, created FROM threads WHERE slug =$1")
-	conn.Prepare("get_thread_by_id", "SELECT id, slug, title, author_nick, forum_slug, message, votes, created FROM threads WHERE id=$1")
-	conn.Prepare("update_thread", "UPDATE threads SET title=COALESCE(NULLIF($1, ''), title), message=COALESCE(NULLIF($2, ''), message) WHERE $3!=0 AND id=$4 OR $5!='' AND slug=$6 RETURNING id, slug, title, author_nick, forum_slug, message, votes, created")
-	conn.Prepare("vote_thread_by_id", "INSERT INTO votes(user_nick, thread_id, vote) VALUES ($1,$2,$3) ON CONFLICT(user_nick, thread_id) DO UPDATE SET vote=$4")
-	conn.Prepare("vote_thread_by_slug", "INSERT INTO votes(user_nick, thread_id, vote) VALUES ($1, (SELECT id FROM threads WHERE slug=$2),$3) ON CONFLICT(user_nick, thread_id) DO UPDATE SET vote=$4 RETURNING thread_id")
+func NewRepo(conn *dbconn.Pool) *Repo {
+	conn.Register("create_thread_now", "INSERT into threads(slug, title, author_nick, forum_slug, message) VALUES (NULLIF($1, ''),$2,$3,$4,$5) RETURNING author_nick, id, forum_slug")
+	conn.Register("create_thread", "INSERT into threads(slug, title, author_nick, forum_slug, message, created) VALUES (NULLIF($1, ''),$2,$3,$4,$5,$6) RETURNING author_nick, id, forum_slug")
+	conn.Register("get_thread_by_slug", "SELECT "+threadColumns+" FROM threads WHERE slug =$1")
+	conn.Register("get_thread_by_id", "SELECT "+threadColumns+" FROM threads WHERE id=$1")
+	conn.Register("update_thread", "UPDATE threads SET title=COALESCE(NULLIF($1, ''), title), message=COALESCE(NULLIF($2, ''), message) WHERE $3!=0 AND id=$4 OR $5!='' AND slug=$6 RETURNING "+threadColumns)
+	conn.Register("vote_thread_by_id", "INSERT INTO votes(user_nick, thread_id, vote) VALUES ($1,$2,$3) ON CONFLICT(user_nick, thread_id) DO UPDATE SET vote=$4")
+	conn.Register("vote_thread_by_slug", "INSERT INTO votes(user_nick, thread_id, vote) VALUES ($1, (SELECT id FROM threads WHERE slug=$2),$3) ON CONFLICT(user_nick, thread_id) DO UPDATE SET vote=$4 RETURNING thread_id")
+	conn.Register("unvote_thread", "DELETE FROM votes WHERE user_nick=$1 AND thread_id=$2")
+	conn.Register("get_thread_votes", "SELECT user_nick, vote FROM votes WHERE thread_id=$1 AND ($2='' OR user_nick>$3) ORDER BY user_nick LIMIT NULLIF($4,0)")
+	conn.Register("get_thread_votes_desc", "SELECT user_nick, vote FROM votes WHERE thread_id=$1 AND ($2='' OR user_nick<$3) ORDER BY user_nick DESC LIMIT NULLIF($4,0)")
+	conn.Register("react_thread", "INSERT INTO thread_reactions(user_nick, thread_id, reaction) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING")
+	conn.Register("unreact_thread", "DELETE FROM thread_reactions WHERE user_nick=$1 AND thread_id=$2 AND reaction=$3")
+	conn.Register("get_thread_reactions", "SELECT reaction, count(*) FROM thread_reactions WHERE thread_id=$1 GROUP BY reaction")
 	return &Repo{Conn: conn}
 }
-func (r *Repo) Create(thread *models.Thread) (*models.Thread, error) {
+func (r *Repo) Create(ctx context.Context, thread *models.Thread) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.Create")
+	defer span.End()
 	var err error
 	if thread.Created == "" {
-		err = r.Conn.QueryRow("EXECUTE create_thread_now($1,$2,$3,$4,$5)", thread.Slug, thread.Title, thread.AuthorNick, thread.ForumSlug, thread.Message).Scan(&thread.AuthorNick, &thread.Id, &thread.ForumSlug)
+		err = r.Conn.QueryRow(ctx, "create_thread_now", thread.Slug, thread.Title, thread.AuthorNick, thread.ForumSlug, thread.Message).Scan(&thread.AuthorNick, &thread.Id, &thread.ForumSlug)
 	} else {
-		err = r.Conn.QueryRow("EXECUTE create_thread($1,$2,$3,$4,$5,$6)", thread.Slug, thread.Title, thread.AuthorNick, thread.ForumSlug, thread.Message, thread.Created).Scan(&thread.AuthorNick, &thread.Id, &thread.ForumSlug)
+		err = r.Conn.QueryRow(ctx, "create_thread", thread.Slug, thread.Title, thread.AuthorNick, thread.ForumSlug, thread.Message, thread.Created).Scan(&thread.AuthorNick, &thread.Id, &thread.ForumSlug)
 	}
 	if err != nil {
 		return nil, err
@@ -36,55 +49,142 @@ func (r *Repo) Create(thread *models.Thread) (*models.Thread, error) {
 	return thread, nil
 }
 
-func (r *Repo) GetBySlugOrId(slug string, id int) (*models.Thread, error) {
-	thread := &models.Thread{}
-	var created time.Time
-	var threadSlug sql.NullString
-	var err error
+func (r *Repo) GetBySlugOrId(ctx context.Context, slug string, id int) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.GetBySlugOrId")
+	defer span.End()
 	if id != 0 {
-		err = r.Conn.QueryRow("EXECUTE get_thread_by_id($1)", id).Scan(&thread.Id, &threadSlug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created)
-	} else {
-		err = r.Conn.QueryRow("EXECUTE get_thread_by_slug($1)", slug).Scan(&thread.Id, &threadSlug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created)
-	}
-	if err != nil {
-		return nil, err
+		return scanThread(r.Conn.QueryRow(ctx, "get_thread_by_id", id))
 	}
-	thread.Created = strfmt.DateTime(created.UTC()).String()
-	thread.Slug = threadSlug.String
-	return thread, nil
+	return scanThread(r.Conn.QueryRow(ctx, "get_thread_by_slug", slug))
 }
 
-func (r *Repo) UpdateThread(thread *models.Thread) (*models.Thread, error) {
+func scanThread(row *dbconn.Row) (*models.Thread, error) {
+	thread := &models.Thread{}
 	var created time.Time
+	var lastPostAt *time.Time
 	var slug sql.NullString
-	err := r.Conn.QueryRow("EXECUTE update_thread($1,$2,$3,$4,$5,$6)", thread.Title, thread.Message, thread.Id, thread.Id, thread.Slug, thread.Slug).Scan(&thread.Id, &slug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created)
+	err := row.Scan(&thread.Id, &slug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created,
+		&thread.PostsCount, &lastPostAt, &thread.LastPostAuthor, &thread.ParticipantsCount)
 	if err != nil {
 		return nil, err
 	}
 	thread.Created = strfmt.DateTime(created.UTC()).String()
 	thread.Slug = slug.String
+	if lastPostAt != nil {
+		thread.LastPostAt = strfmt.DateTime(lastPostAt.UTC()).String()
+	}
 	return thread, nil
 }
 
-func (r *Repo) Vote(vote *models.Vote) (*models.Thread, error) {
-	thread := &models.Thread{}
-	var created time.Time
+// Unvote relies on the delete trigger of votes to take the voice back from threads.votes.
+func (r *Repo) Unvote(ctx context.Context, vote *models.Vote) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.Unvote")
+	defer span.End()
+	thread, err := r.GetBySlugOrId(ctx, vote.ThreadSlug, vote.ThreadId)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := r.Conn.Exec(ctx, "unvote_thread", vote.Nick, thread.Id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return thread, nil
+	}
+	return r.GetBySlugOrId(ctx, "", thread.Id)
+}
+
+func (r *Repo) GetVotes(ctx context.Context, slug string, id int, desc bool, limit int, since string) ([]models.Vote, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.GetVotes")
+	defer span.End()
+	thread, err := r.GetBySlugOrId(ctx, slug, id)
+	if err != nil {
+		return nil, err
+	}
+	query := "get_thread_votes"
+	if desc {
+		query += "_desc"
+	}
+	rows, err := r.Conn.Query(ctx, query, thread.Id, since, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	votes := make([]models.Vote, 0)
+	for rows.Next() {
+		vote := models.Vote{ThreadId: thread.Id}
+		if err = rows.Scan(&vote.Nick, &vote.Voice); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+func (r *Repo) UpdateThread(ctx context.Context, thread *models.Thread) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.UpdateThread")
+	defer span.End()
+	return scanThread(r.Conn.QueryRow(ctx, "update_thread", thread.Title, thread.Message, thread.Id, thread.Id, thread.Slug, thread.Slug))
+}
+
+func (r *Repo) Vote(ctx context.Context, vote *models.Vote) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.Vote")
+	defer span.End()
 	var err error
 	if vote.ThreadId != 0 {
-		_, err = r.Conn.Exec("EXECUTE vote_thread_by_id($1,$2,$3,$4)", vote.Nick, vote.ThreadId, vote.Voice, vote.Voice)
+		_, err = r.Conn.Exec(ctx, "vote_thread_by_id", vote.Nick, vote.ThreadId, vote.Voice, vote.Voice)
 	} else {
-		err = r.Conn.QueryRow("EXECUTE vote_thread_by_slug($1,$2,$3,$4)", vote.Nick, vote.ThreadSlug, vote.Voice, vote.Voice).Scan(&vote.ThreadId)
+		err = r.Conn.QueryRow(ctx, "vote_thread_by_slug", vote.Nick, vote.ThreadSlug, vote.Voice, vote.Voice).Scan(&vote.ThreadId)
 	}
 	if err != nil {
 		return nil, err
 	}
-	var slug sql.NullString
-	err = r.Conn.QueryRow("EXECUTE get_thread_by_id($1)", vote.ThreadId).Scan(&thread.Id, &slug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created)
+	return scanThread(r.Conn.QueryRow(ctx, "get_thread_by_id", vote.ThreadId))
+}
+
+func (r *Repo) React(ctx context.Context, reaction *models.Reaction) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.React")
+	defer span.End()
+	return r.react(ctx, "react_thread", reaction)
+}
+
+func (r *Repo) Unreact(ctx context.Context, reaction *models.Reaction) (*models.Thread, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.Unreact")
+	defer span.End()
+	return r.react(ctx, "unreact_thread", reaction)
+}
+
+// react runs the insert or delete of a reaction and returns the thread with the new counts.
+func (r *Repo) react(ctx context.Context, query string, reaction *models.Reaction) (*models.Thread, error) {
+	thread, err := r.GetBySlugOrId(ctx, reaction.ThreadSlug, reaction.ThreadId)
 	if err != nil {
 		return nil, err
 	}
-	thread.Created = strfmt.DateTime(created.UTC()).String()
-	thread.Slug = slug.String
-
+	if _, err = r.Conn.Exec(ctx, query, reaction.Nick, thread.Id, reaction.Reaction); err != nil {
+		return nil, err
+	}
+	if thread.Reactions, err = r.GetReactions(ctx, thread.Id); err != nil {
+		return nil, err
+	}
 	return thread, nil
 }
+
+func (r *Repo) GetReactions(ctx context.Context, id int) (map[string]int, error) {
+	ctx, span := tracing.Start(ctx, "threadRepo.GetReactions")
+	defer span.End()
+	rows, err := r.Conn.Query(ctx, "get_thread_reactions", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reactions := map[string]int{}
+	for rows.Next() {
+		var reaction string
+		var count int
+		if err = rows.Scan(&reaction, &count); err != nil {
+			return nil, err
+		}
+		reactions[reaction] = count
+	}
+	return reactions, rows.Err()
+}
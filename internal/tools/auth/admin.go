@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	AdminTokenHeader = "X-Admin-Token"
+	bearerPrefix     = "Bearer "
+)
+
+// Admin lets a request through only if it carries the configured admin token,
+// either in X-Admin-Token or as a bearer token. The test profile needs no token.
+func Admin(cfg config.AdminConfigStruct) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if cfg.Profile == config.ProfileTest {
+				return next(ctx)
+			}
+			if cfg.Token == "" {
+				return echo.NewHTTPError(http.StatusForbidden, errors.ADMIN_DISABLED)
+			}
+			token := ctx.Request().Header.Get(AdminTokenHeader)
+			if token == "" {
+				token = strings.TrimPrefix(ctx.Request().Header.Get(echo.HeaderAuthorization), bearerPrefix)
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) != 1 {
+				return echo.NewHTTPError(http.StatusUnauthorized, errors.BAD_ADMIN_TOKEN)
+			}
+			return next(ctx)
+		}
+	}
+}
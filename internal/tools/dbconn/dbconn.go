@@ -0,0 +1,256 @@
+package dbconn
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	inlineStatement = "inline"
+	copyStatement   = "copy"
+	statementAttr   = "db.statement.name"
+	rowsAttr        = "db.rows"
+)
+
+// Pool wraps pgxpool.Pool so that every repo query gets its own span, a statement timeout,
+// and is logged if it is slower than SlowQueryThreshold.
+//
+// Repos register their statements with Register and then pass the statement name instead of SQL;
+// the registry's AfterConnect has prepared it under that name on every connection of the pool.
+type Pool struct {
+	*pgxpool.Pool
+	SlowQueryThreshold time.Duration
+	StatementTimeout   time.Duration
+
+	registry *Registry
+}
+
+// NewPool expects registry.AfterConnect to be installed as the AfterConnect hook of pool.
+func NewPool(pool *pgxpool.Pool, registry *Registry, slowQueryThreshold time.Duration, statementTimeout time.Duration) *Pool {
+	return &Pool{
+		Pool:               pool,
+		SlowQueryThreshold: slowQueryThreshold,
+		StatementTimeout:   statementTimeout,
+		registry:           registry,
+	}
+}
+
+func (p *Pool) Register(name, sql string) {
+	p.registry.Register(name, sql)
+}
+
+// Validate is called once all repos are registered: it drops connections opened before that and
+// opens a fresh one, which fails with the PrepareErrors of every statement the server refuses.
+func (p *Pool) Validate(ctx context.Context) error {
+	p.Pool.Reset()
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn.Release()
+	return nil
+}
+
+// Statements returns the sorted names of the registered statements and the errors of those
+// that failed to prepare on the last new connection.
+func (p *Pool) Statements() ([]string, map[string]error) {
+	return p.registry.Names(), p.registry.Failures()
+}
+
+// resolve tells the statement name for tracing. Registered names are prepared on every connection
+// and are sent as is; anything else is inline SQL and goes through pgx's statement cache.
+func (p *Pool) resolve(sql string) string {
+	if _, ok := p.registry.lookup(sql); ok {
+		return sql
+	}
+	return inlineStatement
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Rows struct {
+	pgx.Rows
+	count  int64
+	finish func(err error, rows int64)
+}
+
+type Row struct {
+	row    pgx.Row
+	finish func(err error, rows int64)
+}
+
+func (p *Pool) Query(ctx context.Context, sql string, args ...any) (*Rows, error) {
+	return p.query(ctx, p.Pool, sql, args)
+}
+
+func (p *Pool) QueryRow(ctx context.Context, sql string, args ...any) *Row {
+	return p.queryRow(ctx, p.Pool, sql, args)
+}
+
+func (p *Pool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return p.exec(ctx, p.Pool, sql, args)
+}
+
+func (p *Pool) query(ctx context.Context, q querier, sql string, args []any) (*Rows, error) {
+	ctx, finish := p.track(ctx, sql, args)
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		finish(err, 0)
+		return &Rows{}, err
+	}
+	return &Rows{Rows: rows, finish: finish}, nil
+}
+
+func (p *Pool) queryRow(ctx context.Context, q querier, sql string, args []any) *Row {
+	ctx, finish := p.track(ctx, sql, args)
+	return &Row{row: q.QueryRow(ctx, sql, args...), finish: finish}
+}
+
+func (p *Pool) exec(ctx context.Context, q querier, sql string, args []any) (pgconn.CommandTag, error) {
+	ctx, finish := p.track(ctx, sql, args)
+	tag, err := q.Exec(ctx, sql, args...)
+	finish(err, tag.RowsAffected())
+	return tag, err
+}
+
+// Tx runs its queries through the same tracking as Pool.
+type Tx struct {
+	tx   pgx.Tx
+	pool *Pool
+}
+
+func (p *Pool) Begin(ctx context.Context) (*Tx, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx, pool: p}, nil
+}
+
+func (t *Tx) Query(ctx context.Context, sql string, args ...any) (*Rows, error) {
+	return t.pool.query(ctx, t.tx, sql, args)
+}
+
+func (t *Tx) QueryRow(ctx context.Context, sql string, args ...any) *Row {
+	return t.pool.queryRow(ctx, t.tx, sql, args)
+}
+
+func (t *Tx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return t.pool.exec(ctx, t.tx, sql, args)
+}
+
+func (t *Tx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+// CopyFrom sends rows with COPY FROM STDIN; it is traced as the "copy" statement.
+func (t *Tx) CopyFrom(ctx context.Context, table pgx.Identifier, columns []string, rows [][]any) (int64, error) {
+	ctx, finish := t.pool.trackStatement(ctx, copyStatement, nil)
+	n, err := t.tx.CopyFrom(ctx, table, columns, pgx.CopyFromRows(rows))
+	finish(err, n)
+	return n, err
+}
+
+// Rollback is a no-op after a successful Commit, so it can be deferred right after Begin.
+func (t *Tx) Rollback(ctx context.Context) error {
+	err := t.tx.Rollback(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return nil
+	}
+	return err
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows == nil {
+		return false
+	}
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+func (r *Rows) Err() error {
+	if r.Rows == nil {
+		return nil
+	}
+	return r.Rows.Err()
+}
+
+// Close must be called even if Next returned false: the query duration is recorded here.
+func (r *Rows) Close() {
+	if r.Rows == nil {
+		return
+	}
+	r.Rows.Close()
+	if r.finish != nil {
+		r.finish(r.Rows.Err(), r.count)
+		r.finish = nil
+	}
+}
+
+func (r *Row) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	switch {
+	case err == nil:
+		r.finish(nil, 1)
+	case errors.Is(err, pgx.ErrNoRows):
+		r.finish(nil, 0)
+	default:
+		r.finish(err, 0)
+	}
+	return err
+}
+
+// track starts the statement span and timeout. The returned func must be called exactly once when the statement is done.
+func (p *Pool) track(ctx context.Context, sql string, args []any) (context.Context, func(err error, rows int64)) {
+	return p.trackStatement(ctx, p.resolve(sql), args)
+}
+
+func (p *Pool) trackStatement(ctx context.Context, name string, args []any) (context.Context, func(err error, rows int64)) {
+	ctx, span := tracing.Start(ctx, name, semconv.DBSystemPostgreSQL, attribute.String(statementAttr, name))
+	cancel := context.CancelFunc(func() {})
+	if p.StatementTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.StatementTimeout)
+	}
+	start := time.Now()
+	return ctx, func(err error, rows int64) {
+		cancel()
+		span.SetAttributes(attribute.Int64(rowsAttr, rows))
+		tracing.End(span, err)
+		duration := time.Since(start)
+		if duration < p.SlowQueryThreshold {
+			return
+		}
+		zerolog.Ctx(ctx).Warn().
+			Str("statement", name).
+			Interface("args", args).
+			Dur("duration", duration).
+			Int64("rows", rows).
+			AnErr("query_error", err).
+			Msg("slow query")
+	}
+}
+
+// ErrorCode returns the SQLSTATE of a postgres error, or "" for any other error.
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package dbconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Registry holds every named statement of the repos. Its AfterConnect prepares them on each new
+// pool connection, so a statement name can be sent instead of SQL on any connection of the pool.
+type Registry struct {
+	mu         sync.RWMutex
+	statements map[string]string
+	failures   map[string]error
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		statements: map[string]string{},
+		failures:   map[string]error{},
+	}
+}
+
+// PrepareError tells which statement the server refused and why.
+type PrepareError struct {
+	Name string
+	SQL  string
+	Err  error
+}
+
+func (e *PrepareError) Error() string {
+	var pgErr *pgconn.PgError
+	if errors.As(e.Err, &pgErr) && pgErr.Position > 0 && int(pgErr.Position) <= len(e.SQL) {
+		return fmt.Sprintf("prepare %q: %v, near %q", e.Name, e.Err, e.SQL[pgErr.Position-1:])
+	}
+	return fmt.Sprintf("prepare %q: %v", e.Name, e.Err)
+}
+
+func (e *PrepareError) Unwrap() error {
+	return e.Err
+}
+
+// Register adds a statement. Registering another SQL under a taken name is a programming error and panics.
+func (r *Registry) Register(name, sql string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if registered, ok := r.statements[name]; ok && registered != sql {
+		panic(fmt.Sprintf("dbconn: statement %q is already registered with different SQL", name))
+	}
+	r.statements[name] = sql
+}
+
+func (r *Registry) lookup(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sql, ok := r.statements[name]
+	return sql, ok
+}
+
+// Names returns the sorted names of all registered statements.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.statements))
+	for name := range r.statements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Failures returns the statements that failed on the last connection that tried them.
+func (r *Registry) Failures() map[string]error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	failures := make(map[string]error, len(r.failures))
+	for name, err := range r.failures {
+		failures[name] = err
+	}
+	return failures
+}
+
+// AfterConnect prepares all registered statements on conn. It is meant for pgxpool.Config.AfterConnect:
+// if any statement fails the connection is discarded and the joined PrepareErrors are returned.
+func (r *Registry) AfterConnect(ctx context.Context, conn *pgx.Conn) error {
+	var errs []error
+	for _, name := range r.Names() {
+		sql, _ := r.lookup(name)
+		_, err := conn.Prepare(ctx, name, sql)
+		r.mu.Lock()
+		if err != nil {
+			r.failures[name] = err
+			errs = append(errs, &PrepareError{Name: name, SQL: sql, Err: err})
+		} else {
+			delete(r.failures, name)
+		}
+		r.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// PrepareErrors collects every PrepareError wrapped in err.
+func PrepareErrors(err error) []*PrepareError {
+	var prepareErrs []*PrepareError
+	var walk func(err error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case *PrepareError:
+			prepareErrs = append(prepareErrs, e)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+	walk(err)
+	return prepareErrs
+}
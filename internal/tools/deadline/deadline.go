@@ -0,0 +1,57 @@
+package deadline
+
+import (
+	"context"
+	goErrors "errors"
+	"net/http"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+// queryCanceled is what postgres reports when statement_timeout or a cancel request stops a query.
+const queryCanceled = "57014"
+
+// Middleware bounds the request context with the deadline configured for the matched route,
+// so every repo call made by the handler is aborted once it runs out.
+func Middleware(cfg config.DeadlineConfigStruct) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			timeout, ok := cfg.Endpoints[req.Method+" "+ctx.Path()]
+			if !ok {
+				timeout = cfg.Default
+			}
+			if timeout <= 0 {
+				return next(ctx)
+			}
+			reqCtx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			ctx.SetRequest(req.WithContext(reqCtx))
+
+			err := next(ctx)
+			if err != nil && reqCtx.Err() != nil && !ctx.Response().Committed {
+				return HTTPError(reqCtx.Err())
+			}
+			return err
+		}
+	}
+}
+
+// HTTPError turns an unexpected repo error into the response the client gets:
+// 504 when the request or the statement ran out of time, 503 when the database
+// could not be reached or the request was cancelled, 500 otherwise.
+func HTTPError(err error) *echo.HTTPError {
+	var connectErr *pgconn.ConnectError
+	switch {
+	case err == nil:
+	case goErrors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err), dbconn.ErrorCode(err) == queryCanceled:
+		return echo.NewHTTPError(http.StatusGatewayTimeout, errors.DEADLINE_EXCEEDED)
+	case goErrors.Is(err, context.Canceled), goErrors.As(err, &connectErr):
+		return echo.NewHTTPError(http.StatusServiceUnavailable, errors.DB_UNAVAILABLE)
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+}
@@ -13,4 +13,23 @@ const (
 	NO_POST                       = "can't find post by id: "
 	NO_THREAD_FORUM               = "Can't find thread forum by slug: "
 	UNKNOWN_SORT_TYPE             = "unknown sort type"
+	UNKNOWN_PERIOD                = "unknown period, expected day, week, month, year or all"
+	BAD_THREAD_SINCE              = "since is a time, or the id of the last thread of the previous page with the hot, top and active sorts"
+	NOT_READY                     = "server is starting or shutting down"
+	SCHEMA_VERSION_MISMATCH       = "unexpected schema version: "
+	ADMIN_DISABLED                = "admin endpoints are disabled: no admin token configured"
+	BAD_ADMIN_TOKEN               = "missing or invalid admin token"
+	CLEAR_DISABLED                = "clearing data is disabled"
+	CLEAR_NOT_CONFIRMED           = "confirm by sending X-Confirm-Clear: "
+	NOT_FOUND_FORUM               = "Can't find forum by slug: "
+	DEADLINE_EXCEEDED             = "request deadline exceeded"
+	DB_UNAVAILABLE                = "database is unavailable, try again later"
+	NO_MAINTENANCE_TASK           = "can't find maintenance task: "
+	INVALID_REQUEST               = "request does not match the API spec: "
+	MAINTENANCE_TASK_BUSY         = "maintenance task is already queued or running: "
+	BAD_CURSOR                    = "cursor is not one returned by this endpoint"
+	NICKNAME_TAKEN                = "nickname is taken by user: "
+	INVALID_INPUT                 = "invalid input: "
+	UNKNOWN_INCLUDE               = "unknown include, expected author, forum or thread: "
+	GHOST_USER                    = "the account of deleted users can't be changed"
 )
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"os"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIdField = "request_id"
+	TraceIdField   = "trace_id"
+)
+
+func New(cfg config.LogConfigStruct) zerolog.Logger {
+	level, err := zerolog.ParseLevel(cfg.Level)
+	if err != nil || level == zerolog.NoLevel {
+		level = zerolog.InfoLevel
+	}
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	zerolog.DurationFieldUnit = time.Millisecond
+	l := zerolog.New(os.Stdout).Level(level).With().Timestamp().Logger()
+	zerolog.DefaultContextLogger = &l
+	return l
+}
+
+// Middleware puts a logger carrying the request id into the request context and writes one access line per request.
+// Must be registered after middleware.RequestID and tracing.Middleware.
+func Middleware(base zerolog.Logger, cfg config.LogConfigStruct) echo.MiddlewareFunc {
+	sampled := base.Sample(&zerolog.BasicSampler{N: cfg.SampleN})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			res := ctx.Response()
+			requestId := res.Header().Get(echo.HeaderXRequestID)
+			loggerCtx := base.With().Str(RequestIdField, requestId)
+			if spanCtx := trace.SpanContextFromContext(req.Context()); spanCtx.HasTraceID() {
+				loggerCtx = loggerCtx.Str(TraceIdField, spanCtx.TraceID().String())
+			}
+			reqLogger := loggerCtx.Logger()
+			ctx.SetRequest(req.WithContext(reqLogger.WithContext(req.Context())))
+
+			start := time.Now()
+			err := next(ctx)
+			if err != nil {
+				ctx.Error(err)
+			}
+			duration := time.Since(start)
+
+			var event *zerolog.Event
+			switch {
+			case res.Status >= 500:
+				event = base.Error()
+			case duration >= cfg.SlowRequest:
+				event = base.Warn()
+			case res.Status >= 400:
+				// client errors are not sampled, but they are part of the normal traffic of the API
+				event = base.Info()
+			default:
+				event = sampled.Info()
+			}
+			event.Str(RequestIdField, requestId).
+				Str("method", req.Method).
+				Str("uri", req.RequestURI).
+				Str("route", ctx.Path()).
+				Int("status", res.Status).
+				Int64("size", res.Size).
+				Dur("duration", duration).
+				Err(err).
+				Msg("request")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package openapi serves the API spec embedded in the binary and validates requests against it.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	goErrors "errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	//go:embed openapi.yml
+	specYAML []byte
+	//go:embed swagger.html
+	swaggerUI []byte
+
+	specParam = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// Spec is the loaded document with its operations indexed by the shape of their path,
+// so that the route echo matched leads straight to the operation.
+type Spec struct {
+	Doc    *openapi3.T
+	json   []byte
+	prefix string
+	routes map[string]route
+}
+
+type route struct {
+	route *routers.Route
+	// params are the path parameter names of the spec in the order they appear in the path
+	params []string
+}
+
+// Load parses and validates the embedded spec. Its first server URL is the prefix the API is mounted on.
+func Load() (*Spec, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(specYAML)
+	if err != nil {
+		return nil, err
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	specJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	spec := &Spec{Doc: doc, json: specJSON, routes: map[string]route{}}
+	if len(doc.Servers) != 0 {
+		spec.prefix = strings.TrimSuffix(doc.Servers[0].URL, "/")
+	}
+	for path, pathItem := range doc.Paths {
+		params := make([]string, 0)
+		for _, match := range specParam.FindAllStringSubmatch(path, -1) {
+			params = append(params, match[1])
+		}
+		shape := specParam.ReplaceAllString(path, "{}")
+		for method, operation := range pathItem.Operations() {
+			spec.routes[method+" "+shape] = route{
+				route:  &routers.Route{Spec: doc, Path: path, PathItem: pathItem, Method: method, Operation: operation},
+				params: params,
+			}
+		}
+	}
+	return spec, nil
+}
+
+// shape turns a route registered in echo, "/api/thread/:slug/create", into the shape of the spec path, "/thread/{}/create".
+func (s *Spec) shape(echoPath string) (string, bool) {
+	if !strings.HasPrefix(echoPath, s.prefix+"/") {
+		return "", false
+	}
+	segments := strings.Split(strings.TrimPrefix(echoPath, s.prefix), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// Middleware rejects with 400 the requests whose parameters or body do not match the spec.
+// Routes the spec does not describe are let through.
+func (s *Spec) Middleware() echo.MiddlewareFunc {
+	options := &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			shape, ok := s.shape(ctx.Path())
+			if !ok {
+				return next(ctx)
+			}
+			r, ok := s.routes[req.Method+" "+shape]
+			if !ok {
+				return next(ctx)
+			}
+			pathParams := make(map[string]string, len(r.params))
+			values := ctx.ParamValues()
+			for i, name := range r.params {
+				if i < len(values) {
+					pathParams[name] = values[i]
+				}
+			}
+			input := &openapi3filter.RequestValidationInput{Request: req, PathParams: pathParams, Route: r.route, Options: options}
+			if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+				field := describe(err)
+				return echo.NewHTTPError(http.StatusBadRequest, &models.ValidationError{
+					Message: errors.INVALID_REQUEST + field.Field + ": " + field.Reason,
+					Fields:  []models.FieldError{field},
+				})
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// describe tells in one line which field of the request is wrong and why; the errors of the validator dump the whole schema.
+// The field is named like validate names them: the parameter name, or the path in the body as in "[0].message".
+func describe(err error) models.FieldError {
+	var reqErr *openapi3filter.RequestError
+	if !goErrors.As(err, &reqErr) {
+		return models.FieldError{Field: "request", Reason: err.Error()}
+	}
+	field := models.FieldError{Field: "body"}
+	if reqErr.Parameter != nil {
+		field.Field = reqErr.Parameter.Name
+	}
+	var schemaErr *openapi3.SchemaError
+	if goErrors.As(reqErr.Err, &schemaErr) {
+		if pointer := schemaErr.JSONPointer(); len(pointer) != 0 && reqErr.Parameter == nil {
+			field.Field = fieldPath(pointer)
+		}
+		field.Reason = schemaErr.Reason
+		if field.Reason == "" {
+			field.Reason = "doesn't match " + schemaErr.SchemaField
+		}
+		// the format errors go on with the whole regular expression
+		field.Reason, _, _ = strings.Cut(field.Reason, " (regular expression")
+		return field
+	}
+	field.Reason = reqErr.Reason
+	if reqErr.Err != nil {
+		if field.Reason == "" {
+			field.Reason = reqErr.Err.Error()
+		} else {
+			field.Reason += ": " + reqErr.Err.Error()
+		}
+	}
+	return field
+}
+
+func fieldPath(pointer []string) string {
+	var path strings.Builder
+	for _, segment := range pointer {
+		if _, err := strconv.Atoi(segment); err == nil {
+			path.WriteString("[" + segment + "]")
+			continue
+		}
+		if path.Len() != 0 {
+			path.WriteByte('.')
+		}
+		path.WriteString(segment)
+	}
+	return path.String()
+}
+
+func (s *Spec) ServeJSON(ctx echo.Context) error {
+	return ctx.JSONBlob(http.StatusOK, s.json)
+}
+
+func (s *Spec) ServeUI(ctx echo.Context) error {
+	return ctx.HTMLBlob(http.StatusOK, swaggerUI)
+}
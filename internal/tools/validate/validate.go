@@ -0,0 +1,250 @@
+// Package validate checks the input of the handlers before it reaches the repos, so that bad input
+// gets a 400 that names the fields instead of a database error.
+package validate
+
+import (
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/go-openapi/strfmt"
+	"github.com/labstack/echo/v4"
+)
+
+// The limits are in characters. openapi.yml repeats them.
+const (
+	MaxNicknameLength = 64
+	MaxSlugLength     = 64
+	MaxFullnameLength = 128
+	MaxEmailLength    = 254
+	MaxAboutLength    = 4096
+	MaxTitleLength    = 256
+	MaxMessageLength  = 65536
+)
+
+// MaxSiblings caps the replies a post context takes on each side of the post. openapi.yml repeats it.
+const MaxSiblings = 100
+
+var (
+	nicknamePattern = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	slugPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	digitsPattern   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Error lists the fields that failed, by their JSON names. Fields of the i-th element of a list are "[i].name".
+type Error struct {
+	Fields []models.FieldError
+}
+
+func (e *Error) Error() string {
+	reasons := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		reasons = append(reasons, field.Field+" "+field.Reason)
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// HTTPError is the 400 of /api for err: the usual message, and the fields.
+func HTTPError(err error) *echo.HTTPError {
+	body := &models.ValidationError{Message: errors.INVALID_INPUT + err.Error()}
+	if validationErr, ok := err.(*Error); ok {
+		body.Fields = validationErr.Fields
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, body)
+}
+
+type checker struct {
+	prefix string
+	fields []models.FieldError
+}
+
+func (c *checker) fail(field string, reason string) {
+	c.fields = append(c.fields, models.FieldError{Field: c.prefix + field, Reason: reason})
+}
+
+func (c *checker) err() error {
+	if len(c.fields) == 0 {
+		return nil
+	}
+	return &Error{Fields: c.fields}
+}
+
+// text checks the length of value, and that it is set if required.
+func (c *checker) text(field string, value string, max int, required bool) bool {
+	if value == "" {
+		if required {
+			c.fail(field, "is required")
+		}
+		return false
+	}
+	if utf8.RuneCountInString(value) > max {
+		c.fail(field, "is longer than "+strconv.Itoa(max)+" characters")
+		return false
+	}
+	return true
+}
+
+func (c *checker) nickname(field string, value string) {
+	if c.text(field, value, MaxNicknameLength, true) && !nicknamePattern.MatchString(value) {
+		c.fail(field, "may only contain latin letters, digits, _ and .")
+	}
+}
+
+func (c *checker) slug(field string, value string, required bool) bool {
+	if c.text(field, value, MaxSlugLength, required) && !slugPattern.MatchString(value) {
+		c.fail(field, "may only contain latin letters, digits, _ and -")
+		return false
+	}
+	return value != ""
+}
+
+func (c *checker) email(field string, value string, required bool) {
+	if !c.text(field, value, MaxEmailLength, required) {
+		return
+	}
+	if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+		c.fail(field, "is not an email address")
+	}
+}
+
+func (c *checker) user(user *models.User) {
+	c.nickname("nickname", user.Nick)
+	c.text("fullname", user.Name, MaxFullnameLength, true)
+	c.email("email", user.Email, true)
+	c.text("about", user.About, MaxAboutLength, false)
+}
+
+func User(user *models.User) error {
+	c := &checker{}
+	c.user(user)
+	return c.err()
+}
+
+func Users(users []models.User) error {
+	c := &checker{}
+	for i := range users {
+		c.prefix = "[" + strconv.Itoa(i) + "]."
+		c.user(&users[i])
+	}
+	return c.err()
+}
+
+// UserUpdate allows the empty fields, they are kept as they are.
+func UserUpdate(user *models.User) error {
+	c := &checker{}
+	c.text("fullname", user.Name, MaxFullnameLength, false)
+	c.email("email", user.Email, false)
+	c.text("about", user.About, MaxAboutLength, false)
+	return c.err()
+}
+
+func UserRename(rename *models.UserRename) error {
+	c := &checker{}
+	c.nickname("nickname", rename.Nick)
+	return c.err()
+}
+
+func Forum(forum *models.Forum) error {
+	c := &checker{}
+	c.slug("slug", forum.Slug, true)
+	c.text("title", forum.Title, MaxTitleLength, true)
+	c.nickname("user", forum.UserNick)
+	return c.err()
+}
+
+// Thread also rejects the slugs made of digits only: /thread/{slug_or_id} would take them for an id.
+func Thread(thread *models.Thread) error {
+	c := &checker{}
+	if c.slug("slug", thread.Slug, false) && digitsPattern.MatchString(thread.Slug) {
+		c.fail("slug", "must not be a number, it would be taken for a thread id")
+	}
+	c.text("title", thread.Title, MaxTitleLength, true)
+	c.nickname("author", thread.AuthorNick)
+	c.text("message", thread.Message, MaxMessageLength, true)
+	if thread.Created != "" {
+		if _, err := strfmt.ParseDateTime(thread.Created); err != nil {
+			c.fail("created", "is not an RFC 3339 time")
+		}
+	}
+	return c.err()
+}
+
+func ThreadUpdate(thread *models.Thread) error {
+	c := &checker{}
+	c.text("title", thread.Title, MaxTitleLength, false)
+	c.text("message", thread.Message, MaxMessageLength, false)
+	return c.err()
+}
+
+func Vote(vote *models.Vote) error {
+	c := &checker{}
+	c.nickname("nickname", vote.Nick)
+	if vote.Voice != 1 && vote.Voice != -1 {
+		c.fail("voice", "must be 1 or -1")
+	}
+	return c.err()
+}
+
+func PostVote(vote *models.PostVote) error {
+	c := &checker{}
+	c.nickname("nickname", vote.Nick)
+	if vote.Voice != 1 && vote.Voice != -1 {
+		c.fail("voice", "must be 1 or -1")
+	}
+	return c.err()
+}
+
+func Reaction(reaction *models.Reaction) error {
+	c := &checker{}
+	c.nickname("nickname", reaction.Nick)
+	allowed := false
+	for _, name := range config.ReactionConfig.Allowed {
+		allowed = allowed || name == reaction.Reaction
+	}
+	if !allowed {
+		c.fail("reaction", "must be one of "+strings.Join(config.ReactionConfig.Allowed, ", "))
+	}
+	return c.err()
+}
+
+func Posts(posts []models.Post) error {
+	c := &checker{}
+	for i := range posts {
+		c.prefix = "[" + strconv.Itoa(i) + "]."
+		c.nickname("author", posts[i].AuthorNick)
+		c.text("message", posts[i].Message, MaxMessageLength, true)
+		if posts[i].ParentId < 0 {
+			c.fail("parent", "must not be negative")
+		}
+	}
+	return c.err()
+}
+
+func PostUpdate(post *models.Post) error {
+	c := &checker{}
+	c.text("message", post.Message, MaxMessageLength, false)
+	return c.err()
+}
+
+// Siblings parses the siblings query parameter, def if it is not set. Values above MaxSiblings are cut to it.
+func Siblings(param string, def int) (int, error) {
+	if param == "" {
+		return def, nil
+	}
+	siblings, err := strconv.Atoi(param)
+	if err != nil || siblings < 0 {
+		c := &checker{}
+		c.fail("siblings", "must be a number from 0 to "+strconv.Itoa(MaxSiblings))
+		return 0, c.err()
+	}
+	if siblings > MaxSiblings {
+		siblings = MaxSiblings
+	}
+	return siblings, nil
+}
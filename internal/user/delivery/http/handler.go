@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/deadline"
 	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
 	"github.com/Natali-Skv/technopark_db_forum/internal/user"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
@@ -15,7 +21,9 @@ type Handler struct {
 }
 
 const (
-	NickCtxKey = "username"
+	NickCtxKey       = "username"
+	FormatQueryParam = "format"
+	NicksQueryParam  = "nicks"
 )
 
 func NewHandler(repo user.Repo) *Handler {
@@ -27,45 +35,220 @@ func (h *Handler) CreateUser(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
 	newUserReq.Nick = ctx.Param(NickCtxKey)
-	newUserResp, err := h.Repo.Create(&newUserReq)
+	if err := validate.User(&newUserReq); err != nil {
+		return validate.HTTPError(err)
+	}
+	newUserResp, err := h.Repo.Create(ctx.Request().Context(), &newUserReq)
 	if err != nil {
-		conflictUsers, err := h.Repo.GetByEmailOrNick(&newUserReq)
+		conflictUsers, err := h.Repo.GetByEmailOrNick(ctx.Request().Context(), &newUserReq)
 		if err != nil || len(conflictUsers) == 0 {
-			return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+			return deadline.HTTPError(err)
 		}
 		return ctx.JSON(http.StatusConflict, conflictUsers)
 	}
 	return ctx.JSON(http.StatusCreated, newUserResp)
 }
 
+// CreateUsers creates the users of the body in order; the result of each is the 201 or the 409
+// CreateUser would have answered with.
+func (h *Handler) CreateUsers(ctx echo.Context) error {
+	var newUsersReq []models.User
+	if err := ctx.Bind(&newUsersReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
+	}
+	if err := validate.Users(newUsersReq); err != nil {
+		return validate.HTTPError(err)
+	}
+	results := make([]models.BulkUserResult, 0, len(newUsersReq))
+	if len(newUsersReq) == 0 {
+		return ctx.JSON(http.StatusOK, results)
+	}
+	conflicts, err := h.Repo.CreateBulk(ctx.Request().Context(), newUsersReq)
+	if err != nil {
+		return deadline.HTTPError(err)
+	}
+	for i := range newUsersReq {
+		if len(conflicts[i]) > 0 {
+			results = append(results, models.BulkUserResult{Status: http.StatusConflict, Existing: conflicts[i]})
+		} else {
+			results = append(results, models.BulkUserResult{Status: http.StatusCreated, User: &newUsersReq[i]})
+		}
+	}
+	return ctx.JSON(http.StatusOK, results)
+}
+
+// GetUsers looks up the comma separated nicks; the unknown ones are left out.
+func (h *Handler) GetUsers(ctx echo.Context) error {
+	nicks := strings.Split(ctx.QueryParam(NicksQueryParam), ",")
+	usersResp, err := h.Repo.GetByNicks(ctx.Request().Context(), nicks)
+	if err != nil {
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, usersResp)
+}
+
 func (h *Handler) GetUser(ctx echo.Context) error {
 	nick := ctx.Param(NickCtxKey)
-	userResp, err := h.Repo.GetByNick(nick)
+	userResp, err := h.Repo.GetByNick(ctx.Request().Context(), nick)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+nick)
+			return h.redirectRenamed(ctx, nick)
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+		return deadline.HTTPError(err)
 	}
 	return ctx.JSON(http.StatusOK, userResp)
 }
 
+// redirectRenamed sends the lookups by the nickname a user had before a rename to the current one.
+func (h *Handler) redirectRenamed(ctx echo.Context, nick string) error {
+	newNick, err := h.Repo.GetRenamed(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.Redirect(http.StatusPermanentRedirect, renamedPath(ctx, newNick))
+}
+
+// renamedPath is the path and query of the request with the nickname parameter replaced by newNick.
+func renamedPath(ctx echo.Context, newNick string) string {
+	path := strings.Replace(ctx.Path(), ":"+NickCtxKey, url.PathEscape(newNick), 1)
+	if query := ctx.QueryString(); query != "" {
+		path += "?" + query
+	}
+	return path
+}
+
+func (h *Handler) RenameUser(ctx echo.Context) error {
+	var rename models.UserRename
+	if err := ctx.Bind(&rename); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
+	}
+	if err := validate.UserRename(&rename); err != nil {
+		return validate.HTTPError(err)
+	}
+	nick := ctx.Param(NickCtxKey)
+	if strings.EqualFold(nick, user.Ghost.Nick) {
+		return echo.NewHTTPError(http.StatusForbidden, errors.GHOST_USER)
+	}
+	renamed, err := h.Repo.Rename(ctx.Request().Context(), nick, rename.Nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+nick)
+		}
+		if dbconn.ErrorCode(err) == "23505" {
+			conflictUser, err := h.Repo.GetByNick(ctx.Request().Context(), rename.Nick)
+			if err != nil {
+				return deadline.HTTPError(err)
+			}
+			return ctx.JSON(http.StatusConflict, conflictUser)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, renamed)
+}
+
 func (h *Handler) UpdateUser(ctx echo.Context) error {
 	var updateUserReq models.User
 	if err := ctx.Bind(&updateUserReq); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, errors.BAD_BODY)
 	}
 	updateUserReq.Nick = ctx.Param(NickCtxKey)
-	newUserResp, err := h.Repo.Update(&updateUserReq)
+	if err := validate.UserUpdate(&updateUserReq); err != nil {
+		return validate.HTTPError(err)
+	}
+	if strings.EqualFold(updateUserReq.Nick, user.Ghost.Nick) {
+		return echo.NewHTTPError(http.StatusForbidden, errors.GHOST_USER)
+	}
+	newUserResp, err := h.Repo.Update(ctx.Request().Context(), &updateUserReq)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+updateUserReq.Nick)
 		}
-		conflictUser, err := h.Repo.GetByEmail(updateUserReq.Email)
+		conflictUser, err := h.Repo.GetByEmail(ctx.Request().Context(), updateUserReq.Email)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusInternalServerError, errors.INTERNAL_SERVER_ERROR)
+			return deadline.HTTPError(err)
 		}
 		return echo.NewHTTPError(http.StatusConflict, errors.EMAIL_ALREADY_IN_USE+conflictUser)
 	}
 	return ctx.JSON(http.StatusOK, newUserResp)
 }
+
+// ExportUser answers with everything stored about the user as one JSON document or,
+// with format=ndjson, as one {"type", "data"} record per line.
+func (h *Handler) ExportUser(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	export, err := h.Repo.Export(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return h.redirectRenamed(ctx, nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	if ctx.QueryParam(FormatQueryParam) == "ndjson" {
+		ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+export.User.Nick+`.ndjson"`)
+		ctx.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+		ctx.Response().WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(ctx.Response())
+		for _, record := range exportRecords(export) {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+export.User.Nick+`.json"`)
+	return ctx.JSON(http.StatusOK, export)
+}
+
+// GetKarma sums up the voices given to the threads and posts of the user.
+func (h *Handler) GetKarma(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	karma, err := h.Repo.Karma(ctx.Request().Context(), nick)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return h.redirectRenamed(ctx, nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.JSON(http.StatusOK, karma)
+}
+
+func exportRecords(export *models.UserExport) []models.ExportRecord {
+	records := make([]models.ExportRecord, 0, 1+len(export.Threads)+len(export.Posts)+len(export.Votes)+len(export.PostVotes)+len(export.Reactions)+len(export.Forums))
+	records = append(records, models.ExportRecord{Type: "user", Data: export.User})
+	for _, thread := range export.Threads {
+		records = append(records, models.ExportRecord{Type: "thread", Data: thread})
+	}
+	for _, post := range export.Posts {
+		records = append(records, models.ExportRecord{Type: "post", Data: post})
+	}
+	for _, vote := range export.Votes {
+		records = append(records, models.ExportRecord{Type: "vote", Data: vote})
+	}
+	for _, vote := range export.PostVotes {
+		records = append(records, models.ExportRecord{Type: "post_vote", Data: vote})
+	}
+	for _, reaction := range export.Reactions {
+		records = append(records, models.ExportRecord{Type: "reaction", Data: reaction})
+	}
+	for _, forum := range export.Forums {
+		records = append(records, models.ExportRecord{Type: "forum", Data: forum})
+	}
+	return records
+}
+
+func (h *Handler) DeleteUser(ctx echo.Context) error {
+	nick := ctx.Param(NickCtxKey)
+	if strings.EqualFold(nick, user.Ghost.Nick) {
+		return echo.NewHTTPError(http.StatusForbidden, errors.GHOST_USER)
+	}
+	if err := h.Repo.Delete(ctx.Request().Context(), nick); err != nil {
+		if err == pgx.ErrNoRows {
+			return echo.NewHTTPError(http.StatusNotFound, errors.NOT_FOUND_USER_BY_NICK+nick)
+		}
+		return deadline.HTTPError(err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
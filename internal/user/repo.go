@@ -1,13 +1,35 @@
 package user
 
 import (
+	"context"
+
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
 )
 
+// Ghost is the account the threads, posts and forums of deleted users are handed over to.
+// Its nickname and email can't be registered, so it never collides with a real user.
+var Ghost = models.User{Name: "Deleted user", Nick: "deleted-user", Email: "deleted@deleted.invalid"}
+
 type Repo interface {
-	Create(user *models.User) (*models.User, error)
-	GetByEmailOrNick(user *models.User) ([]models.User, error)
-	GetByNick(nick string) (*models.User, error)
-	GetByEmail(email string) (string, error)
-	Update(user *models.User) (*models.User, error)
+	Create(ctx context.Context, user *models.User) (*models.User, error)
+	GetByEmailOrNick(ctx context.Context, user *models.User) ([]models.User, error)
+	// CreateBulk creates the users as if one by one in order. conflicts[i] lists the users that kept
+	// users[i] from being created, it is empty for the created ones.
+	CreateBulk(ctx context.Context, users []models.User) (conflicts [][]models.User, err error)
+	GetByNick(ctx context.Context, nick string) (*models.User, error)
+	// GetByNicks returns the users in the order of nicks, once each; the missing ones are skipped.
+	GetByNicks(ctx context.Context, nicks []string) ([]models.User, error)
+	GetByEmail(ctx context.Context, email string) (string, error)
+	Update(ctx context.Context, user *models.User) (*models.User, error)
+	// Rename changes the nickname everywhere it is copied and keeps the old one as a redirect.
+	Rename(ctx context.Context, nick string, newNick string) (*models.User, error)
+	// GetRenamed returns the current nickname of the user who had nick before a rename.
+	GetRenamed(ctx context.Context, nick string) (string, error)
+	// Export collects everything stored about the user, as of one moment.
+	Export(ctx context.Context, nick string) (*models.UserExport, error)
+	// Karma sums up the votes cast on the threads and posts of the user.
+	Karma(ctx context.Context, nick string) (*models.Karma, error)
+	// Delete hands the content of the user over to Ghost, retracts their votes and removes
+	// them from the forum users.
+	Delete(ctx context.Context, nick string) error
 }
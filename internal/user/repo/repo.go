@@ -1,40 +1,84 @@
 package userRepo
 
 import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
 	"github.com/Natali-Skv/technopark_db_forum/internal/models"
-	"github.com/jackc/pgx"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/tracing"
+	"github.com/Natali-Skv/technopark_db_forum/internal/user"
+	"github.com/go-openapi/strfmt"
+	"github.com/jackc/pgx/v5"
 )
 
 type Repo struct {
-	Conn *pgx.ConnPool
+	Conn *dbconn.Pool
 }
 
-func NewRepo(conn *pgx.ConnPool) *Repo {
-	conn.Prepare("create_user", "INSERT into users(name, nick, email, about) VALUES ($1,$2,$3,$4)")
-	conn.Prepare("update_user", "UPDATE users SET name=COALESCE(NULLIF($1, ''), name), email=COALESCE(NULLIF($2, ''), email), about=COALESCE(NULLIF($3, ''), about) WHERE nick = $4 RETURNING name,nick,email,about")
-	conn.Prepare("get_user_by_email_or_nick", "SELECT name,nick,email,about FROM users WHERE nick=$1 OR email=$2")
-	conn.Prepare("get_user_by_nick", "SELECT name, nick, email, about FROM users WHERE nick=$1")
-	conn.Prepare("get_user_by_email", "SELECT nick FROM users WHERE email=$1")
+func NewRepo(conn *dbconn.Pool) *Repo {
+	conn.Register("create_user", "INSERT into users(name, nick, email, about) VALUES ($1,$2,$3,$4)")
+	conn.Register("update_user", "UPDATE users SET name=COALESCE(NULLIF($1, ''), name), email=COALESCE(NULLIF($2, ''), email), about=COALESCE(NULLIF($3, ''), about) WHERE nick = $4 RETURNING name,nick,email,about")
+	conn.Register("get_user_by_email_or_nick", "SELECT name,nick,email,about FROM users WHERE nick=$1 OR email=$2")
+	conn.Register("get_user_karma", "SELECT u.nick, (SELECT COALESCE(sum(votes), 0) FROM threads WHERE author_nick=u.nick), (SELECT COALESCE(sum(votes), 0) FROM posts WHERE author_nick=u.nick) FROM users u WHERE u.nick=$1")
+	conn.Register("get_user_by_nick", "SELECT name, nick, email, about FROM users WHERE nick=$1")
+	conn.Register("get_users_by_nicks", "SELECT u.name, u.nick, u.email, u.about FROM (SELECT nick::citext AS nick, min(ord) AS ord FROM unnest($1::text[]) WITH ORDINALITY AS n(nick, ord) GROUP BY 1) n JOIN users u ON u.nick=n.nick ORDER BY n.ord")
+	conn.Register("get_user_by_email", "SELECT nick FROM users WHERE email=$1")
+	conn.Register("lock_user_by_nick", "SELECT id, nick FROM users WHERE nick=$1 FOR UPDATE")
+	conn.Register("rename_user", "UPDATE users SET nick=$1 WHERE id=$2 RETURNING name,nick,email,about")
+	conn.Register("drop_user_rename", "DELETE FROM user_renames WHERE old_nick=$1")
+	conn.Register("add_user_rename", "INSERT INTO user_renames(old_nick, user_id) VALUES ($1,$2) ON CONFLICT (old_nick) DO UPDATE SET user_id=EXCLUDED.user_id")
+	conn.Register("get_renamed_user", "SELECT u.nick FROM user_renames r JOIN users u ON u.id=r.user_id WHERE r.old_nick=$1")
+	conn.Register("export_snapshot", "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY")
+	conn.Register("export_threads", "SELECT id, slug, title, author_nick, forum_slug, message, votes, created, posts_count, CASE WHEN posts_count>0 THEN last_post_at END, COALESCE(last_post_author, ''), participants_count FROM threads WHERE author_nick=$1 ORDER BY id")
+	conn.Register("export_posts", "SELECT id, parent_id, author_nick, forum_slug, thread_id, message, created, is_edited, votes, children, post_descendants(thread_id, path), array_length(path, 1) - 1 FROM posts WHERE author_nick=$1 ORDER BY id")
+	conn.Register("export_votes", "SELECT user_nick, thread_id, vote FROM votes WHERE user_nick=$1 ORDER BY thread_id")
+	conn.Register("export_post_votes", "SELECT user_nick, post_id, vote FROM post_votes WHERE user_nick=$1 ORDER BY post_id")
+	conn.Register("export_reactions", "SELECT post_id, 0, reaction FROM post_reactions WHERE user_nick=$1 UNION ALL SELECT 0, thread_id, reaction FROM thread_reactions WHERE user_nick=$2 ORDER BY 2, 1, 3")
+	conn.Register("export_forums", "SELECT forum_slug, posts, threads, first_activity, last_activity FROM forum_users WHERE nick=$1 ORDER BY forum_slug")
+	conn.Register("create_ghost", "INSERT INTO users(name, nick, email, about) VALUES ($1,$2,$3,'') ON CONFLICT DO NOTHING")
+	conn.Register("get_user_id", "SELECT id FROM users WHERE nick=$1")
+	conn.Register("retract_user_votes", "DELETE FROM votes WHERE user_nick=$1")
+	conn.Register("retract_user_post_votes", "DELETE FROM post_votes WHERE user_nick=$1")
+	conn.Register("retract_user_post_reactions", "DELETE FROM post_reactions WHERE user_nick=$1")
+	conn.Register("retract_user_thread_reactions", "DELETE FROM thread_reactions WHERE user_nick=$1")
+	conn.Register("ghost_forums", "UPDATE forums SET author_nick=$1 WHERE author_nick=$2")
+	conn.Register("ghost_threads", "UPDATE threads SET author_nick=$1 WHERE author_nick=$2")
+	conn.Register("ghost_last_posts", "UPDATE threads SET last_post_author=$1 WHERE last_post_author=$2")
+	// the ghost may already take part in a thread: then the user is one participant less
+	conn.Register("merge_ghost_participants", "WITH merged AS (DELETE FROM thread_participants p WHERE nick=$2 AND EXISTS (SELECT 1 FROM thread_participants g WHERE g.thread_id=p.thread_id AND g.nick=$1) RETURNING thread_id) UPDATE threads t SET participants_count=participants_count-1 FROM merged WHERE t.id=merged.thread_id")
+	conn.Register("ghost_participants", "UPDATE thread_participants SET nick=$1 WHERE nick=$2")
+	conn.Register("ghost_posts", "UPDATE posts SET author_nick=$1, author_id=$2 WHERE author_nick=$3")
+	conn.Register("purge_forum_users", "DELETE FROM forum_users WHERE nick=$1")
+	conn.Register("delete_user", "DELETE FROM users WHERE id=$1")
 
 	return &Repo{Conn: conn}
 }
-func (r *Repo) Create(user *models.User) (*models.User, error) {
-	_, err := r.Conn.Exec(`EXECUTE create_user($1,$2,$3,$4)`, user.Name, user.Nick, user.Email, user.About)
+func (r *Repo) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.Create")
+	defer span.End()
+	_, err := r.Conn.Exec(ctx, "create_user", user.Name, user.Nick, user.Email, user.About)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
-func (r *Repo) Update(user *models.User) (*models.User, error) {
-	err := r.Conn.QueryRow("EXECUTE update_user($1,$2,$3,$4)", user.Name, user.Email, user.About, user.Nick).Scan(&user.Name, &user.Nick, &user.Email, &user.About)
+func (r *Repo) Update(ctx context.Context, user *models.User) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.Update")
+	defer span.End()
+	err := r.Conn.QueryRow(ctx, "update_user", user.Name, user.Email, user.About, user.Nick).Scan(&user.Name, &user.Nick, &user.Email, &user.About)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
-func (r *Repo) GetByEmailOrNick(user *models.User) ([]models.User, error) {
+func (r *Repo) GetByEmailOrNick(ctx context.Context, user *models.User) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.GetByEmailOrNick")
+	defer span.End()
 	userResp := make([]models.User, 0, 2)
-	userRows, err := r.Conn.Query(`EXECUTE get_user_by_email_or_nick($1,$2)`, user.Nick, user.Email)
+	userRows, err := r.Conn.Query(ctx, "get_user_by_email_or_nick", user.Nick, user.Email)
 	if err != nil {
 		return nil, err
 	}
@@ -49,19 +93,360 @@ func (r *Repo) GetByEmailOrNick(user *models.User) ([]models.User, error) {
 	}
 	return userResp, nil
 }
-func (r *Repo) GetByNick(nick string) (*models.User, error) {
+func (r *Repo) GetByNick(ctx context.Context, nick string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.GetByNick")
+	defer span.End()
 	user := &models.User{}
-	err := r.Conn.QueryRow("EXECUTE get_user_by_nick($1)", nick).Scan(&user.Name, &user.Nick, &user.Email, &user.About)
+	err := r.Conn.QueryRow(ctx, "get_user_by_nick", nick).Scan(&user.Name, &user.Nick, &user.Email, &user.About)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
-func (r *Repo) GetByEmail(email string) (string, error) {
+func (r *Repo) GetByNicks(ctx context.Context, nicks []string) ([]models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.GetByNicks")
+	defer span.End()
+	usersResp := make([]models.User, 0, len(nicks))
+	rows, err := r.Conn.Query(ctx, "get_users_by_nicks", nicks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user := models.User{}
+		if err = rows.Scan(&user.Name, &user.Nick, &user.Email, &user.About); err != nil {
+			return nil, err
+		}
+		usersResp = append(usersResp, user)
+	}
+	return usersResp, rows.Err()
+}
+
+// The import statements run inline: users_import only exists inside the transaction of CreateBulk,
+// so they can't be prepared when the connection is opened. Its columns are text, COPY does not know citext.
+const (
+	createUsersImport = `CREATE TEMP TABLE users_import (ord integer PRIMARY KEY, name text, nick text, email text, about text) ON COMMIT DROP`
+	// the users are inserted in the order of the request, so a user conflicting with an earlier one of
+	// the same request loses to it; the ord of a created user is the first import row it matches
+	insertUsersImport = `WITH created AS (
+		INSERT INTO users(name, nick, email, about) SELECT name, nick, email, about FROM users_import ORDER BY ord
+		ON CONFLICT DO NOTHING RETURNING nick, email)
+	SELECT min(i.ord) FROM created c JOIN users_import i ON c.nick=i.nick::citext AND c.email=i.email::citext GROUP BY c.nick`
+	usersImportConflicts = `SELECT i.ord, u.name, u.nick, u.email, u.about FROM users_import i JOIN users u ON u.nick=i.nick::citext OR u.email=i.email::citext
+	WHERE i.ord <> ALL($1) ORDER BY i.ord, u.id`
+)
+
+// CreateBulk sends the users with COPY into a temporary table and inserts them from there with one statement.
+func (r *Repo) CreateBulk(ctx context.Context, users []models.User) ([][]models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.CreateBulk")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, createUsersImport); err != nil {
+		return nil, err
+	}
+	importRows := make([][]any, 0, len(users))
+	for i, user := range users {
+		importRows = append(importRows, []any{int32(i), user.Name, user.Nick, user.Email, user.About})
+	}
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"users_import"}, []string{"ord", "name", "nick", "email", "about"}, importRows); err != nil {
+		return nil, err
+	}
+
+	created := make([]int32, 0, len(users))
+	rows, err := tx.Query(ctx, insertUsersImport)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ord int32
+		if err = rows.Scan(&ord); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		created = append(created, ord)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	conflicts := make([][]models.User, len(users))
+	rows, err = tx.Query(ctx, usersImportConflicts, created)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var ord int32
+		user := models.User{}
+		if err = rows.Scan(&ord, &user.Name, &user.Nick, &user.Email, &user.About); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		conflicts[ord] = append(conflicts[ord], user)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return conflicts, tx.Commit(ctx)
+}
+
+func (r *Repo) Karma(ctx context.Context, nick string) (*models.Karma, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.Karma")
+	defer span.End()
+	karma := &models.Karma{}
+	err := r.Conn.QueryRow(ctx, "get_user_karma", nick).Scan(&karma.Nick, &karma.ThreadVotes, &karma.PostVotes)
+	if err != nil {
+		return nil, err
+	}
+	karma.Karma = karma.ThreadVotes + karma.PostVotes
+	return karma, nil
+}
+
+func (r *Repo) GetByEmail(ctx context.Context, email string) (string, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.GetByEmail")
+	defer span.End()
 	var userNick string
-	err := r.Conn.QueryRow("EXECUTE get_user_by_email($1)", email).Scan(&userNick)
+	err := r.Conn.QueryRow(ctx, "get_user_by_email", email).Scan(&userNick)
 	if err != nil {
 		return "", err
 	}
 	return userNick, nil
 }
+
+// Rename relies on the ON UPDATE CASCADE foreign keys of db/db.sql to rename the copies of the nickname
+// in forums, threads, posts, votes and forum_users in the same transaction.
+func (r *Repo) Rename(ctx context.Context, nick string, newNick string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.Rename")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userId int64
+	if err = tx.QueryRow(ctx, "lock_user_by_nick", nick).Scan(&userId, &nick); err != nil {
+		return nil, err
+	}
+	user := &models.User{}
+	err = tx.QueryRow(ctx, "rename_user", newNick, userId).Scan(&user.Name, &user.Nick, &user.Email, &user.About)
+	if err != nil {
+		return nil, err
+	}
+	// the new nickname is taken now, it no longer leads to whoever had it before
+	if _, err = tx.Exec(ctx, "drop_user_rename", newNick); err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(nick, newNick) {
+		if _, err = tx.Exec(ctx, "add_user_rename", nick, userId); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *Repo) GetRenamed(ctx context.Context, nick string) (string, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.GetRenamed")
+	defer span.End()
+	var newNick string
+	err := r.Conn.QueryRow(ctx, "get_renamed_user", nick).Scan(&newNick)
+	return newNick, err
+}
+
+// Export reads in one repeatable read transaction, so the parts of the export agree with each other.
+func (r *Repo) Export(ctx context.Context, nick string) (*models.UserExport, error) {
+	ctx, span := tracing.Start(ctx, "userRepo.Export")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err = tx.Exec(ctx, "export_snapshot"); err != nil {
+		return nil, err
+	}
+
+	export := &models.UserExport{
+		Threads:   make([]models.Thread, 0),
+		Posts:     make([]models.Post, 0),
+		Votes:     make([]models.Vote, 0),
+		PostVotes: make([]models.PostVote, 0),
+		Reactions: make([]models.Reaction, 0),
+		Forums:    make([]models.ForumMembership, 0),
+	}
+	u := &export.User
+	if err = tx.QueryRow(ctx, "get_user_by_nick", nick).Scan(&u.Name, &u.Nick, &u.Email, &u.About); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "export_threads", u.Nick)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		thread := models.Thread{}
+		var created time.Time
+		var lastPostAt *time.Time
+		var slug sql.NullString
+		if err = rows.Scan(&thread.Id, &slug, &thread.Title, &thread.AuthorNick, &thread.ForumSlug, &thread.Message, &thread.Votes, &created,
+			&thread.PostsCount, &lastPostAt, &thread.LastPostAuthor, &thread.ParticipantsCount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		thread.Created = strfmt.DateTime(created.UTC()).String()
+		thread.Slug = slug.String
+		if lastPostAt != nil {
+			thread.LastPostAt = strfmt.DateTime(lastPostAt.UTC()).String()
+		}
+		export.Threads = append(export.Threads, thread)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = tx.Query(ctx, "export_posts", u.Nick); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		post := models.Post{}
+		parentId := sql.NullInt64{}
+		var created time.Time
+		if err = rows.Scan(&post.Id, &parentId, &post.AuthorNick, &post.ForumSlug, &post.ThreadId, &post.Message, &created, &post.IsEdited, &post.Votes, &post.Children, &post.Descendants, &post.Depth); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		post.ParentId = int(parentId.Int64)
+		post.Created = strfmt.DateTime(created.UTC()).String()
+		export.Posts = append(export.Posts, post)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = tx.Query(ctx, "export_votes", u.Nick); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		vote := models.Vote{}
+		if err = rows.Scan(&vote.Nick, &vote.ThreadId, &vote.Voice); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Votes = append(export.Votes, vote)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = tx.Query(ctx, "export_post_votes", u.Nick); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		vote := models.PostVote{}
+		if err = rows.Scan(&vote.Nick, &vote.PostId, &vote.Voice); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.PostVotes = append(export.PostVotes, vote)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = tx.Query(ctx, "export_reactions", u.Nick, u.Nick); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		reaction := models.Reaction{Nick: u.Nick}
+		if err = rows.Scan(&reaction.PostId, &reaction.ThreadId, &reaction.Reaction); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		export.Reactions = append(export.Reactions, reaction)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rows, err = tx.Query(ctx, "export_forums", u.Nick); err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		forum := models.ForumMembership{}
+		var firstActivity, lastActivity time.Time
+		if err = rows.Scan(&forum.Forum, &forum.Posts, &forum.Threads, &firstActivity, &lastActivity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		forum.FirstActivity = strfmt.DateTime(firstActivity.UTC()).String()
+		forum.LastActivity = strfmt.DateTime(lastActivity.UTC()).String()
+		export.Forums = append(export.Forums, forum)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Delete runs in one transaction. The triggers of db/db.sql take care of the vote sums and of the
+// forum and global counters.
+func (r *Repo) Delete(ctx context.Context, nick string) error {
+	ctx, span := tracing.Start(ctx, "userRepo.Delete")
+	defer span.End()
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var userId int64
+	if err = tx.QueryRow(ctx, "lock_user_by_nick", nick).Scan(&userId, &nick); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, "create_ghost", user.Ghost.Name, user.Ghost.Nick, user.Ghost.Email); err != nil {
+		return err
+	}
+	var ghostId int64
+	if err = tx.QueryRow(ctx, "get_user_id", user.Ghost.Nick).Scan(&ghostId); err != nil {
+		return err
+	}
+	for _, query := range []string{"retract_user_votes", "retract_user_post_votes", "retract_user_post_reactions", "retract_user_thread_reactions"} {
+		if _, err = tx.Exec(ctx, query, nick); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(ctx, "ghost_forums", user.Ghost.Nick, nick); err != nil {
+		return err
+	}
+	for _, query := range []string{"ghost_threads", "ghost_last_posts", "merge_ghost_participants", "ghost_participants"} {
+		if _, err = tx.Exec(ctx, query, user.Ghost.Nick, nick); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(ctx, "ghost_posts", user.Ghost.Nick, ghostId, nick); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, "purge_forum_users", nick); err != nil {
+		return err
+	}
+	// user_renames pointing at the user go with it: ON DELETE CASCADE
+	if _, err = tx.Exec(ctx, "delete_user", userId); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,942 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/configRouting"
+	"github.com/Natali-Skv/technopark_db_forum/internal/apiv2"
+	"github.com/Natali-Skv/technopark_db_forum/internal/maintenance"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/auth"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/errors"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/validate"
+	"github.com/labstack/echo/v4"
+)
+
+type message struct {
+	Message string `json:"message"`
+}
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		alice := c.createUser("Alice")
+		bob := c.createUser("bob")
+
+		var profile models.User
+		c.get("/api/user/ALICE/profile", http.StatusOK, &profile)
+		if profile != alice {
+			t.Errorf("profile %+v, want %+v", profile, alice)
+		}
+		c.get("/api/user/nobody/profile", http.StatusNotFound, &message{})
+
+		// a new user clashing with alice by nick and with bob by email gets both back
+		var conflicts []models.User
+		c.post("/api/user/alice/create", models.User{Name: "x", Email: "BOB@mail.ru"}, http.StatusConflict, &conflicts)
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Nick < conflicts[j].Nick })
+		if len(conflicts) != 2 || conflicts[0] != alice || conflicts[1] != bob {
+			t.Errorf("conflicts %+v, want alice and bob", conflicts)
+		}
+
+		var updated models.User
+		c.post("/api/user/alice/profile", models.User{About: "new about"}, http.StatusOK, &updated)
+		if updated.Nick != alice.Nick || updated.About != "new about" || updated.Email != alice.Email {
+			t.Errorf("updated %+v", updated)
+		}
+		c.post("/api/user/alice/profile", models.User{Email: bob.Email}, http.StatusConflict, &message{})
+		c.post("/api/user/nobody/profile", models.User{About: "x"}, http.StatusNotFound, &message{})
+	})
+}
+
+func TestForums(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		alice := c.createUser("Alice")
+		forum := c.createForum("pirates", "alice")
+		if forum.UserNick != alice.Nick {
+			t.Errorf("forum author %q, want the nick as registered, %q", forum.UserNick, alice.Nick)
+		}
+
+		var existing models.Forum
+		c.post("/api/forum/create", models.Forum{Slug: "PIRATES", Title: "other", UserNick: "alice"}, http.StatusConflict, &existing)
+		if existing.Slug != "pirates" || existing.Title != forum.Title {
+			t.Errorf("conflict returned %+v, want %+v", existing, forum)
+		}
+		c.post("/api/forum/create", models.Forum{Slug: "ninjas", Title: "t", UserNick: "nobody"}, http.StatusNotFound, &message{})
+
+		var details models.Forum
+		c.get("/api/forum/Pirates/details", http.StatusOK, &details)
+		if details != forum {
+			t.Errorf("details %+v, want %+v", details, forum)
+		}
+		c.get("/api/forum/ninjas/details", http.StatusNotFound, &message{})
+		c.get("/api/forum/ninjas/threads", http.StatusNotFound, &message{})
+		c.get("/api/forum/ninjas/users", http.StatusNotFound, &message{})
+	})
+}
+
+func TestForumThreads(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		created := []string{"2020-01-01T00:00:00.000Z", "2020-01-03T00:00:00.000Z", "2020-01-02T00:00:00.000Z"}
+		for i, at := range created {
+			c.createThread("pirates", models.Thread{Title: "t" + strconv.Itoa(i), AuthorNick: "alice", Message: "m", Created: at})
+		}
+
+		cases := []struct {
+			query string
+			want  []string
+		}{
+			{"", []string{"t0", "t2", "t1"}},
+			{"?desc=true", []string{"t1", "t2", "t0"}},
+			{"?limit=2", []string{"t0", "t2"}},
+			{"?since=2020-01-02T00:00:00.000Z", []string{"t2", "t1"}},
+			{"?since=2020-01-02T00:00:00.000Z&desc=true", []string{"t2", "t0"}},
+			{"?since=2020-01-02T00:00:00.000Z&desc=true&limit=1", []string{"t2"}},
+		}
+		for _, tc := range cases {
+			var threads []models.Thread
+			c.get("/api/forum/pirates/threads"+tc.query, http.StatusOK, &threads)
+			titles := make([]string, 0, len(threads))
+			for _, thread := range threads {
+				titles = append(titles, thread.Title)
+			}
+			if strings.Join(titles, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("threads%s: got %v, want %v", tc.query, titles, tc.want)
+			}
+		}
+	})
+}
+
+func TestForumThreadRanking(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		for _, nick := range []string{"alice", "bob", "carol"} {
+			c.createUser(nick)
+		}
+		c.createForum("pirates", "alice")
+		created := []string{"2020-01-01T00:00:00.000Z", "2020-01-02T00:00:00.000Z", "2020-01-03T00:00:00.000Z", ""}
+		ids := map[string]int{}
+		for i, at := range created {
+			thread := c.createThread("pirates", models.Thread{Title: "t" + strconv.Itoa(i), AuthorNick: "alice", Message: "m", Created: at})
+			ids[thread.Title] = thread.Id
+		}
+		votes := []struct {
+			thread string
+			nick   string
+			voice  int
+		}{
+			{"t0", "alice", 1}, {"t0", "bob", 1}, {"t0", "carol", 1},
+			{"t2", "alice", -1}, {"t2", "bob", -1},
+			{"t3", "alice", 1},
+		}
+		for _, vote := range votes {
+			c.post("/api/thread/"+strconv.Itoa(ids[vote.thread])+"/vote", models.Vote{Nick: vote.nick, Voice: vote.voice}, http.StatusOK, nil)
+		}
+		c.createPosts(strconv.Itoa(ids["t0"]), models.Post{AuthorNick: "bob", Message: "m"})
+
+		// a day is worth more than the few votes: hot goes by age first
+		cases := []struct {
+			query string
+			want  []string
+		}{
+			{"?sort=hot&desc=true", []string{"t3", "t2", "t1", "t0"}},
+			{"?sort=hot", []string{"t0", "t1", "t2", "t3"}},
+			{"?sort=top&desc=true", []string{"t0", "t3", "t1", "t2"}},
+			{"?sort=top&desc=true&period=week", []string{"t3"}},
+			{"?sort=top&desc=true&since=" + strconv.Itoa(ids["t3"]), []string{"t1", "t2"}},
+			{"?sort=active&desc=true", []string{"t0", "t3", "t2", "t1"}},
+			{"?sort=active&limit=2&since=" + strconv.Itoa(ids["t1"]), []string{"t2", "t3"}},
+			{"?sort=active&since=100500", []string{}},
+		}
+		for _, tc := range cases {
+			var threads []models.Thread
+			c.get("/api/forum/pirates/threads"+tc.query, http.StatusOK, &threads)
+			titles := make([]string, 0, len(threads))
+			for _, thread := range threads {
+				titles = append(titles, thread.Title)
+			}
+			if strings.Join(titles, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("threads%s: got %v, want %v", tc.query, titles, tc.want)
+			}
+		}
+		c.get("/api/forum/pirates/threads?sort=top&since=2020-01-01T00:00:00.000Z", http.StatusBadRequest, &message{})
+		c.get("/api/forum/pirates/threads?sort=best", http.StatusBadRequest, &message{})
+		c.get("/api/forum/pirates/threads?sort=top&period=decade", http.StatusBadRequest, &message{})
+		c.get("/api/forum/nothing/threads?sort=hot", http.StatusNotFound, &message{})
+
+		threads := pages[apiv2.Thread](c, "/api/v2/forums/pirates/threads?sort=top&desc=true", 1, nil)
+		titles := make([]string, 0, len(threads))
+		for _, thread := range threads {
+			titles = append(titles, thread.Title)
+		}
+		if strings.Join(titles, " ") != "t0 t3 t1 t2" {
+			t.Errorf("v2 top threads %v", titles)
+		}
+		c.expectError(http.MethodGet, "/api/v2/forums/pirates/threads?sort=hot&cursor=bogus", nil, http.StatusBadRequest, apiv2.CodeInvalidCursor)
+		c.expectError(http.MethodGet, "/api/v2/forums/pirates/threads?sort=best", nil, http.StatusBadRequest, apiv2.CodeValidation)
+	})
+}
+
+func TestForumUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		for _, nick := range []string{"carol", "Bob", "alice", "dave"} {
+			c.createUser(nick)
+		}
+		c.createForum("pirates", "dave")
+		thread := c.createThread("pirates", models.Thread{Title: "t", AuthorNick: "carol", Message: "m", Created: "2020-01-01T00:00:00.000Z"})
+		c.createPosts(strconv.Itoa(thread.Id), models.Post{AuthorNick: "alice", Message: "m"}, models.Post{AuthorNick: "BOB", Message: "m"})
+		c.createPosts(strconv.Itoa(thread.Id), models.Post{AuthorNick: "alice", Message: "again"})
+		c.post("/api/user/alice/profile", models.User{About: "pirate"}, http.StatusOK, nil)
+
+		// dave only created the forum, so he is not one of its users; nicks compare case-insensitively
+		cases := []struct {
+			query string
+			want  []string
+		}{
+			{"", []string{"alice", "Bob", "carol"}},
+			{"?desc=true", []string{"carol", "Bob", "alice"}},
+			{"?since=alice&limit=1", []string{"Bob"}},
+			{"?since=Carol&desc=true", []string{"Bob", "alice"}},
+			// alice wrote last, carol's thread is back in 2020
+			{"?sort=activity", []string{"carol", "Bob", "alice"}},
+			{"?sort=activity&desc=true&limit=2", []string{"alice", "Bob"}},
+			{"?sort=activity&since=bob", []string{"alice"}},
+			{"?sort=activity&since=alice&desc=true", []string{"Bob", "carol"}},
+			{"?sort=activity&since=dave", []string{}},
+		}
+		for _, tc := range cases {
+			var users []models.User
+			c.get("/api/forum/pirates/users"+tc.query, http.StatusOK, &users)
+			nicks := make([]string, 0, len(users))
+			for _, user := range users {
+				nicks = append(nicks, user.Nick)
+			}
+			if strings.Join(nicks, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("users%s: got %v, want %v", tc.query, nicks, tc.want)
+			}
+		}
+		c.get("/api/forum/pirates/users?sort=votes", http.StatusBadRequest, &message{})
+
+		// the profile comes from the user, so the update above shows up
+		var users []models.ForumUser
+		c.get("/api/forum/pirates/users", http.StatusOK, &users)
+		alice, carol := users[0], users[2]
+		if alice.About != "pirate" || alice.Posts != 2 || alice.Threads != 0 || alice.FirstActivity > alice.LastActivity {
+			t.Errorf("alice in the forum: %+v", alice)
+		}
+		if carol.Posts != 0 || carol.Threads != 1 ||
+			carol.FirstActivity != "2020-01-01T00:00:00.000Z" || carol.LastActivity != "2020-01-01T00:00:00.000Z" {
+			t.Errorf("carol in the forum: %+v", carol)
+		}
+	})
+}
+
+func TestThreads(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("Pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "Treasure", Title: "t", AuthorNick: "ALICE", Message: "m", Created: "2020-01-01T00:00:00.000Z"})
+		if thread.Id == 0 || thread.ForumSlug != "Pirates" || thread.AuthorNick != "alice" {
+			t.Errorf("created %+v", thread)
+		}
+		noSlug := c.createThread("pirates", models.Thread{Title: "no slug", AuthorNick: "alice", Message: "m"})
+
+		var existing models.Thread
+		c.post("/api/forum/pirates/create", models.Thread{Slug: "treasure", Title: "x", AuthorNick: "alice", Message: "m"}, http.StatusConflict, &existing)
+		if existing.Id != thread.Id {
+			t.Errorf("conflict returned %+v, want %+v", existing, thread)
+		}
+		c.post("/api/forum/ninjas/create", models.Thread{Title: "x", AuthorNick: "alice", Message: "m"}, http.StatusNotFound, &message{})
+		c.post("/api/forum/pirates/create", models.Thread{Title: "x", AuthorNick: "nobody", Message: "m"}, http.StatusNotFound, &message{})
+
+		var bySlug, byId models.Thread
+		c.get("/api/thread/treasure/details", http.StatusOK, &bySlug)
+		c.get("/api/thread/"+strconv.Itoa(thread.Id)+"/details", http.StatusOK, &byId)
+		if !reflect.DeepEqual(bySlug, byId) || bySlug.Slug != "Treasure" || bySlug.Created != "2020-01-01T00:00:00.000Z" {
+			t.Errorf("by slug %+v, by id %+v", bySlug, byId)
+		}
+		var slugless models.Thread
+		c.get("/api/thread/"+strconv.Itoa(noSlug.Id)+"/details", http.StatusOK, &slugless)
+		if slugless.Slug != "" || slugless.Title != "no slug" {
+			t.Errorf("thread without slug %+v", slugless)
+		}
+		c.get("/api/thread/nothing/details", http.StatusNotFound, &message{})
+		c.get("/api/thread/100500/details", http.StatusNotFound, &message{})
+
+		var updated models.Thread
+		c.post("/api/thread/treasure/details", models.Thread{Title: "new title"}, http.StatusOK, &updated)
+		c.post("/api/thread/"+strconv.Itoa(thread.Id)+"/details", models.Thread{Message: "new message"}, http.StatusOK, &updated)
+		if updated.Title != "new title" || updated.Message != "new message" {
+			t.Errorf("updated %+v", updated)
+		}
+		c.post("/api/thread/nothing/details", models.Thread{Title: "x"}, http.StatusNotFound, &message{})
+
+		var forum models.Forum
+		c.get("/api/forum/pirates/details", http.StatusOK, &forum)
+		if forum.Threads != 2 {
+			t.Errorf("forum threads %d, want 2", forum.Threads)
+		}
+	})
+}
+
+func TestVotes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createUser("bob")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		byId := "/api/thread/" + strconv.Itoa(thread.Id) + "/vote"
+
+		steps := []struct {
+			path  string
+			vote  models.Vote
+			votes int
+		}{
+			{"/api/thread/treasure/vote", models.Vote{Nick: "alice", Voice: 1}, 1},
+			{byId, models.Vote{Nick: "bob", Voice: 1}, 2},
+			// re-casting replaces the old voice instead of adding to it
+			{byId, models.Vote{Nick: "ALICE", Voice: -1}, 0},
+			{"/api/thread/TREASURE/vote", models.Vote{Nick: "alice", Voice: -1}, 0},
+			{byId, models.Vote{Nick: "bob", Voice: -1}, -2},
+		}
+		for i, step := range steps {
+			var voted models.Thread
+			c.post(step.path, step.vote, http.StatusOK, &voted)
+			if voted.Votes != step.votes || voted.Id != thread.Id {
+				t.Errorf("step %d: votes %d, want %d", i, voted.Votes, step.votes)
+			}
+		}
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "nobody", Voice: 1}, http.StatusNotFound, &message{})
+		c.post("/api/thread/nothing/vote", models.Vote{Nick: "alice", Voice: 1}, http.StatusNotFound, &message{})
+		c.post("/api/thread/100500/vote", models.Vote{Nick: "alice", Voice: 1}, http.StatusNotFound, &message{})
+
+		var status models.Status
+		c.get("/api/service/status", http.StatusOK, &status)
+		if status.Votes != 2 {
+			t.Errorf("status votes %d, want 2", status.Votes)
+		}
+	})
+}
+
+func TestVoteRetractionAndKarma(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createUser("bob")
+		c.createUser("carol")
+		c.createForum("pirates", "alice")
+		c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		post := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "m"})[0]
+		postPath := "/api/post/" + strconv.Itoa(post.Id)
+
+		for _, voter := range []string{"carol", "bob"} {
+			c.post("/api/thread/treasure/vote", models.Vote{Nick: voter, Voice: 1}, http.StatusOK, nil)
+		}
+		var voted models.Post
+		c.post(postPath+"/vote", models.PostVote{Nick: "bob", Voice: -1}, http.StatusOK, &voted)
+		c.post(postPath+"/vote", models.PostVote{Nick: "carol", Voice: -1}, http.StatusOK, &voted)
+		c.post(postPath+"/vote", models.PostVote{Nick: "BOB", Voice: 1}, http.StatusOK, &voted)
+		if voted.Votes != 0 || voted.Id != post.Id {
+			t.Errorf("post votes %d after revote, want 0", voted.Votes)
+		}
+		c.post(postPath+"/vote", models.PostVote{Nick: "bob", Voice: 2}, http.StatusBadRequest, &message{})
+		c.post(postPath+"/vote", models.PostVote{Nick: "nobody", Voice: 1}, http.StatusNotFound, &message{})
+		c.post("/api/post/100500/vote", models.PostVote{Nick: "bob", Voice: 1}, http.StatusNotFound, &message{})
+
+		var threadVotes []models.Vote
+		c.get("/api/thread/treasure/votes", http.StatusOK, &threadVotes)
+		if len(threadVotes) != 2 || threadVotes[0].Nick != "bob" || threadVotes[1].Nick != "carol" {
+			t.Errorf("thread votes %+v", threadVotes)
+		}
+		var postVotes []models.PostVote
+		c.get(postPath+"/votes?desc=true&limit=1", http.StatusOK, &postVotes)
+		if len(postVotes) != 1 || postVotes[0].Nick != "carol" || postVotes[0].Voice != -1 {
+			t.Errorf("post votes %+v", postVotes)
+		}
+		c.get(postPath+"/votes?since=bob", http.StatusOK, &postVotes)
+		if len(postVotes) != 1 || postVotes[0].Nick != "carol" {
+			t.Errorf("post votes since bob %+v", postVotes)
+		}
+		c.get("/api/thread/nothing/votes", http.StatusNotFound, &message{})
+
+		var karma models.Karma
+		c.get("/api/user/alice/karma", http.StatusOK, &karma)
+		if karma.Karma != 2 || karma.ThreadVotes != 2 || karma.PostVotes != 0 {
+			t.Errorf("karma %+v, want 2 from threads", karma)
+		}
+
+		var thread models.Thread
+		c.expect(http.MethodDelete, "/api/thread/treasure/vote/carol", nil, http.StatusOK, &thread)
+		if thread.Votes != 1 {
+			t.Errorf("thread votes %d after retraction, want 1", thread.Votes)
+		}
+		// retracting a vote that is not there changes nothing
+		c.expect(http.MethodDelete, "/api/thread/treasure/vote/carol", nil, http.StatusOK, &thread)
+		if thread.Votes != 1 {
+			t.Errorf("thread votes %d after second retraction, want 1", thread.Votes)
+		}
+		c.expect(http.MethodDelete, "/api/thread/nothing/vote/carol", nil, http.StatusNotFound, &message{})
+		c.expect(http.MethodDelete, postPath+"/vote/bob", nil, http.StatusOK, &voted)
+		if voted.Votes != -1 {
+			t.Errorf("post votes %d after retraction, want -1", voted.Votes)
+		}
+
+		c.get("/api/user/alice/karma", http.StatusOK, &karma)
+		if karma.Karma != 0 || karma.ThreadVotes != 1 || karma.PostVotes != -1 {
+			t.Errorf("karma %+v after retractions", karma)
+		}
+		var status models.Status
+		c.get("/api/service/status", http.StatusOK, &status)
+		if status.Votes != 1 {
+			t.Errorf("status votes %d, want 1", status.Votes)
+		}
+
+		var got apiv2.Post
+		c.expect(http.MethodPut, "/api/v2/posts/"+strconv.Itoa(post.Id)+"/votes/bob", apiv2.NewVote{Voice: -1}, http.StatusOK, &got)
+		if got.Votes != -2 {
+			t.Errorf("v2 post votes %d, want -2", got.Votes)
+		}
+		voters := pages[apiv2.Voter](c, "/api/v2/posts/"+strconv.Itoa(post.Id)+"/votes?desc=true", 1, nil)
+		if len(voters) != 2 || voters[0].Nickname != "carol" || voters[1].Nickname != "bob" {
+			t.Errorf("v2 post voters %+v", voters)
+		}
+		var v2Karma apiv2.Karma
+		c.get("/api/v2/users/alice/karma", http.StatusOK, &v2Karma)
+		if v2Karma.Karma != -1 {
+			t.Errorf("v2 karma %+v, want -1", v2Karma)
+		}
+		c.expectError(http.MethodGet, "/api/v2/users/nobody/karma", nil, http.StatusNotFound, apiv2.CodeUserNotFound)
+	})
+}
+
+func TestReactions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createUser("bob")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		posts := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "m"}, models.Post{AuthorNick: "bob", Message: "m"})
+		postPath := "/api/post/" + strconv.Itoa(posts[0].Id)
+
+		var post models.Post
+		c.post(postPath+"/reactions", models.Reaction{Nick: "bob", Reaction: "heart"}, http.StatusOK, &post)
+		c.post(postPath+"/reactions", models.Reaction{Nick: "BOB", Reaction: "heart"}, http.StatusOK, &post)
+		c.post(postPath+"/reactions", models.Reaction{Nick: "alice", Reaction: "heart"}, http.StatusOK, &post)
+		c.post(postPath+"/reactions", models.Reaction{Nick: "alice", Reaction: "rocket"}, http.StatusOK, &post)
+		if post.Reactions["heart"] != 2 || post.Reactions["rocket"] != 1 || len(post.Reactions) != 2 {
+			t.Errorf("post reactions %v, want 2 hearts and a rocket", post.Reactions)
+		}
+		c.post(postPath+"/reactions", models.Reaction{Nick: "bob", Reaction: "poop"}, http.StatusBadRequest, &message{})
+		c.post(postPath+"/reactions", models.Reaction{Nick: "nobody", Reaction: "like"}, http.StatusNotFound, &message{})
+		c.post("/api/post/100500/reactions", models.Reaction{Nick: "bob", Reaction: "like"}, http.StatusNotFound, &message{})
+
+		var details struct {
+			Post *models.Post `json:"post"`
+		}
+		c.get(postPath+"/details", http.StatusOK, &details)
+		if details.Post == nil || details.Post.Reactions != nil {
+			t.Errorf("post details without related=reactions %+v", details.Post)
+		}
+		c.get(postPath+"/details?related=reactions", http.StatusOK, &details)
+		if details.Post == nil || details.Post.Reactions["heart"] != 2 {
+			t.Errorf("post details with related=reactions %+v", details.Post)
+		}
+
+		c.expect(http.MethodDelete, postPath+"/reactions/bob/heart", nil, http.StatusOK, &post)
+		// taking back a reaction that is not there changes nothing
+		c.expect(http.MethodDelete, postPath+"/reactions/bob/heart", nil, http.StatusOK, &post)
+		if post.Reactions["heart"] != 1 || post.Reactions["rocket"] != 1 {
+			t.Errorf("post reactions %v after removal", post.Reactions)
+		}
+
+		var page []models.Post
+		c.get("/api/thread/treasure/posts?related=reactions", http.StatusOK, &page)
+		if len(page) != 2 || page[0].Reactions["rocket"] != 1 || page[1].Reactions != nil {
+			t.Errorf("thread posts with reactions %+v", page)
+		}
+		var plain []models.Post
+		c.get("/api/thread/treasure/posts?sort=tree", http.StatusOK, &plain)
+		if len(plain) != 2 || plain[0].Reactions != nil {
+			t.Errorf("thread posts without related=reactions %+v", plain)
+		}
+
+		var got models.Thread
+		c.post("/api/thread/treasure/reactions", models.Reaction{Nick: "bob", Reaction: "eyes"}, http.StatusOK, &got)
+		c.post("/api/thread/"+strconv.Itoa(thread.Id)+"/reactions", models.Reaction{Nick: "alice", Reaction: "eyes"}, http.StatusOK, &got)
+		c.post("/api/thread/nothing/reactions", models.Reaction{Nick: "bob", Reaction: "eyes"}, http.StatusNotFound, &message{})
+		c.get("/api/thread/treasure/details", http.StatusOK, &got)
+		if got.Reactions["eyes"] != 2 {
+			t.Errorf("thread reactions %v, want 2 eyes", got.Reactions)
+		}
+		c.expect(http.MethodDelete, "/api/thread/treasure/reactions/alice/eyes", nil, http.StatusOK, &got)
+		if got.Reactions["eyes"] != 1 {
+			t.Errorf("thread reactions %v after removal", got.Reactions)
+		}
+
+		var v2Post apiv2.Post
+		c.expect(http.MethodPut, "/api/v2/posts/"+strconv.Itoa(posts[1].Id)+"/reactions/alice/like", nil, http.StatusOK, &v2Post)
+		if v2Post.Reactions["like"] != 1 {
+			t.Errorf("v2 post reactions %v", v2Post.Reactions)
+		}
+		c.expectError(http.MethodPut, "/api/v2/posts/"+strconv.Itoa(posts[1].Id)+"/reactions/nobody/like", nil, http.StatusNotFound, apiv2.CodeUserNotFound)
+		c.expectError(http.MethodPut, "/api/v2/posts/100500/reactions/alice/like", nil, http.StatusNotFound, apiv2.CodePostNotFound)
+		c.expectError(http.MethodPut, "/api/v2/posts/"+strconv.Itoa(posts[1].Id)+"/reactions/alice/poop", nil, http.StatusBadRequest, apiv2.CodeValidation)
+		v2Posts := pages[apiv2.Post](c, "/api/v2/threads/"+strconv.Itoa(thread.Id)+"/posts?include=reactions", 1, nil)
+		if len(v2Posts) != 2 || v2Posts[0].Reactions["rocket"] != 1 || v2Posts[1].Reactions["like"] != 1 {
+			t.Errorf("v2 thread posts with reactions %+v", v2Posts)
+		}
+		var v2Thread apiv2.Thread
+		c.expect(http.MethodDelete, "/api/v2/threads/"+strconv.Itoa(thread.Id)+"/reactions/bob/eyes", nil, http.StatusOK, &v2Thread)
+		if len(v2Thread.Reactions) != 0 {
+			t.Errorf("v2 thread reactions %v, want none", v2Thread.Reactions)
+		}
+		c.expectError(http.MethodPut, "/api/v2/threads/100500/reactions/bob/eyes", nil, http.StatusNotFound, apiv2.CodeThreadNotFound)
+	})
+}
+
+func TestStatusAndClear(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createUser("bob")
+		c.createForum("pirates", "alice")
+		c.createForum("ninjas", "bob")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		posts := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "m"}, models.Post{AuthorNick: "bob", Message: "m"})
+		c.post("/api/post/"+strconv.Itoa(posts[0].Id)+"/details", map[string]string{"message": "edited"}, http.StatusOK, &models.Post{})
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "bob", Voice: 1}, http.StatusOK, &models.Thread{})
+
+		var status models.Status
+		c.get("/api/service/status", http.StatusOK, &status)
+		want := models.Status{Users: 2, Forums: 2, Threads: 1, Posts: 2, Votes: 1, ForumUsers: 2, EditedPosts: 1}
+		if status != want {
+			t.Errorf("status %+v, want %+v", status, want)
+		}
+
+		c.post("/api/service/clear/votes", nil, http.StatusOK, nil)
+		var voted models.Thread
+		c.get("/api/thread/"+strconv.Itoa(thread.Id)+"/details", http.StatusOK, &voted)
+		if voted.Votes != 0 {
+			t.Errorf("thread votes after clearing votes %d", voted.Votes)
+		}
+
+		c.post("/api/service/clear/forum/PIRATES", nil, http.StatusOK, nil)
+		c.post("/api/service/clear/forum/pirates", nil, http.StatusNotFound, &message{})
+		c.get("/api/service/status", http.StatusOK, &status)
+		want = models.Status{Users: 2, Forums: 1, DeletedPosts: 2}
+		if status != want {
+			t.Errorf("status after clearing forum %+v, want %+v", status, want)
+		}
+
+		c.post("/api/service/clear", nil, http.StatusOK, nil)
+		c.get("/api/service/status", http.StatusOK, &status)
+		if status != (models.Status{}) {
+			t.Errorf("status after clear %+v", status)
+		}
+	})
+}
+
+func TestMaintenance(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		var tasks []models.MaintenanceTask
+		c.get("/api/service/maintenance", http.StatusOK, &tasks)
+		if len(tasks) != len(config.MaintenanceConfig.Tasks) {
+			t.Fatalf("got %d tasks, want %d", len(tasks), len(config.MaintenanceConfig.Tasks))
+		}
+		for _, task := range tasks {
+			if task.State != maintenance.StateIdle || task.Runs != 0 {
+				t.Errorf("task before any run: %+v", task)
+			}
+		}
+
+		c.post("/api/service/maintenance/defrag", nil, http.StatusNotFound, &message{})
+		var task models.MaintenanceTask
+		c.post("/api/service/maintenance/vacuum_analyze", nil, http.StatusAccepted, &task)
+		if task.Name != "vacuum_analyze" || task.State == maintenance.StateIdle {
+			t.Errorf("queued task %+v", task)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			c.get("/api/service/maintenance", http.StatusOK, &tasks)
+			for _, listed := range tasks {
+				if listed.Name == task.Name {
+					task = listed
+				}
+			}
+			if task.State == maintenance.StateIdle || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if task.Runs != 1 || task.Failures != 0 || task.LastTrigger != maintenance.TriggerManual {
+			t.Errorf("task after a manual run: %+v", task)
+		}
+	})
+}
+
+func TestValidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+
+		cases := []struct {
+			name   string
+			method string
+			path   string
+			body   interface{}
+		}{
+			{"empty post message", http.MethodPost, "/api/thread/treasure/create", []models.Post{{AuthorNick: "alice"}}},
+			{"negative parent", http.MethodPost, "/api/thread/treasure/create", []map[string]interface{}{{"author": "alice", "message": "m", "parent": -1}}},
+			{"voice out of range", http.MethodPost, "/api/thread/treasure/vote", models.Vote{Nick: "alice", Voice: 2}},
+			{"vote without nickname", http.MethodPost, "/api/thread/treasure/vote", map[string]int{"voice": 1}},
+			{"thread without title", http.MethodPost, "/api/forum/pirates/create", map[string]string{"author": "alice", "message": "m"}},
+			{"bad thread created", http.MethodPost, "/api/forum/pirates/create", map[string]string{"author": "alice", "title": "t", "message": "m", "created": "yesterday"}},
+			{"forum without user", http.MethodPost, "/api/forum/create", map[string]string{"slug": "ninjas", "title": "t"}},
+			{"user without email", http.MethodPost, "/api/user/bob/create", map[string]string{"fullname": "Bob"}},
+			{"zero limit", http.MethodGet, "/api/forum/pirates/threads?limit=0", nil},
+			{"bad since", http.MethodGet, "/api/forum/pirates/threads?since=yesterday", nil},
+			{"bad desc", http.MethodGet, "/api/forum/pirates/users?desc=maybe", nil},
+			{"unknown sort", http.MethodGet, "/api/thread/treasure/posts?sort=random", nil},
+			{"bad post id", http.MethodGet, "/api/post/first/details", nil},
+			{"unknown related", http.MethodGet, "/api/post/1/details?related=user,likes", nil},
+		}
+		for _, tc := range cases {
+			var msg message
+			if status := c.do(tc.method, tc.path, tc.body, &msg); status != http.StatusBadRequest {
+				t.Errorf("%s: status %d, want %d", tc.name, status, http.StatusBadRequest)
+			} else if !strings.HasPrefix(msg.Message, errors.INVALID_REQUEST) {
+				t.Errorf("%s: message %q", tc.name, msg.Message)
+			}
+		}
+
+		var doc map[string]interface{}
+		c.get("/api/openapi.json", http.StatusOK, &doc)
+		if doc["openapi"] == nil || doc["paths"] == nil {
+			t.Errorf("openapi.json has no openapi version or paths")
+		}
+		resp, err := http.Get(c.url + "/api/docs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get(echo.HeaderContentType), echo.MIMETextHTML) {
+			t.Errorf("docs: status %d, content type %q", resp.StatusCode, resp.Header.Get(echo.HeaderContentType))
+		}
+	})
+}
+
+func TestInputValidation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		long := strings.Repeat("x", validate.MaxTitleLength+1)
+
+		cases := []struct {
+			name   string
+			method string
+			path   string
+			body   interface{}
+			field  string
+		}{
+			{"nickname charset", http.MethodPost, "/api/user/bob smith/create", models.User{Name: "Bob", Email: "bob@mail.ru"}, "nickname"},
+			{"email syntax", http.MethodPost, "/api/user/bob/create", models.User{Name: "Bob", Email: "bob@"}, "email"},
+			{"slug charset", http.MethodPost, "/api/forum/create", models.Forum{Slug: "pi rates", Title: "t", UserNick: "alice"}, "slug"},
+			{"numeric thread slug", http.MethodPost, "/api/forum/pirates/create", models.Thread{Slug: "42", Title: "t", AuthorNick: "alice", Message: "m"}, "slug"},
+			{"long title", http.MethodPost, "/api/forum/pirates/create", models.Thread{Title: long, AuthorNick: "alice", Message: "m"}, "title"},
+			{"long title update", http.MethodPost, "/api/thread/treasure/details", models.Thread{Title: long}, "title"},
+			{"second post author", http.MethodPost, "/api/thread/treasure/create", []models.Post{{AuthorNick: "alice", Message: "m"}, {AuthorNick: "al ice", Message: "m"}}, "[1].author"},
+			{"v2 forum author", http.MethodPost, "/api/v2/forums", map[string]string{"slug": "ninjas", "title": "t", "author": "al/ice"}, "author"},
+			{"v2 numeric thread slug", http.MethodPost, "/api/v2/forums/pirates/threads", map[string]string{"slug": "42", "title": "t", "author": "alice", "message": "m"}, "slug"},
+			{"v2 voter", http.MethodPut, "/api/v2/threads/1/votes/al ice", map[string]int{"voice": 1}, "voter"},
+		}
+		for _, tc := range cases {
+			var got struct {
+				Fields []models.FieldError `json:"fields"`
+				Error  struct {
+					Code   string              `json:"code"`
+					Fields []models.FieldError `json:"fields"`
+				} `json:"error"`
+			}
+			path := strings.ReplaceAll(tc.path, " ", "%20")
+			if status := c.do(tc.method, path, tc.body, &got); status != http.StatusBadRequest {
+				t.Errorf("%s: status %d, want %d", tc.name, status, http.StatusBadRequest)
+				continue
+			}
+			fields := got.Fields
+			if strings.HasPrefix(tc.path, "/api/v2/") {
+				if got.Error.Code != apiv2.CodeValidation {
+					t.Errorf("%s: code %q, want %q", tc.name, got.Error.Code, apiv2.CodeValidation)
+				}
+				fields = got.Error.Fields
+			}
+			if len(fields) != 1 || fields[0].Field != tc.field || fields[0].Reason == "" {
+				t.Errorf("%s: fields %+v, want one for %s", tc.name, fields, tc.field)
+			}
+		}
+	})
+}
+
+func TestUserRename(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		bob := c.createUser("bob")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		posts := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "m"})
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "alice", Voice: 1}, http.StatusOK, nil)
+
+		var renamed models.User
+		c.post("/api/user/ALICE/rename", models.UserRename{Nick: "alicia"}, http.StatusOK, &renamed)
+		if renamed.Nick != "alicia" || renamed.Email != "alice@mail.ru" {
+			t.Errorf("renamed user %+v", renamed)
+		}
+
+		var forum models.Forum
+		c.get("/api/forum/pirates/details", http.StatusOK, &forum)
+		var gotThread models.Thread
+		c.get("/api/thread/treasure/details", http.StatusOK, &gotThread)
+		var post struct {
+			Post models.Post `json:"post"`
+		}
+		c.get("/api/post/"+strconv.Itoa(posts[0].Id)+"/details", http.StatusOK, &post)
+		var forumUsers []models.User
+		c.get("/api/forum/pirates/users", http.StatusOK, &forumUsers)
+		if forum.UserNick != "alicia" || gotThread.AuthorNick != "alicia" || post.Post.AuthorNick != "alicia" ||
+			len(forumUsers) != 1 || forumUsers[0].Nick != "alicia" {
+			t.Errorf("after rename: forum by %s, thread by %s, post by %s, forum users %v",
+				forum.UserNick, gotThread.AuthorNick, post.Post.AuthorNick, forumUsers)
+		}
+		// the vote moved with the user, so voting again replaces it
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "alicia", Voice: -1}, http.StatusOK, &gotThread)
+		if gotThread.Votes != -1 || gotThread.Id != thread.Id {
+			t.Errorf("votes %d after revote, want -1", gotThread.Votes)
+		}
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "alice", Voice: 1}, http.StatusNotFound, nil)
+
+		// the old nickname redirects to the new one, the client follows it
+		var user models.User
+		c.get("/api/user/alice/profile", http.StatusOK, &user)
+		if user.Nick != "alicia" {
+			t.Errorf("old nickname leads to %q, want alicia", user.Nick)
+		}
+		var v2User apiv2.User
+		c.get("/api/v2/users/alice", http.StatusOK, &v2User)
+		if v2User.Nickname != "alicia" {
+			t.Errorf("v2: old nickname leads to %q, want alicia", v2User.Nickname)
+		}
+
+		var conflict models.User
+		c.post("/api/user/alicia/rename", models.UserRename{Nick: "BOB"}, http.StatusConflict, &conflict)
+		if conflict != bob {
+			t.Errorf("conflict with %+v, want %+v", conflict, bob)
+		}
+		c.post("/api/user/nobody/rename", models.UserRename{Nick: "somebody"}, http.StatusNotFound, nil)
+		c.expectError(http.MethodPost, "/api/v2/users/alicia/rename", apiv2.UserRename{Nickname: "bob"}, http.StatusConflict, apiv2.CodeUserConflict)
+
+		// once someone else takes the old nickname, it is theirs
+		c.post("/api/user/alice/create", models.User{Name: "New alice", Email: "new.alice@mail.ru"}, http.StatusCreated, nil)
+		c.get("/api/user/alice/profile", http.StatusOK, &user)
+		if user.Nick != "alice" || user.Email != "new.alice@mail.ru" {
+			t.Errorf("new alice %+v", user)
+		}
+		c.post("/api/user/bob/rename", models.UserRename{Nick: "Bobby"}, http.StatusOK, nil)
+		c.post("/api/user/alicia/rename", models.UserRename{Nick: "bob"}, http.StatusOK, nil)
+		c.get("/api/user/bob/profile", http.StatusOK, &user)
+		if user.Nick != "bob" || user.Email != "alice@mail.ru" {
+			t.Errorf("bob after alicia took the nickname: %+v", user)
+		}
+	})
+}
+
+func TestUserExportAndDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createUser("bob")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		root := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "ahoy"})
+		c.createPosts("treasure", models.Post{AuthorNick: "bob", Message: "hi", ParentId: root[0].Id})
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "alice", Voice: 1}, http.StatusOK, nil)
+		c.post("/api/thread/treasure/vote", models.Vote{Nick: "bob", Voice: 1}, http.StatusOK, nil)
+		c.post("/api/user/alice/rename", models.UserRename{Nick: "alicia"}, http.StatusOK, nil)
+
+		// by the old nickname, through the redirect
+		var export models.UserExport
+		c.get("/api/user/alice/export", http.StatusOK, &export)
+		if export.User.Nick != "alicia" || len(export.Threads) != 1 || export.Threads[0].Id != thread.Id ||
+			len(export.Posts) != 1 || export.Posts[0].Message != "ahoy" ||
+			len(export.Votes) != 1 || export.Votes[0] != (models.Vote{Nick: "alicia", Voice: 1, ThreadId: thread.Id}) ||
+			len(export.Forums) != 1 || export.Forums[0].Forum != "pirates" || export.Forums[0].Posts != 1 || export.Forums[0].Threads != 1 {
+			t.Errorf("export %+v", export)
+		}
+		resp, err := http.Get(c.url + "/api/user/alice/export?format=ndjson")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		types := make([]string, 0)
+		lines := bufio.NewScanner(resp.Body)
+		for lines.Scan() {
+			var record struct {
+				Type string `json:"type"`
+			}
+			if err = json.Unmarshal(lines.Bytes(), &record); err != nil {
+				t.Fatalf("ndjson line %q: %v", lines.Text(), err)
+			}
+			types = append(types, record.Type)
+		}
+		if got := strings.Join(types, " "); got != "user thread post vote forum" ||
+			resp.Header.Get(echo.HeaderContentType) != "application/x-ndjson" ||
+			!strings.Contains(resp.Header.Get(echo.HeaderContentDisposition), "alicia.ndjson") {
+			t.Errorf("ndjson export: records %s, headers %v", got, resp.Header)
+		}
+
+		c.expect(http.MethodDelete, "/api/user/alicia", nil, http.StatusNoContent, nil)
+		c.expect(http.MethodDelete, "/api/user/alicia", nil, http.StatusNotFound, &message{})
+		c.get("/api/user/alicia/profile", http.StatusNotFound, &message{})
+		c.get("/api/user/alice/profile", http.StatusNotFound, &message{})
+
+		var forum models.Forum
+		c.get("/api/forum/pirates/details", http.StatusOK, &forum)
+		var gotThread models.Thread
+		c.get("/api/thread/treasure/details", http.StatusOK, &gotThread)
+		var post struct {
+			Post models.Post `json:"post"`
+		}
+		c.get("/api/post/"+strconv.Itoa(root[0].Id)+"/details", http.StatusOK, &post)
+		if forum.UserNick != "deleted-user" || gotThread.AuthorNick != "deleted-user" || gotThread.Votes != 1 ||
+			post.Post.AuthorNick != "deleted-user" || post.Post.Message != "ahoy" {
+			t.Errorf("after delete: forum by %s, thread by %s with %d votes, post %+v",
+				forum.UserNick, gotThread.AuthorNick, gotThread.Votes, post.Post)
+		}
+		var forumUsers []models.User
+		c.get("/api/forum/pirates/users", http.StatusOK, &forumUsers)
+		if len(forumUsers) != 1 || forumUsers[0].Nick != "bob" {
+			t.Errorf("forum users after delete: %v", forumUsers)
+		}
+		var status models.Status
+		c.get("/api/service/status", http.StatusOK, &status)
+		if want := (models.Status{Users: 2, Forums: 1, Threads: 1, Posts: 2, Votes: 1, ForumUsers: 1}); status != want {
+			t.Errorf("status %+v, want %+v", status, want)
+		}
+
+		// the ghost account stays as it is
+		c.expect(http.MethodDelete, "/api/user/deleted-user", nil, http.StatusForbidden, &message{})
+		c.post("/api/user/deleted-user/rename", models.UserRename{Nick: "ghost"}, http.StatusForbidden, &message{})
+		c.post("/api/user/deleted-user/profile", models.User{About: "boo"}, http.StatusForbidden, &message{})
+		c.expectError(http.MethodDelete, "/api/v2/users/deleted-user", nil, http.StatusForbidden, apiv2.CodeForbidden)
+
+		var v2Export apiv2.UserExport
+		c.get("/api/v2/users/bob/export", http.StatusOK, &v2Export)
+		if v2Export.User.Nickname != "bob" || len(v2Export.Posts) != 1 || *v2Export.Posts[0].ParentId != root[0].Id ||
+			len(v2Export.Votes) != 1 || v2Export.Votes[0] != (apiv2.Vote{ThreadId: thread.Id, Voice: 1}) {
+			t.Errorf("v2 export %+v", v2Export)
+		}
+		c.expect(http.MethodDelete, "/api/v2/users/bob", nil, http.StatusNoContent, nil)
+		c.get("/api/thread/treasure/details", http.StatusOK, &gotThread)
+		if gotThread.Votes != 0 {
+			t.Errorf("votes %d after both voters are deleted", gotThread.Votes)
+		}
+		c.expectError(http.MethodGet, "/api/v2/users/bob", nil, http.StatusNotFound, apiv2.CodeUserNotFound)
+	})
+}
+
+func TestUserAdminRoutes(t *testing.T) {
+	adminConfig := config.AdminConfig
+	defer func() { config.AdminConfig = adminConfig }()
+	// the routes take the admin config when they are built
+	config.AdminConfig = config.AdminConfigStruct{Profile: config.ProfileProd, Token: "secret"}
+	c := serve(t, configRouting.MemoryRepos())
+	config.AdminConfig.Token = ""
+	disabled := serve(t, configRouting.MemoryRepos())
+
+	c.createUser("alice")
+	disabled.createUser("alice")
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/api/user/alice/export"},
+		{http.MethodDelete, "/api/user/alice"},
+		{http.MethodGet, "/api/v2/users/alice/export"},
+		{http.MethodDelete, "/api/v2/users/alice"},
+	}
+	for _, route := range routes {
+		c.header = http.Header{auth.AdminTokenHeader: {"wrong"}}
+		if got := c.do(route.method, route.path, nil, nil); got != http.StatusUnauthorized {
+			t.Errorf("%s %s with a wrong token: status %d", route.method, route.path, got)
+		}
+		c.header = nil
+		if got := c.do(route.method, route.path, nil, nil); got != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: status %d", route.method, route.path, got)
+		}
+		if got := disabled.do(route.method, route.path, nil, nil); got != http.StatusForbidden {
+			t.Errorf("%s %s with no token configured: status %d", route.method, route.path, got)
+		}
+	}
+
+	c.header = http.Header{auth.AdminTokenHeader: {"secret"}}
+	c.get("/api/user/alice/export", http.StatusOK, &models.UserExport{})
+	c.header = http.Header{echo.HeaderAuthorization: {"Bearer secret"}}
+	c.expect(http.MethodDelete, "/api/v2/users/alice", nil, http.StatusNoContent, nil)
+}
+
+func TestUsersBulk(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		alice := c.createUser("alice")
+
+		carol := models.User{Nick: "carol", Name: "Carol", Email: "carol@mail.ru"}
+		dave := models.User{Nick: "dave", Name: "Dave", Email: "dave@mail.ru", About: "about dave"}
+		var results []models.BulkUserResult
+		c.post("/api/users/bulk", []models.User{
+			carol,
+			{Nick: "ALICE", Name: "x", Email: "x@mail.ru"},
+			dave,
+			// clashes with carol, who is created earlier in the same request
+			{Nick: "erin", Name: "Erin", Email: "CAROL@mail.ru"},
+		}, http.StatusOK, &results)
+		if len(results) != 4 ||
+			results[0].Status != http.StatusCreated || *results[0].User != carol ||
+			results[1].Status != http.StatusConflict || len(results[1].Existing) != 1 || results[1].Existing[0] != alice ||
+			results[2].Status != http.StatusCreated || *results[2].User != dave ||
+			results[3].Status != http.StatusConflict || len(results[3].Existing) != 1 || results[3].Existing[0] != carol {
+			t.Errorf("bulk results %+v", results)
+		}
+		c.get("/api/user/erin/profile", http.StatusNotFound, &message{})
+
+		var status models.Status
+		c.get("/api/service/status", http.StatusOK, &status)
+		if status.Users != 3 {
+			t.Errorf("%d users after the bulk create, want 3", status.Users)
+		}
+		c.post("/api/users/bulk", []models.User{{Nick: "bad nick", Name: "x", Email: "bad@mail.ru"}}, http.StatusBadRequest, &message{})
+
+		var users []models.User
+		c.get("/api/users?nicks=DAVE,nobody,alice,dave", http.StatusOK, &users)
+		if len(users) != 2 || users[0] != dave || users[1] != alice {
+			t.Errorf("users %+v, want dave and alice", users)
+		}
+		c.get("/api/users", http.StatusBadRequest, &message{})
+
+		var v2Results []apiv2.BulkUserResult
+		c.post("/api/v2/users/bulk", []apiv2.NewUser{
+			{Nickname: "frank", Fullname: "Frank", Email: "frank@mail.ru"},
+			{Nickname: "dave", Fullname: "Dave", Email: "dave2@mail.ru"},
+		}, http.StatusOK, &v2Results)
+		if len(v2Results) != 2 || v2Results[0].Status != http.StatusCreated || v2Results[0].User.Nickname != "frank" ||
+			v2Results[1].Status != http.StatusConflict || v2Results[1].Error == nil || v2Results[1].Error.Code != apiv2.CodeUserConflict {
+			t.Errorf("v2 bulk results %+v", v2Results)
+		}
+		var v2Users []apiv2.User
+		c.get("/api/v2/users?nicknames=frank,carol", http.StatusOK, &v2Users)
+		if len(v2Users) != 2 || v2Users[0].Nickname != "frank" || v2Users[1].Nickname != "carol" {
+			t.Errorf("v2 users %+v", v2Users)
+		}
+	})
+}
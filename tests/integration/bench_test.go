@@ -0,0 +1,43 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/internal/bench"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+func TestBench(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		cfg := config.BenchConfig
+		cfg.URL = c.url + "/api"
+		cfg.Clear = true
+		cfg.Users, cfg.Forums, cfg.Threads, cfg.Posts, cfg.Votes = 20, 3, 10, 300, 50
+		cfg.PostBatch, cfg.MaxDepth, cfg.UserBatch = 25, 5, 7
+		cfg.Concurrency, cfg.Duration, cfg.Requests = 4, 0, 200
+
+		reports, err := bench.Run(context.Background(), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reports) != 2 {
+			t.Fatalf("got %d reports, want fill and replay", len(reports))
+		}
+		fill, replay := reports[0], reports[1]
+		if fill.Total.Errors != 0 {
+			t.Errorf("fill: %d errors", fill.Total.Errors)
+		}
+		if replay.Total.Count != cfg.Requests {
+			t.Errorf("replay: %d requests, want %d", replay.Total.Count, cfg.Requests)
+		}
+
+		status := models.Status{}
+		c.get("/api/service/status", http.StatusOK, &status)
+		if status.Users != cfg.Users || status.Forums != cfg.Forums || status.Threads != cfg.Threads || status.Posts < cfg.Posts {
+			t.Errorf("status after bench: %+v", status)
+		}
+	})
+}
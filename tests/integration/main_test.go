@@ -0,0 +1,205 @@
+// Package integration runs the HTTP API end to end: the real handlers wired by configRouting on top of
+// a throwaway postgres with db/db.sql applied, and on top of the in-memory backend. The postgres runs
+// are skipped when initdb is not available, unless REQUIRE_POSTGRES=true makes that a failure (as in CI).
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Natali-Skv/technopark_db_forum/config"
+	"github.com/Natali-Skv/technopark_db_forum/configRouting"
+	"github.com/Natali-Skv/technopark_db_forum/internal/maintenance"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/dbconn"
+	"github.com/Natali-Skv/technopark_db_forum/internal/tools/openapi"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+var (
+	pgPool    *dbconn.Pool
+	pgSkipped string
+	spec      *openapi.Spec
+)
+
+func TestMain(m *testing.M) {
+	config.AdminConfig.Profile = config.ProfileTest
+	var err error
+	if spec, err = openapi.Load(); err != nil {
+		panic(err)
+	}
+
+	pg, err := startPostgres()
+	if err == nil {
+		pgPool, err = connect(pg.connString())
+	}
+	if err != nil {
+		if os.Getenv("REQUIRE_POSTGRES") == "true" {
+			fmt.Fprintln(os.Stderr, "postgres required:", err)
+			os.Exit(1)
+		}
+		pgSkipped = err.Error()
+	}
+
+	code := m.Run()
+	if pgPool != nil {
+		pgPool.Close()
+	}
+	if pg != nil {
+		pg.stop()
+	}
+	os.Exit(code)
+}
+
+func connect(connString string) (*dbconn.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	registry := dbconn.NewRegistry()
+	poolConfig.AfterConnect = registry.AfterConnect
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dbconn.NewPool(pool, registry, 0, 0), nil
+}
+
+// forEachBackend runs test against a fresh, empty API once per storage backend.
+func forEachBackend(t *testing.T, test func(t *testing.T, c *client)) {
+	t.Run(config.BackendPostgres, func(t *testing.T) {
+		if pgPool == nil {
+			t.Skip("no postgres: " + pgSkipped)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := pgPool.Validate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		c := serve(t, configRouting.PostgresRepos(pgPool))
+		c.expect(http.MethodPost, "/api/service/clear", nil, http.StatusOK, nil)
+		test(t, c)
+	})
+	t.Run(config.BackendMemory, func(t *testing.T) {
+		test(t, serve(t, configRouting.MemoryRepos()))
+	})
+}
+
+// scheduler runs only the tasks requested by a test: the automatic triggers are off.
+func scheduler(t *testing.T, repo maintenance.Repo) *maintenance.Scheduler {
+	cfg := config.MaintenanceConfig
+	cfg.Automatic = false
+	s := maintenance.NewScheduler(repo, cfg, zerolog.Nop())
+	s.Start()
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// serve starts the API as main builds it, over repos.
+func serve(t *testing.T, repos configRouting.Repos) *client {
+	e, handlers := configRouting.NewServer(repos, scheduler(t, repos.Maintenance), spec, zerolog.Nop())
+	e.HideBanner = true
+	handlers.ServiceHandler.SetReady(true)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return &client{t: t, url: server.URL}
+}
+
+type client struct {
+	t   *testing.T
+	url string
+	// header is added to every request.
+	header http.Header
+}
+
+// do sends body as JSON and decodes the response into out, whatever the status.
+func (c *client) do(method string, path string, body interface{}, out interface{}) int {
+	c.t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, c.url+path, &reqBody)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c *client) expect(method string, path string, body interface{}, status int, out interface{}) {
+	c.t.Helper()
+	if got := c.do(method, path, body, out); got != status {
+		c.t.Fatalf("%s %s: status %d, want %d", method, path, got, status)
+	}
+}
+
+func (c *client) get(path string, status int, out interface{}) {
+	c.t.Helper()
+	c.expect(http.MethodGet, path, nil, status, out)
+}
+
+func (c *client) post(path string, body interface{}, status int, out interface{}) {
+	c.t.Helper()
+	c.expect(http.MethodPost, path, body, status, out)
+}
+
+func (c *client) createUser(nick string) models.User {
+	c.t.Helper()
+	user := models.User{Name: "Name " + nick, Email: nick + "@mail.ru", About: "about " + nick}
+	c.post("/api/user/"+nick+"/create", user, http.StatusCreated, &user)
+	return user
+}
+
+func (c *client) createForum(slug string, author string) models.Forum {
+	c.t.Helper()
+	forum := models.Forum{Slug: slug, Title: "Forum " + slug, UserNick: author}
+	c.post("/api/forum/create", forum, http.StatusCreated, &forum)
+	return forum
+}
+
+func (c *client) createThread(forum string, thread models.Thread) models.Thread {
+	c.t.Helper()
+	c.post("/api/forum/"+forum+"/create", thread, http.StatusCreated, &thread)
+	return thread
+}
+
+func (c *client) createPosts(thread string, posts ...models.Post) []models.Post {
+	c.t.Helper()
+	c.post("/api/thread/"+thread+"/create", posts, http.StatusCreated, &posts)
+	return posts
+}
+
+func postIds(posts []models.Post) string {
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, strconv.Itoa(post.Id))
+	}
+	return strings.Join(ids, " ")
+}
@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	pgUser   = "docker"
+	pgDBName = "forum_test"
+	pgPort   = "5432"
+	schema   = "../../db/db.sql"
+)
+
+// postgres is a throwaway server in a temp directory. It listens only on a unix socket in that directory.
+type postgres struct {
+	bin string
+	dir string
+}
+
+// findPgBin looks for initdb in PATH and in the Debian and source install locations.
+func findPgBin() (string, error) {
+	if initdb, err := exec.LookPath("initdb"); err == nil {
+		return filepath.Dir(initdb), nil
+	}
+	candidates, _ := filepath.Glob("/usr/lib/postgresql/*/bin/initdb")
+	candidates = append(candidates, "/usr/local/pgsql/bin/initdb")
+	sort.Sort(sort.Reverse(sort.StringSlice(candidates)))
+	for _, initdb := range candidates {
+		if _, err := os.Stat(initdb); err == nil {
+			return filepath.Dir(initdb), nil
+		}
+	}
+	return "", fmt.Errorf("initdb not found")
+}
+
+// startPostgres runs initdb and pg_ctl, creates the test database and applies db/db.sql.
+func startPostgres() (*postgres, error) {
+	bin, err := findPgBin()
+	if err != nil {
+		return nil, err
+	}
+	if os.Geteuid() == 0 {
+		return nil, fmt.Errorf("initdb refuses to run as root")
+	}
+	dir, err := os.MkdirTemp("", "forum-pg-")
+	if err != nil {
+		return nil, err
+	}
+	pg := &postgres{bin: bin, dir: dir}
+	steps := [][]string{
+		{"initdb", "-D", pg.dataDir(), "-U", pgUser, "--auth=trust", "--no-sync", "-E", "UTF8", "--locale=C"},
+		{"pg_ctl", "-D", pg.dataDir(), "-l", filepath.Join(dir, "postgres.log"), "-w", "start",
+			"-o", fmt.Sprintf("-c listen_addresses='' -k %s -p %s -F", dir, pgPort)},
+		{"psql", "-X", "-q", "-h", dir, "-p", pgPort, "-U", pgUser, "-d", "postgres", "-c", "CREATE DATABASE " + pgDBName},
+		{"psql", "-X", "-q", "-v", "ON_ERROR_STOP=1", "-h", dir, "-p", pgPort, "-U", pgUser, "-d", pgDBName, "-f", schema},
+	}
+	for _, step := range steps {
+		if err = pg.run(step...); err != nil {
+			pg.stop()
+			return nil, err
+		}
+	}
+	return pg, nil
+}
+
+func (pg *postgres) dataDir() string {
+	return filepath.Join(pg.dir, "data")
+}
+
+func (pg *postgres) connString() string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable", pg.dir, pgPort, pgUser, pgDBName)
+}
+
+func (pg *postgres) run(args ...string) error {
+	out, err := exec.Command(filepath.Join(pg.bin, args[0]), args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w\n%s", args[0], err, out)
+	}
+	return nil
+}
+
+func (pg *postgres) stop() {
+	if _, err := os.Stat(filepath.Join(pg.dataDir(), "postmaster.pid")); err == nil {
+		pg.run("pg_ctl", "-D", pg.dataDir(), "-m", "immediate", "-w", "stop")
+	}
+	os.RemoveAll(pg.dir)
+}
@@ -0,0 +1,445 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Natali-Skv/technopark_db_forum/configRouting"
+	"github.com/Natali-Skv/technopark_db_forum/internal/apiv2"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+	postDelivery "github.com/Natali-Skv/technopark_db_forum/internal/post/delivery/http"
+	"github.com/labstack/echo/v4"
+)
+
+func TestPosts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createUser("Bob")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		other := c.createThread("pirates", models.Thread{Slug: "gold", Title: "t", AuthorNick: "alice", Message: "m"})
+
+		posts := c.createPosts("TREASURE", models.Post{AuthorNick: "bob", Message: "root"})
+		post := posts[0]
+		if post.Id == 0 || post.AuthorNick != "Bob" || post.ThreadId != thread.Id || post.ForumSlug != "pirates" || post.Created == "" {
+			t.Errorf("created %+v", post)
+		}
+		// posts created by thread id answer posts created by slug and the other way round
+		byId := strconv.Itoa(thread.Id)
+		batch := c.createPosts(byId,
+			models.Post{AuthorNick: "alice", Message: "reply", ParentId: post.Id},
+			models.Post{AuthorNick: "alice", Message: "root 2"})
+		c.createPosts(byId, models.Post{AuthorNick: "alice", Message: "reply 2", ParentId: batch[1].Id})
+
+		var empty []models.Post
+		c.post("/api/thread/treasure/create", []models.Post{}, http.StatusCreated, &empty)
+		if len(empty) != 0 {
+			t.Errorf("empty batch returned %+v", empty)
+		}
+		c.post("/api/thread/gold/create", []models.Post{{AuthorNick: "alice", Message: "m", ParentId: post.Id}}, http.StatusConflict, &message{})
+		c.post("/api/thread/gold/create", []models.Post{{AuthorNick: "alice", Message: "m", ParentId: 100500}}, http.StatusConflict, &message{})
+		c.post("/api/thread/gold/create", []models.Post{{AuthorNick: "nobody", Message: "m"}}, http.StatusNotFound, &message{})
+		c.post("/api/thread/nothing/create", []models.Post{{AuthorNick: "alice", Message: "m"}}, http.StatusNotFound, &message{})
+		c.post("/api/thread/100500/create", []models.Post{{AuthorNick: "alice", Message: "m"}}, http.StatusNotFound, &message{})
+		// a rejected batch stores nothing
+		c.post("/api/thread/gold/create", []models.Post{{AuthorNick: "alice", Message: "ok"}, {AuthorNick: "nobody", Message: "m"}}, http.StatusNotFound, &message{})
+		var goldPosts []models.Post
+		c.get("/api/thread/"+strconv.Itoa(other.Id)+"/posts", http.StatusOK, &goldPosts)
+		if len(goldPosts) != 0 {
+			t.Errorf("rejected batch left posts %+v", goldPosts)
+		}
+
+		var forum models.Forum
+		c.get("/api/forum/pirates/details", http.StatusOK, &forum)
+		if forum.Posts != 4 {
+			t.Errorf("forum posts %d, want 4", forum.Posts)
+		}
+	})
+}
+
+func TestPostDetails(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		alice := c.createUser("alice")
+		forum := c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		post := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "hello"})[0]
+		path := "/api/post/" + strconv.Itoa(post.Id) + "/details"
+
+		var details struct {
+			Post   *models.Post   `json:"post"`
+			User   *models.User   `json:"author"`
+			Forum  *models.Forum  `json:"forum"`
+			Thread *models.Thread `json:"thread"`
+		}
+		c.get(path, http.StatusOK, &details)
+		if details.Post == nil || !reflect.DeepEqual(*details.Post, post) || details.User != nil || details.Forum != nil || details.Thread != nil {
+			t.Errorf("details without related %+v", details)
+		}
+		c.get(path+"?related=user,thread,forum", http.StatusOK, &details)
+		forum.Posts, forum.Threads = 1, 1
+		if details.User == nil || *details.User != alice || details.Forum == nil || *details.Forum != forum ||
+			details.Thread == nil || details.Thread.Id != thread.Id {
+			t.Errorf("details with related %+v", details)
+		}
+		c.get("/api/post/100500/details", http.StatusNotFound, &message{})
+
+		// the same message does not count as an edit
+		var updated models.Post
+		c.post(path, map[string]string{"message": "hello"}, http.StatusOK, &updated)
+		if updated.IsEdited {
+			t.Errorf("post marked edited without a change")
+		}
+		c.post(path, map[string]string{"message": "changed"}, http.StatusOK, &updated)
+		if !updated.IsEdited || updated.Message != "changed" {
+			t.Errorf("updated %+v", updated)
+		}
+		c.post("/api/post/100500/details", map[string]string{"message": "x"}, http.StatusNotFound, &message{})
+	})
+}
+
+// TestThreadPosts checks every sort against this tree, where a..h are posts in creation order:
+//
+//	a        b      c
+//	├─ d     └─ e   └─ h
+//	│  └─ g
+//	└─ f
+func TestThreadPosts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		c.createThread("pirates", models.Thread{Slug: "noise", Title: "t", AuthorNick: "alice", Message: "m"})
+		c.createPosts("noise", models.Post{AuthorNick: "alice", Message: "noise"})
+
+		posts := map[string]models.Post{}
+		create := func(threadSlugOrId string, names string, parents ...string) {
+			batch := make([]models.Post, 0, len(parents))
+			for i := range parents {
+				batch = append(batch, models.Post{AuthorNick: "alice", Message: names[i : i+1], ParentId: posts[parents[i]].Id})
+			}
+			for _, post := range c.createPosts(threadSlugOrId, batch...) {
+				posts[post.Message] = post
+			}
+		}
+		byId := strconv.Itoa(thread.Id)
+		create("treasure", "abc", "", "", "")
+		create(byId, "def", "a", "b", "a")
+		create("treasure", "gh", "d", "c")
+		names := map[int]string{}
+		for name, post := range posts {
+			names[post.Id] = name
+		}
+		id := func(name string) string {
+			return strconv.Itoa(posts[name].Id)
+		}
+
+		cases := []struct {
+			query string
+			want  string
+		}{
+			{"", "abcdefgh"},
+			{"sort=flat", "abcdefgh"},
+			{"sort=flat&desc=true", "hgfedcba"},
+			{"sort=flat&limit=3", "abc"},
+			{"sort=flat&since=" + id("d") + "&limit=3", "efg"},
+			{"sort=flat&since=" + id("d") + "&desc=true&limit=3", "cba"},
+			{"sort=flat&since=" + id("h"), ""},
+			{"sort=tree", "adgfbech"},
+			{"sort=tree&desc=true", "hcebfgda"},
+			{"sort=tree&limit=4", "adgf"},
+			{"sort=tree&since=" + id("d") + "&limit=3", "gfb"},
+			{"sort=tree&since=" + id("f") + "&desc=true&limit=3", "gda"},
+			{"sort=tree&since=" + id("f") + "&desc=true", "gda"},
+			{"sort=parent_tree", "adgfbech"},
+			{"sort=parent_tree&desc=true", "chbeadgf"},
+			{"sort=parent_tree&limit=2", "adgfbe"},
+			{"sort=parent_tree&desc=true&limit=2", "chbe"},
+			{"sort=parent_tree&since=" + id("d") + "&limit=1", "be"},
+			{"sort=parent_tree&since=" + id("e") + "&desc=true&limit=2", "adgf"},
+			{"sort=parent_tree&since=" + id("h") + "&limit=1", ""},
+		}
+		for _, tc := range cases {
+			for _, threadSlugOrId := range []string{"treasure", "TREASURE", byId} {
+				var got []models.Post
+				c.get(fmt.Sprintf("/api/thread/%s/posts?%s", threadSlugOrId, tc.query), http.StatusOK, &got)
+				var gotNames strings.Builder
+				for _, post := range got {
+					gotNames.WriteString(names[post.Id])
+					if post.ThreadId != thread.Id {
+						t.Errorf("post %d of thread %d listed in thread %d", post.Id, post.ThreadId, thread.Id)
+					}
+				}
+				if gotNames.String() != tc.want {
+					t.Errorf("%s posts?%s: got %q, want %q", threadSlugOrId, tc.query, gotNames.String(), tc.want)
+				}
+			}
+		}
+
+		c.get("/api/thread/nothing/posts", http.StatusNotFound, &message{})
+		c.get("/api/thread/100500/posts?sort=tree", http.StatusNotFound, &message{})
+	})
+}
+
+func TestReplyCounts(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		for _, nick := range []string{"alice", "bob", "carol"} {
+			c.createUser(nick)
+		}
+		c.createForum("pirates", "alice")
+		c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+
+		var thread models.Thread
+		c.get("/api/thread/treasure/details", http.StatusOK, &thread)
+		if thread.PostsCount != 0 || thread.ParticipantsCount != 0 || thread.LastPostAt != "" || thread.LastPostAuthor != "" {
+			t.Errorf("thread without posts %+v", thread)
+		}
+
+		root := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "root"})[0]
+		reply := c.createPosts("treasure", models.Post{AuthorNick: "bob", Message: "reply", ParentId: root.Id})[0]
+		batch := c.createPosts("treasure",
+			models.Post{AuthorNick: "carol", Message: "deep", ParentId: reply.Id},
+			models.Post{AuthorNick: "BOB", Message: "second reply", ParentId: root.Id})
+
+		c.get("/api/thread/treasure/details", http.StatusOK, &thread)
+		if thread.PostsCount != 4 || thread.ParticipantsCount != 3 || thread.LastPostAuthor != "bob" || thread.LastPostAt != batch[1].Created {
+			t.Errorf("thread counts %+v", thread)
+		}
+		var threads []models.Thread
+		c.get("/api/forum/pirates/threads", http.StatusOK, &threads)
+		if len(threads) != 1 || threads[0].PostsCount != 4 || threads[0].LastPostAuthor != "bob" {
+			t.Errorf("forum threads %+v", threads)
+		}
+
+		counts := map[int][2]int{root.Id: {2, 3}, reply.Id: {1, 1}, batch[0].Id: {0, 0}, batch[1].Id: {0, 0}}
+		var posts []models.Post
+		c.get("/api/thread/treasure/posts?sort=tree", http.StatusOK, &posts)
+		for _, post := range posts {
+			if got := [2]int{post.Children, post.Descendants}; got != counts[post.Id] {
+				t.Errorf("post %d children and descendants %v, want %v", post.Id, got, counts[post.Id])
+			}
+		}
+		var details struct {
+			Post   *models.Post   `json:"post"`
+			Thread *models.Thread `json:"thread"`
+		}
+		c.get("/api/post/"+strconv.Itoa(root.Id)+"/details?related=thread", http.StatusOK, &details)
+		if details.Post.Descendants != 3 || details.Thread == nil || details.Thread.ParticipantsCount != 3 {
+			t.Errorf("post details %+v %+v", details.Post, details.Thread)
+		}
+
+		// renames and deletions carry over to the last post author and the participants
+		c.post("/api/user/bob/rename", models.UserRename{Nick: "robert"}, http.StatusOK, nil)
+		c.get("/api/thread/treasure/details", http.StatusOK, &thread)
+		if thread.LastPostAuthor != "robert" || thread.ParticipantsCount != 3 {
+			t.Errorf("thread after rename %+v", thread)
+		}
+		c.expect(http.MethodDelete, "/api/user/robert", nil, http.StatusNoContent, nil)
+		c.expect(http.MethodDelete, "/api/user/carol", nil, http.StatusNoContent, nil)
+		c.get("/api/thread/treasure/details", http.StatusOK, &thread)
+		if thread.LastPostAuthor != "deleted-user" || thread.ParticipantsCount != 2 || thread.PostsCount != 4 {
+			t.Errorf("thread after deletions %+v", thread)
+		}
+
+		var v2Thread apiv2.Thread
+		c.get("/api/v2/threads/"+strconv.Itoa(thread.Id), http.StatusOK, &v2Thread)
+		if v2Thread.PostsCount != 4 || v2Thread.LastPostAuthor == nil || *v2Thread.LastPostAuthor != "deleted-user" {
+			t.Errorf("v2 thread %+v", v2Thread)
+		}
+		var v2Post apiv2.Post
+		c.get("/api/v2/posts/"+strconv.Itoa(root.Id), http.StatusOK, &v2Post)
+		if v2Post.Children != 2 || v2Post.Descendants != 3 {
+			t.Errorf("v2 post %+v", v2Post)
+		}
+	})
+}
+
+func TestPostNavigation(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+
+		reply := func(parent int) models.Post {
+			return c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "m", ParentId: parent})[0]
+		}
+		root := reply(0)
+		a := reply(root.Id)
+		a1 := reply(a.Id)
+		a1x := reply(a1.Id)
+		b, cc, d := reply(root.Id), reply(root.Id), reply(root.Id)
+		other := reply(0)
+		path := func(post models.Post, suffix string) string {
+			return "/api/post/" + strconv.Itoa(post.Id) + suffix
+		}
+
+		var posts []models.Post
+		c.get(path(root, "/subtree"), http.StatusOK, &posts)
+		if got, want := postIds(posts), postIds([]models.Post{a, a1, a1x, b, cc, d}); got != want {
+			t.Errorf("subtree %s, want %s", got, want)
+		}
+		var shallow []models.Post
+		c.get(path(root, "/subtree?depth=1"), http.StatusOK, &shallow)
+		if got, want := postIds(shallow), postIds([]models.Post{a, b, cc, d}); got != want {
+			t.Errorf("subtree of depth 1 %s, want %s", got, want)
+		}
+		var page []models.Post
+		c.get(path(root, "/subtree?limit=2&since="+strconv.Itoa(a1.Id)), http.StatusOK, &page)
+		if got, want := postIds(page), postIds([]models.Post{a1x, b}); got != want {
+			t.Errorf("subtree page %s, want %s", got, want)
+		}
+
+		var ancestors []models.Post
+		c.get(path(a1x, "/ancestors"), http.StatusOK, &ancestors)
+		if got, want := postIds(ancestors), postIds([]models.Post{root, a, a1}); got != want {
+			t.Errorf("ancestors %s, want %s", got, want)
+		}
+		var rootAncestors []models.Post
+		c.get(path(root, "/ancestors"), http.StatusOK, &rootAncestors)
+		if len(rootAncestors) != 0 {
+			t.Errorf("ancestors of a root %s", postIds(rootAncestors))
+		}
+
+		var postContext models.PostContext
+		c.get(path(cc, "/context?siblings=1"), http.StatusOK, &postContext)
+		if postContext.Post.Id != cc.Id || postIds(postContext.Ancestors) != postIds([]models.Post{root}) ||
+			postIds(postContext.Before) != postIds([]models.Post{b}) || postIds(postContext.After) != postIds([]models.Post{d}) {
+			t.Errorf("context %+v", postContext)
+		}
+		var rootContext models.PostContext
+		c.get(path(other, "/context"), http.StatusOK, &rootContext)
+		if len(rootContext.Ancestors) != 0 || postIds(rootContext.Before) != postIds([]models.Post{root}) || len(rootContext.After) != 0 {
+			t.Errorf("context of a root %+v", rootContext)
+		}
+
+		c.get("/api/post/1000000/subtree", http.StatusNotFound, nil)
+		c.get("/api/post/1000000/ancestors", http.StatusNotFound, nil)
+		c.get("/api/post/1000000/context", http.StatusNotFound, nil)
+
+		subtree := pages[apiv2.Post](c, "/api/v2/posts/"+strconv.Itoa(root.Id)+"/subtree?depth=2", 2, nil)
+		if got, want := v2PostIds(subtree), postIds([]models.Post{a, a1, b, cc, d}); got != want {
+			t.Errorf("v2 subtree %s, want %s", got, want)
+		}
+		var v2Context apiv2.PostContext
+		c.get("/api/v2/posts/"+strconv.Itoa(a.Id)+"/context", http.StatusOK, &v2Context)
+		if v2Context.Post.Id != a.Id || len(v2Context.Before) != 0 || v2PostIds(v2Context.After) != postIds([]models.Post{b, cc, d}) {
+			t.Errorf("v2 context %+v", v2Context)
+		}
+		c.expectError(http.MethodGet, "/api/v2/posts/1000000/ancestors", nil, http.StatusNotFound, apiv2.CodePostNotFound)
+	})
+}
+
+// TestContextSiblings calls the context handlers past the spec middleware too, which rejects the same values first.
+func TestContextSiblings(t *testing.T) {
+	repos := configRouting.MemoryRepos()
+	c := serve(t, repos)
+	c.createUser("alice")
+	c.createForum("pirates", "alice")
+	c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+	post := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "m"})[0]
+	id := strconv.Itoa(post.Id)
+
+	v1 := postDelivery.NewHandler(repos.Post)
+	v2 := apiv2.NewHandler(repos.User, repos.Forum, repos.Thread, repos.Post)
+	call := func(handler echo.HandlerFunc, siblings string) (int, error) {
+		req := httptest.NewRequest(http.MethodGet, "/?siblings="+url.QueryEscape(siblings), nil)
+		rec := httptest.NewRecorder()
+		ctx := echo.New().NewContext(req, rec)
+		ctx.SetParamNames(postDelivery.IdCtxKey)
+		ctx.SetParamValues(id)
+		err := handler(ctx)
+		return rec.Code, err
+	}
+	for _, siblings := range []string{"abc", "-1"} {
+		_, err := call(v1.GetContext, siblings)
+		if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("v1 context with siblings=%s: %v", siblings, err)
+		}
+		_, err = call(v2.GetPostContext, siblings)
+		if v2Err, ok := err.(*apiv2.Error); !ok || v2Err.Code != apiv2.CodeValidation ||
+			len(v2Err.Fields) != 1 || v2Err.Fields[0].Field != "siblings" {
+			t.Errorf("v2 context with siblings=%s: %v", siblings, err)
+		}
+
+		var body models.ValidationError
+		c.get("/api/post/"+id+"/context?siblings="+siblings, http.StatusBadRequest, &body)
+		if len(body.Fields) != 1 || body.Fields[0].Field != "siblings" {
+			t.Errorf("context with siblings=%s: %+v", siblings, body)
+		}
+		c.expectError(http.MethodGet, "/api/v2/posts/"+id+"/context?siblings="+siblings, nil, http.StatusBadRequest, apiv2.CodeValidation)
+	}
+	if status, err := call(v1.GetContext, "1000000"); err != nil || status != http.StatusOK {
+		t.Errorf("v1 context with too many siblings: status %d, %v", status, err)
+	}
+}
+
+func TestCollapsedTree(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+
+		names := map[int]string{}
+		reply := func(name string, parent int) int {
+			post := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: name, ParentId: parent})[0]
+			names[post.Id] = name
+			return post.Id
+		}
+		root := reply("root", 0)
+		a := reply("a", root)
+		a1 := reply("a1", a)
+		reply("a1x", a1)
+		reply("a2", a)
+		reply("b", root)
+		reply("c", root)
+		reply("other", 0)
+
+		// render writes a post as name/depth, +N for the replies left out and the nested replies in brackets
+		var render func(posts []models.Post) string
+		render = func(posts []models.Post) string {
+			out := make([]string, 0, len(posts))
+			for _, post := range posts {
+				item := names[post.Id] + "/" + strconv.Itoa(post.Depth)
+				if post.MoreReplies != 0 {
+					item += "+" + strconv.Itoa(post.MoreReplies)
+				}
+				if len(post.Replies) != 0 {
+					item += "[" + render(post.Replies) + "]"
+				}
+				out = append(out, item)
+			}
+			return strings.Join(out, " ")
+		}
+		for query, want := range map[string]string{
+			"sort=tree":                            "root/0 a/1 a1/2 a1x/3 a2/2 b/1 c/1 other/0",
+			"sort=tree&child_limit=1":              "root/0+2 a/1+1 a1/2 a1x/3 other/0",
+			"sort=tree&max_depth=2":                "root/0 a/1+2 b/1 c/1 other/0",
+			"sort=tree&max_depth=2&child_limit=2":  "root/0+1 a/1+2 b/1 other/0",
+			"sort=tree&max_depth=1":                "root/0+3 other/0",
+			"sort=parent_tree&limit=1&max_depth=3": "root/0 a/1 a1/2+1 a2/2 b/1 c/1",
+			"sort=flat&child_limit=1&desc=true":    "other/0 a1x/3 a1/2 a/1+1 root/0+2",
+			"sort=tree&child_limit=1&nested=true":  "root/0+2[a/1+1[a1/2[a1x/3]]] other/0",
+			"sort=tree&limit=3&nested=true":        "root/0[a/1[a1/2]]",
+			// the parent of a2 is not on the page, so it is at the top level
+			"sort=tree&since=" + strconv.Itoa(a1) + "&max_depth=3&nested=true": "a2/2 b/1 c/1 other/0",
+		} {
+			var posts []models.Post
+			c.get("/api/thread/treasure/posts?"+query, http.StatusOK, &posts)
+			if got := render(posts); got != want {
+				t.Errorf("%s: %s, want %s", query, got, want)
+			}
+		}
+
+		var page apiv2.Page[apiv2.Post]
+		c.get("/api/v2/threads/"+strconv.Itoa(thread.Id)+"/posts?sort=tree&max_depth=2&nested=true", http.StatusOK, &page)
+		if len(page.Items) != 2 || len(page.Items[0].Replies) != 3 || page.Items[0].Replies[0].MoreReplies != 2 || page.Items[0].Replies[0].Depth != 1 {
+			t.Errorf("v2 nested page %+v", page.Items)
+		}
+		c.get("/api/thread/treasure/posts?max_depth=0", http.StatusBadRequest, nil)
+	})
+}
@@ -0,0 +1,257 @@
+package integration
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Natali-Skv/technopark_db_forum/internal/apiv2"
+	"github.com/Natali-Skv/technopark_db_forum/internal/models"
+)
+
+type v2Error struct {
+	Error struct {
+		Code     string      `json:"code"`
+		Message  string      `json:"message"`
+		Existing interface{} `json:"existing"`
+	} `json:"error"`
+}
+
+// expectError checks the status and the code of a v2 error response.
+func (c *client) expectError(method string, path string, body interface{}, status int, code string) v2Error {
+	c.t.Helper()
+	var got v2Error
+	c.expect(method, path, body, status, &got)
+	if got.Error.Code != code {
+		c.t.Errorf("%s %s: code %q (%s), want %q", method, path, got.Error.Code, got.Error.Message, code)
+	}
+	return got
+}
+
+// pages follows nextCursor from path and returns every item, checking that each page has at most limit
+// of the items counted by limited, or of all items if it is nil.
+func pages[T any](c *client, path string, limit int, limited func(T) bool) []T {
+	c.t.Helper()
+	items := make([]T, 0)
+	next := ""
+	for i := 0; ; i++ {
+		if i > 100 {
+			c.t.Fatalf("%s: too many pages", path)
+		}
+		pagePath := path + "&limit=" + strconv.Itoa(limit)
+		if next != "" {
+			pagePath += "&cursor=" + url.QueryEscape(next)
+		}
+		var page apiv2.Page[T]
+		c.get(pagePath, http.StatusOK, &page)
+		counted := 0
+		for _, item := range page.Items {
+			if limited == nil || limited(item) {
+				counted++
+			}
+		}
+		if counted > limit {
+			c.t.Errorf("%s: %d items, limit %d", pagePath, counted, limit)
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items
+		}
+		next = page.NextCursor
+	}
+}
+
+func TestV2Resources(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		var user apiv2.User
+		c.post("/api/v2/users", apiv2.NewUser{Nickname: "alice", Fullname: "Alice", Email: "alice@mail.ru"}, http.StatusCreated, &user)
+		if user.Nickname != "alice" || user.Email != "alice@mail.ru" {
+			t.Errorf("created user %+v", user)
+		}
+		conflict := c.expectError(http.MethodPost, "/api/v2/users", apiv2.NewUser{Nickname: "ALICE", Fullname: "A", Email: "a@mail.ru"}, http.StatusConflict, apiv2.CodeUserConflict)
+		if existing, _ := conflict.Error.Existing.([]interface{}); len(existing) != 1 {
+			t.Errorf("conflicting users: %v", conflict.Error.Existing)
+		}
+		c.createUser("bob")
+		c.expectError(http.MethodPatch, "/api/v2/users/alice", apiv2.UserUpdate{Email: "bob@mail.ru"}, http.StatusConflict, apiv2.CodeEmailConflict)
+		c.expect(http.MethodPatch, "/api/v2/users/alice", apiv2.UserUpdate{About: "pirate"}, http.StatusOK, &user)
+		if user.About != "pirate" || user.Fullname != "Alice" {
+			t.Errorf("updated user %+v", user)
+		}
+		c.expectError(http.MethodGet, "/api/v2/users/nobody", nil, http.StatusNotFound, apiv2.CodeUserNotFound)
+
+		var forum apiv2.Forum
+		c.post("/api/v2/forums", apiv2.NewForum{Slug: "pirates", Title: "Pirates", Author: "ALICE"}, http.StatusCreated, &forum)
+		if forum.Author != "alice" {
+			t.Errorf("forum author %q, want the stored nickname", forum.Author)
+		}
+		c.expectError(http.MethodPost, "/api/v2/forums", apiv2.NewForum{Slug: "pirates", Title: "P", Author: "bob"}, http.StatusConflict, apiv2.CodeForumConflict)
+		c.expectError(http.MethodPost, "/api/v2/forums", apiv2.NewForum{Slug: "ninjas", Title: "N", Author: "nobody"}, http.StatusNotFound, apiv2.CodeUserNotFound)
+		c.expectError(http.MethodGet, "/api/v2/forums/ninjas", nil, http.StatusNotFound, apiv2.CodeForumNotFound)
+
+		var thread, slugless apiv2.Thread
+		c.post("/api/v2/forums/pirates/threads", apiv2.NewThread{Slug: "treasure", Title: "T", Author: "alice", Message: "m"}, http.StatusCreated, &thread)
+		c.post("/api/v2/forums/pirates/threads", apiv2.NewThread{Title: "S", Author: "bob", Message: "m", CreatedAt: "2020-01-01T00:00:00.000Z"}, http.StatusCreated, &slugless)
+		if thread.Slug == nil || *thread.Slug != "treasure" || thread.CreatedAt == "" {
+			t.Errorf("created thread %+v", thread)
+		}
+		if slugless.Slug != nil || slugless.CreatedAt != "2020-01-01T00:00:00.000Z" {
+			t.Errorf("created thread without slug %+v", slugless)
+		}
+		c.expectError(http.MethodPost, "/api/v2/forums/pirates/threads", apiv2.NewThread{Slug: "TREASURE", Title: "T", Author: "bob", Message: "m"}, http.StatusConflict, apiv2.CodeThreadConflict)
+		c.expectError(http.MethodPost, "/api/v2/forums/ninjas/threads", apiv2.NewThread{Title: "T", Author: "bob", Message: "m"}, http.StatusNotFound, apiv2.CodeForumNotFound)
+
+		threadPath := "/api/v2/threads/" + strconv.Itoa(thread.Id)
+		var got apiv2.Thread
+		c.get("/api/v2/threads/slug/treasure", http.StatusOK, &got)
+		if got.Id != thread.Id {
+			t.Errorf("thread by slug: id %d, want %d", got.Id, thread.Id)
+		}
+		c.expect(http.MethodPatch, threadPath, apiv2.ThreadUpdate{Title: "Gold"}, http.StatusOK, &got)
+		if got.Title != "Gold" || got.Message != "m" {
+			t.Errorf("updated thread %+v", got)
+		}
+		c.expect(http.MethodPut, threadPath+"/votes/bob", apiv2.NewVote{Voice: 1}, http.StatusOK, &got)
+		c.expect(http.MethodPut, threadPath+"/votes/alice", apiv2.NewVote{Voice: -1}, http.StatusOK, &got)
+		c.expect(http.MethodPut, threadPath+"/votes/bob", apiv2.NewVote{Voice: -1}, http.StatusOK, &got)
+		if got.Votes != -2 {
+			t.Errorf("votes %d, want -2", got.Votes)
+		}
+		c.expectError(http.MethodPut, threadPath+"/votes/nobody", apiv2.NewVote{Voice: 1}, http.StatusNotFound, apiv2.CodeUserNotFound)
+		c.expectError(http.MethodGet, "/api/v2/threads/999999", nil, http.StatusNotFound, apiv2.CodeThreadNotFound)
+		c.expectError(http.MethodGet, "/api/v2/threads/slug/nothing", nil, http.StatusNotFound, apiv2.CodeThreadNotFound)
+
+		var posts []apiv2.Post
+		c.post(threadPath+"/posts", []apiv2.NewPost{{Author: "alice", Message: "root"}}, http.StatusCreated, &posts)
+		root := posts[0]
+		if root.ParentId != nil || root.ThreadId != thread.Id || root.Forum != "pirates" {
+			t.Errorf("root post %+v", root)
+		}
+		c.post(threadPath+"/posts", []apiv2.NewPost{{ParentId: &root.Id, Author: "bob", Message: "reply"}}, http.StatusCreated, &posts)
+		if posts[0].ParentId == nil || *posts[0].ParentId != root.Id {
+			t.Errorf("reply %+v, want parent %d", posts[0], root.Id)
+		}
+		c.expectError(http.MethodPost, "/api/v2/threads/"+strconv.Itoa(slugless.Id)+"/posts", []apiv2.NewPost{{ParentId: &root.Id, Author: "bob", Message: "m"}}, http.StatusConflict, apiv2.CodeParentConflict)
+		c.expectError(http.MethodPost, threadPath+"/posts", []apiv2.NewPost{{Author: "nobody", Message: "m"}}, http.StatusNotFound, apiv2.CodeUserNotFound)
+
+		postPath := "/api/v2/posts/" + strconv.Itoa(root.Id)
+		var post apiv2.Post
+		c.get(postPath, http.StatusOK, &post)
+		if post.Embedded != nil {
+			t.Errorf("post without include embeds %+v", post.Embedded)
+		}
+		c.get(postPath+"?include=author,thread", http.StatusOK, &post)
+		if post.Embedded == nil || post.Embedded.Author == nil || post.Embedded.Author.Nickname != "alice" ||
+			post.Embedded.Thread == nil || post.Embedded.Thread.Id != thread.Id || post.Embedded.Forum != nil {
+			t.Errorf("post with author and thread embeds %+v", post.Embedded)
+		}
+		c.expectError(http.MethodGet, postPath+"?include=votes", nil, http.StatusBadRequest, apiv2.CodeBadRequest)
+		c.expect(http.MethodPatch, postPath, apiv2.PostUpdate{Message: "edited"}, http.StatusOK, &post)
+		if !post.Edited || post.Message != "edited" {
+			t.Errorf("updated post %+v", post)
+		}
+		c.expectError(http.MethodGet, "/api/v2/posts/999999", nil, http.StatusNotFound, apiv2.CodePostNotFound)
+
+		// spec violations, unknown routes and bad cursors are typed too, while /api keeps its errors
+		c.expectError(http.MethodPost, "/api/v2/users", map[string]string{"nickname": "carol"}, http.StatusBadRequest, apiv2.CodeValidation)
+		c.expectError(http.MethodGet, "/api/v2/nothing", nil, http.StatusNotFound, apiv2.CodeNotFound)
+		c.expectError(http.MethodGet, "/api/v2/forums/pirates/threads?cursor=garbage", nil, http.StatusBadRequest, apiv2.CodeInvalidCursor)
+		var v1 message
+		c.get("/api/user/nobody/profile", http.StatusNotFound, &v1)
+		if v1.Message == "" {
+			t.Errorf("v1 error %+v, want a message", v1)
+		}
+	})
+}
+
+func TestV2Pagination(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c *client) {
+		c.createUser("alice")
+		c.createForum("pirates", "alice")
+		// threads created at the same time must not be lost or repeated between pages
+		created := []string{"2020-01-01T00:00:00.000Z", "2020-01-02T00:00:00.000Z", "2020-01-02T00:00:00.000Z",
+			"2020-01-02T00:00:00.000Z", "2020-01-02T00:00:00.000Z", "2020-01-03T00:00:00.000Z", "2020-01-04T00:00:00.000Z"}
+		threadIds := make([]string, 0, len(created))
+		for i, at := range created {
+			thread := c.createThread("pirates", models.Thread{Title: "t" + strconv.Itoa(i), AuthorNick: "alice", Message: "m", Created: at})
+			threadIds = append(threadIds, strconv.Itoa(thread.Id))
+		}
+		reversed := make([]string, 0, len(threadIds))
+		for i := len(threadIds) - 1; i >= 0; i-- {
+			reversed = append(reversed, threadIds[i])
+		}
+		for _, limit := range []int{1, 2, 3, 100} {
+			for query, want := range map[string][]string{"?desc=false": threadIds, "?desc=true": reversed} {
+				threads := pages[apiv2.Thread](c, "/api/v2/forums/pirates/threads"+query, limit, nil)
+				ids := make([]string, 0, len(threads))
+				for _, thread := range threads {
+					ids = append(ids, strconv.Itoa(thread.Id))
+				}
+				if strings.Join(ids, " ") != strings.Join(want, " ") {
+					t.Errorf("threads%s by %d: got %v, want %v", query, limit, ids, want)
+				}
+			}
+		}
+
+		thread := c.createThread("pirates", models.Thread{Slug: "treasure", Title: "t", AuthorNick: "alice", Message: "m"})
+		for _, nick := range []string{"dave", "carol", "bob"} {
+			c.createUser(nick)
+			c.createPosts("treasure", models.Post{AuthorNick: nick, Message: "hi"})
+		}
+		users := pages[apiv2.User](c, "/api/v2/forums/pirates/users?desc=true", 2, nil)
+		nicks := make([]string, 0, len(users))
+		for _, user := range users {
+			nicks = append(nicks, user.Nickname)
+		}
+		if got := strings.Join(nicks, " "); got != "dave carol bob alice" {
+			t.Errorf("users: got %s, want dave carol bob alice", got)
+		}
+		users = pages[apiv2.User](c, "/api/v2/forums/pirates/users?sort=activity", 3, nil)
+		nicks = nicks[:0]
+		for _, user := range users {
+			nicks = append(nicks, user.Nickname)
+		}
+		if got := strings.Join(nicks, " "); got != "alice dave carol bob" {
+			t.Errorf("users by activity: got %s, want alice dave carol bob", got)
+		}
+
+		// one more root with a reply and a reply to the reply, after the three roots above
+		posts := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "x"})
+		reply := c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "y", ParentId: posts[0].Id})
+		c.createPosts("treasure", models.Post{AuthorNick: "alice", Message: "z", ParentId: reply[0].Id})
+		postsPath := "/api/v2/threads/" + strconv.Itoa(thread.Id) + "/posts?"
+		var root func(apiv2.Post) bool
+		for _, sort := range []string{"flat", "tree", "parent_tree"} {
+			for _, desc := range []string{"false", "true"} {
+				query := "sort=" + sort + "&desc=" + desc
+				var whole apiv2.Page[apiv2.Post]
+				c.get(postsPath+query, http.StatusOK, &whole)
+				all := whole.Items
+				if len(all) != 6 || whole.NextCursor != "" {
+					t.Fatalf("posts %s: %d posts, cursor %q", query, len(all), whole.NextCursor)
+				}
+				if sort == "parent_tree" {
+					// limit counts the root posts, a page holds their whole subtrees
+					root = func(post apiv2.Post) bool { return post.ParentId == nil }
+				}
+				for _, limit := range []int{1, 2, 4} {
+					paged := pages[apiv2.Post](c, postsPath+query, limit, root)
+					if got, want := v2PostIds(paged), v2PostIds(all); got != want {
+						t.Errorf("posts %s by %d: got %s, want %s", query, limit, got, want)
+					}
+				}
+			}
+		}
+		c.expectError(http.MethodGet, "/api/v2/threads/999999/posts?sort=flat", nil, http.StatusNotFound, apiv2.CodeThreadNotFound)
+	})
+}
+
+func v2PostIds(posts []apiv2.Post) string {
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, strconv.Itoa(post.Id))
+	}
+	return strings.Join(ids, " ")
+}